@@ -1,6 +1,8 @@
 package main
 
 import (
+    "os"
+
     "github.com/user/nft-marketplace/internal/app"
     "github.com/user/nft-marketplace/internal/config"
 )
@@ -9,6 +11,12 @@ func main() {
     // Load config
     cfg := config.Load()
 
+    // `api migrate ...` manages the schema instead of serving
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        migrate(cfg.DB, os.Args[2:])
+        return
+    }
+
     // Start App
     application.StartApp(cfg)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/db"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up           apply every pending migration
+  down         revert the latest applied migration
+  to VERSION   apply or revert migrations until VERSION is the latest applied (0 reverts all)
+  status       list the migrations and when they were applied`
+
+// migrate runs a migrate subcommand against the configured database.
+func migrate(cfg *config.DBConfig, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+	sqlDB := db.Connect(cfg)
+	defer sqlDB.Close()
+	migrator, err := db.NewMigrator(sqlDB, cfg.Driver)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctx)
+		report("applied", applied, err)
+	case args[0] == "down" && len(args) == 1:
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if reverted == nil {
+			fmt.Println("no migration is applied")
+			return
+		}
+		fmt.Printf("reverted %s\n", reverted)
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("invalid version %q", args[1])
+		}
+		done, err := migrator.To(ctx, version)
+		report("migrated", done, err)
+	case args[0] == "status" && len(args) == 1:
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+// report prints the migrations done by a command, failing with err after
+// printing those done before it.
+func report(verb string, done []db.Migration, err error) {
+	for _, m := range done {
+		fmt.Printf("%s %s\n", verb, m)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(done) == 0 {
+		fmt.Println("already up to date")
+	}
+}
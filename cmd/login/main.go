@@ -0,0 +1,76 @@
+// Command login signs in to the API with Sign-In With Ethereum and prints
+// the resulting token pair as JSON. It is meant for scripts and local
+// testing with development keys.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/nft-marketplace/pkg/client"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8080", "API base URL")
+	hexKey := flag.String("key", "", "hex-encoded private key to sign in with")
+	domain := flag.String("domain", "localhost:5173", "SIWE domain, matching SIWE_DOMAIN")
+	chainID := flag.Int64("chain", 1337, "chain id, matching CHAIN_ID")
+	flag.Parse()
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(*hexKey, "0x"))
+	if err != nil {
+		log.Fatalf("invalid -key: %v", err)
+	}
+
+	api, err := client.NewClientWithResponses(*url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	nonce, err := api.AuthNonceWithResponse(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nonce.JSON200 == nil {
+		log.Fatalf("nonce: %s", nonce.Body)
+	}
+
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := fmt.Sprintf("%s wants you to sign in with your Ethereum account:\n%s\n\n"+
+		"Sign in to NFT Marketplace\n\n"+
+		"URI: http://%s\nVersion: 1\nChain ID: %d\nNonce: %s\nIssued At: %s",
+		*domain, address, *domain, *chainID, nonce.JSON200.Nonce, time.Now().UTC().Format(time.RFC3339))
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	resp, err := api.AuthVerifyWithResponse(ctx, &client.AuthVerifyParams{}, client.VerifyRequest{
+		Message:   message,
+		Signature: hexutil.Encode(sig),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.JSON200 == nil {
+		log.Fatalf("verify: %s", resp.Body)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp.JSON200); err != nil {
+		log.Fatal(err)
+	}
+}
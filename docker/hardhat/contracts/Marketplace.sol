@@ -2,30 +2,39 @@
 pragma solidity ^0.8.20;
 
 import "@openzeppelin/contracts/token/ERC721/IERC721.sol";
+import "@openzeppelin/contracts/token/ERC20/IERC20.sol";
+import "@openzeppelin/contracts/token/ERC20/utils/SafeERC20.sol";
 import "@openzeppelin/contracts/utils/ReentrancyGuard.sol";
 
 contract Marketplace is ReentrancyGuard {
+    using SafeERC20 for IERC20;
+
     struct Listing {
         uint256 price;
         address seller;
         bool active;
+        address currency; // address(0) for the native currency
     }
 
     // NFT Address -> Token ID -> Listing
     mapping(address => mapping(uint256 => Listing)) public listings;
 
-    event Listed(address indexed nft, uint256 indexed tokenId, uint256 price, address indexed seller);
-    event Bought(address indexed nft, uint256 indexed tokenId, uint256 price, address indexed buyer);
+    event Listed(address indexed nft, uint256 indexed tokenId, uint256 price, address indexed seller, address currency);
+    event Bought(address indexed nft, uint256 indexed tokenId, uint256 price, address indexed buyer, address currency);
     event Delisted(address indexed nft, uint256 indexed tokenId, address indexed seller);
 
-    function list(address nft, uint256 tokenId, uint256 price) external nonReentrant {
+    function list(address nft, uint256 tokenId, uint256 price) external {
+        listWithCurrency(nft, tokenId, price, address(0));
+    }
+
+    function listWithCurrency(address nft, uint256 tokenId, uint256 price, address currency) public nonReentrant {
         IERC721 token = IERC721(nft);
         require(token.ownerOf(tokenId) == msg.sender, "Not owner");
         require(token.getApproved(tokenId) == address(this) || token.isApprovedForAll(msg.sender, address(this)), "Not approved");
         require(price > 0, "Price must be > 0");
 
-        listings[nft][tokenId] = Listing(price, msg.sender, true);
-        emit Listed(nft, tokenId, price, msg.sender);
+        listings[nft][tokenId] = Listing(price, msg.sender, true, currency);
+        emit Listed(nft, tokenId, price, msg.sender, currency);
     }
 
     function delist(address nft, uint256 tokenId) external nonReentrant {
@@ -40,6 +49,7 @@ contract Marketplace is ReentrancyGuard {
     function buy(address nft, uint256 tokenId) external payable nonReentrant {
         Listing memory item = listings[nft][tokenId];
         require(item.active, "Not for sale");
+        require(item.currency == address(0), "Priced in ERC20");
         require(msg.value >= item.price, "Insufficient funds");
 
         listings[nft][tokenId].active = false; // Delist
@@ -50,7 +60,22 @@ contract Marketplace is ReentrancyGuard {
         (bool success, ) = payable(item.seller).call{value: item.price}("");
         require(success, "Transfer failed");
         
-        emit Bought(nft, tokenId, item.price, msg.sender);
+        emit Bought(nft, tokenId, item.price, msg.sender, address(0));
+    }
+
+    // Buys a listing priced in an ERC20; the buyer must have approved the
+    // marketplace to spend at least the listing price.
+    function buyWithToken(address nft, uint256 tokenId) external nonReentrant {
+        Listing memory item = listings[nft][tokenId];
+        require(item.active, "Not for sale");
+        require(item.currency != address(0), "Priced in native currency");
+
+        listings[nft][tokenId].active = false; // Delist
+
+        IERC20(item.currency).safeTransferFrom(msg.sender, item.seller, item.price);
+        IERC721(nft).safeTransferFrom(item.seller, msg.sender, tokenId);
+
+        emit Bought(nft, tokenId, item.price, msg.sender, item.currency);
     }
 
     function getListing(address nft, uint256 tokenId) external view returns (Listing memory) {
@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import "@openzeppelin/contracts/token/ERC20/ERC20.sol";
+
+// ERC20 used to demo paying for listings in a token other than the native currency.
+contract TestToken is ERC20 {
+    constructor(address[] memory holders) ERC20("Test USD", "TUSD") {
+        for (uint256 i = 0; i < holders.length; i++) {
+            _mint(holders[i], 1_000_000 * 10 ** decimals());
+        }
+    }
+
+    function decimals() public pure override returns (uint8) {
+        return 6;
+    }
+}
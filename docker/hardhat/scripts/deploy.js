@@ -22,11 +22,18 @@ async function main() {
     const marketAddress = await marketplace.getAddress();
     console.log(`Marketplace deployed to: ${marketAddress}`);
 
+    const TestToken = await ethers.getContractFactory("TestToken");
+    const testToken = await TestToken.deploy([owner.address, seller.address, buyer.address]);
+    await testToken.waitForDeployment();
+    const tokenAddress = await testToken.getAddress();
+    console.log(`TestToken deployed to: ${tokenAddress}`);
+
     // Create .env content
     const envContent = `CHAIN_ID=1337
 RPC_URL=http://localhost:8500
 NFT_ADDRESS=${nftAddress}
 MARKET_ADDRESS=${marketAddress}
+PAYMENT_TOKEN_ADDRESS=${tokenAddress}
 OWNER_PRIVATE_KEY=0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80
 SELLER_PRIVATE_KEY=0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d
 BUYER_PRIVATE_KEY=0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a
@@ -49,6 +56,9 @@ BUYER_PRIVATE_KEY=0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab
     const marketArtifact = await artifacts.readArtifact("Marketplace");
     fs.writeFileSync(path.join(artifactsDir, "Marketplace.json"), JSON.stringify(marketArtifact.abi));
 
+    const tokenArtifact = await artifacts.readArtifact("TestToken");
+    fs.writeFileSync(path.join(artifactsDir, "ERC20.json"), JSON.stringify(tokenArtifact.abi));
+
     console.log(`Exported ABIs to ${artifactsDir}`);
 }
 
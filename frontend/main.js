@@ -7,14 +7,7 @@ const $ = (id) => document.getElementById(id);
 let listings = [];
 let currentView = 'marketplace'; // marketplace, my-nfts
 let currentUser = { id: 1, wallet_address: "0x..." }; // Mock user for demo
-
-// Init
-// Mock Users (matching .env keys for demo)
-const MOCK_USERS = {
-    owner: { wallet: "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80", name: "Reseller (Owner)" },
-    buyer1: { wallet: "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d", name: "Alice (Buyer)" },
-    buyer2: { wallet: "0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a", name: "Bob (Buyer)" }
-};
+let session = null; // { access_token, refresh_token } from Sign-In With Ethereum
 
 // Init
 async function init() {
@@ -23,6 +16,7 @@ async function init() {
 
     // Load initial data
     loadListings();
+    watchListings();
     setupEventListeners();
 
     // User Switcher Listener
@@ -33,29 +27,79 @@ async function init() {
             // Refresh view
             if (currentView === 'my-nfts') loadMyNFTs();
             if (currentView === 'marketplace') loadListings();
-            showToast(`Switched to ${currentUser.name}`, 'info');
+            showToast(`Switched to ${currentUser.name || currentUser.wallet_address}`, 'info');
         });
     }
 }
 
+// Sign-In With Ethereum (EIP-4361): sign a nonce-bearing message with the
+// browser wallet and exchange it for a session.
 async function loginUser(userKey) {
     try {
-        const mock = MOCK_USERS[userKey];
-        const res = await fetch(`${API_URL}/users`, {
+        if (!window.ethereum) {
+            throw new Error("No Ethereum wallet found");
+        }
+        // Switching users asks the wallet to pick another account
+        if (userKey !== "owner") {
+            await window.ethereum.request({ method: 'wallet_requestPermissions', params: [{ eth_accounts: {} }] });
+        }
+        const [address] = await window.ethereum.request({ method: 'eth_requestAccounts' });
+        const chainId = parseInt(await window.ethereum.request({ method: 'eth_chainId' }), 16);
+
+        const { nonce } = await (await fetch(`${API_URL}/auth/nonce`)).json();
+        const message = [
+            `${window.location.host} wants you to sign in with your Ethereum account:`,
+            address,
+            '',
+            'Sign in to the NFT Marketplace.',
+            '',
+            `URI: ${window.location.origin}`,
+            'Version: 1',
+            `Chain ID: ${chainId}`,
+            `Nonce: ${nonce}`,
+            `Issued At: ${new Date().toISOString().replace(/\.\d{3}Z$/, 'Z')}`,
+        ].join('\n');
+        const signature = await window.ethereum.request({ method: 'personal_sign', params: [message, address] });
+
+        const res = await fetch(`${API_URL}/auth/verify`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ wallet_address: mock.wallet, name: mock.name })
+            body: JSON.stringify({ message, signature })
         });
-        if (res.ok) {
-            currentUser = await res.json();
-            $('user-wallet').innerText = `🟢 ${currentUser.wallet_address.slice(0, 6)}...`;
+        if (!res.ok) {
+            const err = await res.json();
+            throw new Error(err.detail || "Sign-in failed");
         }
+        session = await res.json();
+        currentUser = session.user;
+        $('user-wallet').innerText = `🟢 ${currentUser.wallet_address.slice(0, 6)}...`;
     } catch (err) {
         console.error("Login failed", err);
         $('user-wallet').innerText = '🔴 Login Error';
     }
 }
 
+// authFetch sends the session's access token, refreshing it once on 401.
+async function authFetch(url, options = {}) {
+    const send = () => fetch(url, {
+        ...options,
+        headers: { ...(options.headers || {}), Authorization: `Bearer ${session?.access_token}` }
+    });
+    let res = await send();
+    if (res.status === 401 && session?.refresh_token) {
+        const refresh = await fetch(`${API_URL}/auth/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: session.refresh_token })
+        });
+        if (refresh.ok) {
+            session = await refresh.json();
+            res = await send();
+        }
+    }
+    return res;
+}
+
 // Setup Event Listeners
 function setupEventListeners() {
     // Tab switching
@@ -90,11 +134,10 @@ function setupEventListeners() {
             btn.disabled = true;
 
             try {
-                const res = await fetch(`${API_URL}/nfts/mint`, {
+                const res = await authFetch(`${API_URL}/nfts/mint`, {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({
-                        owner_id: currentUser.id,
                         name: name,
                         symbol: symbol,
                         description: desc,
@@ -112,7 +155,7 @@ function setupEventListeners() {
                     }
                 } else {
                     const err = await res.json();
-                    throw new Error(err.error || "Minting failed");
+                    throw new Error(err.detail || "Minting failed");
                 }
             } catch (err) {
                 showToast(`❌ Minting failed: ${err.message}`, 'error');
@@ -128,7 +171,7 @@ function setupEventListeners() {
 async function loadListings() {
     try {
         const res = await fetch(`${API_URL}/listings`);
-        listings = await res.json();
+        listings = (await res.json()).items;
         renderMarketplace(listings);
     } catch (err) {
         console.error("Failed to load listings", err);
@@ -136,11 +179,21 @@ async function loadListings() {
     }
 }
 
+// Reload the marketplace when listings change instead of polling. EventSource
+// reconnects on its own, resuming after the last event it received.
+function watchListings() {
+    const types = ['listing.created', 'listing.cancelled', 'listing.sold'];
+    const stream = new EventSource(`${API_URL}/events/stream?${types.map(t => `type=${t}`).join('&')}`);
+    types.forEach(type => stream.addEventListener(type, () => {
+        if (currentView === 'marketplace') loadListings();
+    }));
+}
+
 // Load My NFTs
 async function loadMyNFTs() {
     try {
         const res = await fetch(`${API_URL}/nfts?owner_id=${currentUser.id}`);
-        const myNFTs = await res.json();
+        const myNFTs = (await res.json()).items;
         renderMyNFTs(myNFTs);
     } catch (err) {
         console.error("Failed to load my NFTs", err);
@@ -166,7 +219,7 @@ function renderMarketplace(list) {
             <img src="${imageUri}" class="coin-img" onerror="this.src='https://placehold.co/200x200/667eea/white?text=NFT'">
             <div class="coin-info">
                 <h4>NFT #${listing.nft.token_id}</h4>
-                <div class="ticker">Price: ${parseFloat(listing.price_wei) / 1e18} ${listing.currency}</div>
+                <div class="ticker">Price: ${listing.price.decimal} ${listing.currency}</div>
                 <div class="coin-mc">Seller: ${listing.seller.name || listing.seller.wallet_address.slice(0, 6)}...</div>
             </div>
         `;
@@ -214,15 +267,30 @@ function showListModal(nft) {
     createListing(nft.id, price);
 }
 
+// Converts a decimal amount such as "0.1" to an integer wei string without floating point rounding.
+function toWei(amount, decimals = 18) {
+    const [whole, frac = ''] = amount.trim().split('.');
+    if (!/^\d*$/.test(whole) || !/^\d*$/.test(frac) || frac.length > decimals) {
+        throw new Error(`Invalid amount: ${amount}`);
+    }
+    return BigInt((whole || '0') + frac.padEnd(decimals, '0')).toString();
+}
+
+// Demo purchases don't send a transaction, but the API still expects a
+// well-formed 32-byte hash.
+function demoTxHash() {
+    const bytes = crypto.getRandomValues(new Uint8Array(32));
+    return '0x' + Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('');
+}
+
 async function createListing(nftId, priceEth) {
-    const wei = (parseFloat(priceEth) * 1e18).toString();
     try {
-        const res = await fetch(`${API_URL}/listings`, {
+        const wei = toWei(priceEth);
+        const res = await authFetch(`${API_URL}/listings`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({
                 nft_id: nftId,
-                seller_user_id: currentUser.id,
                 price_wei: wei,
                 currency: "ETH"
             })
@@ -232,7 +300,7 @@ async function createListing(nftId, priceEth) {
             loadListings();
         } else {
             const err = await res.json();
-            throw new Error(err.error);
+            throw new Error(err.detail);
         }
     } catch (err) {
         showToast(`❌ Listing failed: ${err.message}`, "error");
@@ -259,7 +327,7 @@ function openTradeModal(listing) {
     $('modal-img').src = listing.nft.metadata_url;
     $('modal-name').innerText = `NFT #${listing.nft.token_id}`;
     $('modal-ticker').innerText = listing.nft.contract_address;
-    $('modal-mc').innerText = `${parseFloat(listing.price_wei) / 1e18} ${listing.currency}`;
+    $('modal-mc').innerText = `${listing.price.decimal} ${listing.currency}`;
     $('modal-creator').innerText = listing.seller.wallet_address;
     $('modal-desc').innerText = `Chain: ${listing.nft.chain}`;
     $('modal-token-id').value = listing.id;
@@ -281,18 +349,18 @@ if (tradeForm) {
         btn.disabled = true;
 
         try {
-            const orderRes = await fetch(`${API_URL}/orders`, {
+            const orderRes = await authFetch(`${API_URL}/orders`, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ listing_id: listingId, buyer_user_id: currentUser.id })
+                body: JSON.stringify({ listing_id: listingId })
             });
             const order = await orderRes.json();
 
             if (order.id) {
-                const confirmRes = await fetch(`${API_URL}/orders/${order.id}/confirm`, {
+                const confirmRes = await authFetch(`${API_URL}/orders/${order.id}/confirm`, {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ tx_hash: "0x" + Math.random().toString(16).slice(2) })
+                    body: JSON.stringify({ tx_hash: demoTxHash() })
                 });
                 const result = await confirmRes.json();
 
@@ -301,10 +369,10 @@ if (tradeForm) {
                     loadListings();
                     setTimeout(() => modal.classList.add('hidden'), 2000);
                 } else {
-                    throw new Error(result.error || "Confirmation failed");
+                    throw new Error(result.detail || "Confirmation failed");
                 }
             } else {
-                throw new Error(order.error || "Order creation failed");
+                throw new Error(order.detail || "Order creation failed");
             }
         } catch (err) {
             showToast(`❌ Error: ${err.message}`, 'error');
@@ -2,6 +2,7 @@ package application
 
 import (
     "context"
+    "errors"
     "net/http"
     "os"
     "os/signal"
@@ -11,9 +12,14 @@ import (
     "github.com/gin-gonic/gin"
     "github.com/sirupsen/logrus"
     "github.com/user/nft-marketplace/internal/config"
+    "github.com/user/nft-marketplace/internal/core"
     "github.com/user/nft-marketplace/internal/db"
     "github.com/user/nft-marketplace/internal/handler"
     "github.com/user/nft-marketplace/internal/platform/eth"
+    "github.com/user/nft-marketplace/internal/platform/events"
+    "github.com/user/nft-marketplace/internal/platform/idempotency"
+    "github.com/user/nft-marketplace/internal/platform/ratelimit"
+    "github.com/user/nft-marketplace/internal/platform/validation"
     "github.com/user/nft-marketplace/internal/repository"
     "github.com/user/nft-marketplace/internal/server"
     "github.com/user/nft-marketplace/internal/service"
@@ -23,21 +29,31 @@ import (
 type ServiceClient struct {
     Config    *config.Config
     Database  *gorm.DB
-    EthClient *eth.Client
+    EthClient core.ChainClient
     Handler   *handler.Handler
     Service   *service.MarketplaceService
+    Auth      *service.AuthService
+    Limiter   ratelimit.Limiter
+    Idem      idempotency.Store
+    Events    *events.Hub
 }
 
 func StartApp(cfg *config.Config) {
     client := initServiceClient(cfg)
+    if err := validation.Register(); err != nil {
+        logrus.Fatalf("Failed to register request validators: %v", err)
+    }
     router := gin.Default()
+    if err := router.SetTrustedProxies(cfg.RateLimit.TrustedProxies); err != nil {
+        logrus.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+    }
 
     srv := &http.Server{
         Addr:    ":" + cfg.HTTP.Port,
         Handler: router,
     }
 
-    app := server.NewServer(cfg, router, client.Database, client.Handler)
+    app := server.NewServer(cfg, router, client.Database, client.Handler, client.Auth, client.Limiter, client.Idem, client.Events)
     server.ConfigRoutesAndSchedulers(app)
 
     serverErr := make(chan error, 1)
@@ -51,18 +67,87 @@ func StartApp(cfg *config.Config) {
 }
 
 func initServiceClient(cfg *config.Config) *ServiceClient {
-    dbConn := db.InitDB(cfg.DB)
+    hub := events.NewHub(cfg.Events.Buffer, cfg.Events.MaxSubscribers)
+    var dbConn *gorm.DB
+    var repo core.Store
+    var ethClient core.ChainClient
+    if cfg.Demo {
+        logrus.Warn("Demo mode: data is kept in memory and the chain is simulated")
+        repo = repository.NewMemoryRepository(hub)
+        ethClient = eth.NewMemoryChain(cfg.Ethereum.NFTAddress)
+        // Sales never reach the simulated chain, which checks would take for drift
+        cfg.Listing.CheckInterval, cfg.Listing.MaxAge = 0, 0
+        cfg.Reconcile.Interval = 0
+        cfg.Idempotency.Backend = "memory"
+        if cfg.RateLimit.Backend != "off" {
+            cfg.RateLimit.Backend = "memory"
+        }
+    } else {
+        dbConn = db.InitDB(cfg.DB)
 
-    // Init Eth Client
-    ethClient, err := eth.NewClient(*cfg.Ethereum)
-    if err != nil {
-        logrus.Fatalf("Failed to initialize Ethereum client: %v", err)
+        // Init Eth Client
+        client, err := eth.NewClient(*cfg.Ethereum)
+        if err != nil {
+            logrus.Fatalf("Failed to initialize Ethereum client: %v", err)
+        }
+        ethClient = client
+        repo = repository.NewRepository(dbConn, hub)
     }
 
     // Init layers
-    repo := repository.NewRepository(dbConn)
-    svc := service.NewMarketplaceService(repo, ethClient)
-    h := handler.NewHandler(svc)
+    svc, err := service.NewMarketplaceService(cfg, repo, ethClient)
+    if err != nil {
+        logrus.Fatalf("Failed to initialize marketplace service: %v", err)
+    }
+    if err := svc.EnsureNativeCurrency(); err != nil {
+        logrus.Fatalf("Failed to register native currency: %v", err)
+    }
+    authSvc, err := service.NewAuthService(cfg, repo)
+    if err != nil {
+        logrus.Fatalf("Failed to initialize auth service: %v", err)
+    }
+    if err := authSvc.EnsureAdmins(); err != nil {
+        logrus.Fatalf("Failed to grant admin roles: %v", err)
+    }
+    // Finishes mints and burns left open, including by a previous run
+    go svc.RunIntents(context.Background())
+    if cfg.Expiry.Interval > 0 {
+        go svc.RunExpiry(context.Background())
+    }
+    if cfg.Listing.CheckInterval > 0 {
+        go svc.RunListingChecks(context.Background())
+    }
+    if cfg.Reconcile.Interval > 0 {
+        go func() {
+            for range time.Tick(cfg.Reconcile.Interval) {
+                _, err := svc.RunReconciliation(context.Background())
+                if err != nil && !errors.Is(err, core.Conflict("reconciliation_running", "")) {
+                    logrus.Errorf("Ownership reconciliation failed: %v", err)
+                }
+            }
+        }()
+    }
+    eventSvc := service.NewEventService(repo, hub, cfg.Events.Heartbeat)
+    go func() {
+        for range time.Tick(time.Hour) {
+            if err := eventSvc.PruneEvents(cfg.Events.Retention); err != nil {
+                logrus.Errorf("Event log pruning failed: %v", err)
+            }
+        }
+    }()
+    webhookSvc := service.NewWebhookService(cfg.Webhook, repo)
+    go webhookSvc.Run(context.Background())
+    go func() {
+        for range time.Tick(time.Hour) {
+            if err := webhookSvc.PruneDeliveries(); err != nil {
+                logrus.Errorf("Webhook delivery pruning failed: %v", err)
+            }
+        }
+    }()
+    h := handler.NewHandler(svc, authSvc, eventSvc, webhookSvc)
+    go svc.RunRarity(context.Background())
+    limiter := initRateLimiter(cfg.RateLimit, dbConn)
+    idem := initIdempotencyStore(cfg.Idempotency, dbConn)
 
     return &ServiceClient{
         Config:    cfg,
@@ -70,6 +155,58 @@ func initServiceClient(cfg *config.Config) *ServiceClient {
         EthClient: ethClient,
         Handler:   h,
         Service:   svc,
+        Auth:      authSvc,
+        Limiter:   limiter,
+        Idem:      idem,
+        Events:    hub,
+    }
+}
+
+func initIdempotencyStore(cfg *config.IdempotencyConfig, dbConn *gorm.DB) idempotency.Store {
+    var store idempotency.Store
+    switch cfg.Backend {
+    case "postgres":
+        store = idempotency.NewGormStore(dbConn, cfg.LockTimeout)
+    case "memory":
+        store = idempotency.NewMemoryStore(cfg.LockTimeout)
+    default:
+        logrus.Fatalf("Unknown IDEMPOTENCY_BACKEND %q", cfg.Backend)
+    }
+
+    go func() {
+        for range time.Tick(time.Hour) {
+            if err := store.Prune(context.Background()); err != nil {
+                logrus.Errorf("Idempotency key pruning failed: %v", err)
+            }
+        }
+    }()
+    return store
+}
+
+func initRateLimiter(cfg *config.RateLimitConfig, dbConn *gorm.DB) ratelimit.Limiter {
+    switch cfg.Backend {
+    case "off":
+        logrus.Warn("Rate limiting is disabled")
+        return nil
+    case "postgres":
+        limiter, err := ratelimit.NewPostgresLimiter(dbConn)
+        if err != nil {
+            logrus.Fatalf("Failed to initialize rate limiter: %v", err)
+        }
+        // Idle buckets are full again after a window, so they can be dropped
+        go func() {
+            for range time.Tick(time.Hour) {
+                if err := limiter.Prune(24 * time.Hour); err != nil {
+                    logrus.Errorf("Rate limit bucket pruning failed: %v", err)
+                }
+            }
+        }()
+        return limiter
+    case "memory":
+        return ratelimit.NewMemoryLimiter()
+    default:
+        logrus.Fatalf("Unknown RATE_LIMIT_BACKEND %q", cfg.Backend)
+        return nil
     }
 }
 
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+type AuthConfig struct {
+	JWTSecret  string
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	NonceTTL   time.Duration
+	// SIWEDomain is the domain SIWE messages and their URIs must be for;
+	// empty accepts any.
+	SIWEDomain string
+	// AdminWallets are granted the admin role on startup.
+	AdminWallets []string
+}
+
+func LoadAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		JWTSecret:    getEnv("JWT_SECRET", ""),
+		Issuer:       getEnv("JWT_ISSUER", "nft-marketplace"),
+		AccessTTL:    getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTTL:   getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		NonceTTL:     getDuration("SIWE_NONCE_TTL", 10*time.Minute),
+		SIWEDomain:   getEnv("SIWE_DOMAIN", ""),
+		AdminWallets: getList("ADMIN_WALLETS"),
+	}
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+func getList(key string) []string {
+	return getListValue(getEnv(key, ""))
+}
+
+func getListValue(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -6,19 +6,43 @@ import (
 )
 
 type Config struct {
-    DB       *DBConfig
-    HTTP     *HTTPConfig
-    Ethereum *EthConfig
-    LogLevel string
+    DB          *DBConfig
+    HTTP        *HTTPConfig
+    Ethereum    *EthConfig
+    Rarity      *RarityConfig
+    Auth        *AuthConfig
+    RateLimit   *RateLimitConfig
+    Idempotency *IdempotencyConfig
+    Events      *EventsConfig
+    Webhook     *WebhookConfig
+    Intent      *IntentConfig
+    Reconcile   *ReconcileConfig
+    Listing     *ListingConfig
+    Expiry      *ExpiryConfig
+    LogLevel    string
+    // Demo runs the API without a database or a node: everything is kept
+    // in memory and the chain is simulated.
+    Demo        bool
 }
 
 func Load() *Config {
     _ = godotenv.Overload() // Ensure .env values overwrite existing env vars
     cfg := &Config{
-        DB:       LoadDBConfig(),
-        HTTP:     LoadHTTPConfig(),
-        Ethereum: LoadEthConfig(),
-        LogLevel: getEnv("LOG_LEVEL", "info"),
+        DB:          LoadDBConfig(),
+        HTTP:        LoadHTTPConfig(),
+        Ethereum:    LoadEthConfig(),
+        Rarity:      LoadRarityConfig(),
+        Auth:        LoadAuthConfig(),
+        RateLimit:   LoadRateLimitConfig(),
+        Idempotency: LoadIdempotencyConfig(),
+        Events:      LoadEventsConfig(),
+        Webhook:     LoadWebhookConfig(),
+        Intent:      LoadIntentConfig(),
+        Reconcile:   LoadReconcileConfig(),
+        Listing:     LoadListingConfig(),
+        Expiry:      LoadExpiryConfig(),
+        LogLevel:    getEnv("LOG_LEVEL", "info"),
+        Demo:        getEnv("DEMO_MODE", "false") == "true",
     }
     return cfg
 }
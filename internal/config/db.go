@@ -7,8 +7,9 @@ import (
 )
 
 type DBConfig struct {
-	User           string
-	Password       string
+	User     string
+	Password string
+	// Driver is "postgres" or "sqlite", for which Name is the database file.
 	Driver         string
 	Name           string
 	Host           string
@@ -18,6 +19,8 @@ type DBConfig struct {
 	DBMaxIdleConns int
 	DBConnMaxLife  int
 	AppEnv         string
+	// MigrateOnStart applies pending migrations when the API starts.
+	MigrateOnStart bool
 }
 
 func LoadDBConfig() *DBConfig {
@@ -39,7 +42,7 @@ func LoadDBConfig() *DBConfig {
 	return &DBConfig{
 		User:           os.Getenv("DB_USER"),
 		Password:       os.Getenv("DB_PASSWORD"),
-		Driver:         os.Getenv("DB_DRIVER"),
+		Driver:         getEnv("DB_DRIVER", "postgres"),
 		Name:           os.Getenv("DB_NAME"),
 		Host:           os.Getenv("DB_HOST"),
 		Port:           os.Getenv("DB_PORT"),
@@ -47,6 +50,7 @@ func LoadDBConfig() *DBConfig {
 		DBMaxOpenConns: maxOpenConns,
 		DBMaxIdleConns: maxIdleConns,
 		DBConnMaxLife:  connMaxLife,
+		MigrateOnStart: getEnv("DB_MIGRATE_ON_START", "true") == "true",
 		// AppEnv:         os.Getenv("GIN_MODE"),
 	}
 }
@@ -0,0 +1,24 @@
+package config
+
+import "time"
+
+type EventsConfig struct {
+	// Buffer is how many events a stream may fall behind by before it is
+	// disconnected to resume from the event log.
+	Buffer int
+	// MaxSubscribers caps the open event streams per replica.
+	MaxSubscribers int
+	// Heartbeat is how often idle streams are pinged.
+	Heartbeat time.Duration
+	// Retention is how long events are kept for resuming streams.
+	Retention time.Duration
+}
+
+func LoadEventsConfig() *EventsConfig {
+	return &EventsConfig{
+		Buffer:         int(getInt64("EVENTS_BUFFER", 256)),
+		MaxSubscribers: int(getInt64("EVENTS_MAX_SUBSCRIBERS", 1000)),
+		Heartbeat:      getDuration("EVENTS_HEARTBEAT", 30*time.Second),
+		Retention:      getDuration("EVENTS_RETENTION", 7*24*time.Hour),
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+type ExpiryConfig struct {
+	// OrderTTL is how long an order stays pending before it expires; 0
+	// keeps orders pending until confirmed or failed.
+	OrderTTL time.Duration
+	// Reservation is how long creating an order reserves its listing for
+	// the buyer, extended to the order's expiry if that is later; 0
+	// disables reservations.
+	Reservation time.Duration
+	// Interval is how often expired listings and orders are moved to
+	// EXPIRED; 0 disables it, though they still cannot be ordered or
+	// confirmed.
+	Interval time.Duration
+}
+
+func LoadExpiryConfig() *ExpiryConfig {
+	return &ExpiryConfig{
+		OrderTTL:    getDuration("ORDER_TTL", 30*time.Minute),
+		Reservation: getDuration("RESERVATION_TTL", 15*time.Minute),
+		Interval:    getDuration("EXPIRY_INTERVAL", time.Minute),
+	}
+}
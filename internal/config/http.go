@@ -1,11 +1,64 @@
 package config
 
+import (
+    "log"
+    "strconv"
+    "time"
+)
+
 type HTTPConfig struct {
     Port string
+    CORS CORSConfig
+    // ContentSecurityPolicy is sent on every API response.
+    ContentSecurityPolicy string
+    // HSTSMaxAge is sent in Strict-Transport-Security on HTTPS requests; zero disables it.
+    HSTSMaxAge   time.Duration
+    MaxBodyBytes int64
+}
+
+type CORSConfig struct {
+    // AllowedOrigins are exact origins or wildcard subdomains such as
+    // https://*.example.com; "*" allows any origin without credentials.
+    AllowedOrigins   []string
+    AllowedMethods   []string
+    AllowedHeaders   []string
+    ExposedHeaders   []string
+    AllowCredentials bool
+    MaxAge           time.Duration
 }
 
 func LoadHTTPConfig() *HTTPConfig {
     return &HTTPConfig{
         Port: getEnv("PORT", "8080"),
+        CORS: CORSConfig{
+            AllowedOrigins:   getListOr("CORS_ALLOWED_ORIGINS", "http://localhost:5173"),
+            AllowedMethods:   getListOr("CORS_ALLOWED_METHODS", "GET,POST,PATCH,DELETE,OPTIONS"),
+            AllowedHeaders:   getListOr("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-API-Key,Idempotency-Key"),
+            ExposedHeaders:   getListOr("CORS_EXPOSED_HEADERS", "RateLimit-Policy,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"),
+            AllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "false") == "true",
+            MaxAge:           getDuration("CORS_MAX_AGE", 10*time.Minute),
+        },
+        ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+        HSTSMaxAge:            getDuration("HSTS_MAX_AGE", 180*24*time.Hour),
+        MaxBodyBytes:          getInt64("MAX_BODY_BYTES", 1<<20),
+    }
+}
+
+func getListOr(key, fallback string) []string {
+    if values := getList(key); len(values) > 0 {
+        return values
     }
-}
\ No newline at end of file
+    return getListValue(fallback)
+}
+
+func getInt64(key string, fallback int64) int64 {
+    value := getEnv(key, "")
+    if value == "" {
+        return fallback
+    }
+    n, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+        log.Fatalf("Invalid %s: %v", key, err)
+    }
+    return n
+}
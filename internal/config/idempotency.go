@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+type IdempotencyConfig struct {
+	// Backend is "postgres" (the database, whatever its driver, shared across
+	// replicas) or "memory".
+	Backend string
+	// TTL is how long a key and its stored response are kept.
+	TTL time.Duration
+	// Wait is how long a duplicate of an in-flight request waits for it
+	// before getting 409.
+	Wait time.Duration
+	// LockTimeout is after how long an in-flight key that its request stopped
+	// extending, e.g. because its replica crashed, is considered abandoned.
+	LockTimeout time.Duration
+}
+
+func LoadIdempotencyConfig() *IdempotencyConfig {
+	return &IdempotencyConfig{
+		Backend:     getEnv("IDEMPOTENCY_BACKEND", "postgres"),
+		TTL:         getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		Wait:        getDuration("IDEMPOTENCY_WAIT", 10*time.Second),
+		LockTimeout: getDuration("IDEMPOTENCY_LOCK_TIMEOUT", 5*time.Minute),
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+type IntentConfig struct {
+	// Wait is how long a request waits for its transaction to be mined
+	// before answering with the pending intent.
+	Wait time.Duration
+	// Lease is how long a worker holds an intent; an intent whose worker
+	// died is picked up again once it runs out.
+	Lease time.Duration
+	// PollInterval is how often open intents are checked.
+	PollInterval time.Duration
+	// MaxAttempts is after how many failed broadcasts an intent fails, or
+	// for a burn sent by the user's wallet, how many waits for its receipt.
+	MaxAttempts int
+}
+
+func LoadIntentConfig() *IntentConfig {
+	return &IntentConfig{
+		Wait:         getDuration("INTENT_WAIT", 30*time.Second),
+		Lease:        getDuration("INTENT_LEASE", 2*time.Minute),
+		PollInterval: getDuration("INTENT_POLL_INTERVAL", 5*time.Second),
+		MaxAttempts:  int(getInt64("INTENT_MAX_ATTEMPTS", 5)),
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+type ListingConfig struct {
+	// CheckInterval is how often open listings are validated against the
+	// chain; 0 disables the periodic checks.
+	CheckInterval time.Duration
+	// MaxAge is how long a check holds: listings checked longer ago are
+	// checked again when read or ordered. 0 disables checks on read.
+	MaxAge time.Duration
+}
+
+func LoadListingConfig() *ListingConfig {
+	return &ListingConfig{
+		CheckInterval: getDuration("LISTING_CHECK_INTERVAL", 10*time.Minute),
+		MaxAge:        getDuration("LISTING_CHECK_MAX_AGE", time.Minute),
+	}
+}
@@ -12,6 +12,11 @@ type EthConfig struct {
 	SellerPrivateKey string
 	BuyerPrivateKey  string
 	ChainID         int64
+	ChainName       string
+	NativeCurrency  string
+	// MulticallAddress is a Multicall3 deployment used to batch reads; when
+	// empty or not deployed, reads are made one by one.
+	MulticallAddress string
 }
 
 func LoadEthConfig() *EthConfig {
@@ -24,5 +29,8 @@ func LoadEthConfig() *EthConfig {
 		SellerPrivateKey: getEnv("SELLER_PRIVATE_KEY", ""),
 		BuyerPrivateKey:  getEnv("BUYER_PRIVATE_KEY", ""),
 		ChainID:         chainID,
+		ChainName:       getEnv("CHAIN_NAME", "Qubetics"),
+		NativeCurrency:  getEnv("NATIVE_CURRENCY", "ETH"),
+		MulticallAddress: getEnv("MULTICALL_ADDRESS", "0xcA11bde05977b3631167028862bE2a173976CA11"),
 	}
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+type RarityConfig struct {
+	Method string
+	// Interval is how often collections whose tokens changed are rescored.
+	Interval time.Duration
+}
+
+func LoadRarityConfig() *RarityConfig {
+	return &RarityConfig{
+		Method:   getEnv("RARITY_METHOD", "statistical"),
+		Interval: getDuration("RARITY_INTERVAL", 5*time.Second),
+	}
+}
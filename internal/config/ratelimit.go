@@ -0,0 +1,39 @@
+package config
+
+import (
+	"log"
+
+	"github.com/user/nft-marketplace/internal/platform/ratelimit"
+)
+
+type RateLimitConfig struct {
+	// Backend is "memory", "postgres" (shared across replicas) or "off".
+	Backend string
+	Limits  map[ratelimit.Class]ratelimit.Limit
+	// TrustedProxies are the addresses or CIDRs of the proxies whose
+	// X-Forwarded-For gives the client IP requests are limited by. By
+	// default none is trusted and the client IP is the peer's address.
+	TrustedProxies []string
+}
+
+func LoadRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Backend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+		Limits: map[ratelimit.Class]ratelimit.Limit{
+			ratelimit.ClassRead:  getLimit("RATE_LIMIT_READ", "300/m"),
+			ratelimit.ClassWrite: getLimit("RATE_LIMIT_WRITE", "60/m"),
+			ratelimit.ClassMint:  getLimit("RATE_LIMIT_MINT", "10/m"),
+			ratelimit.ClassChain: getLimit("RATE_LIMIT_CHAIN", "20/m"),
+			ratelimit.ClassAuth:  getLimit("RATE_LIMIT_AUTH", "600/m"),
+		},
+		TrustedProxies: getList("TRUSTED_PROXIES"),
+	}
+}
+
+func getLimit(key, fallback string) ratelimit.Limit {
+	limit, err := ratelimit.ParseLimit(getEnv(key, fallback))
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return limit
+}
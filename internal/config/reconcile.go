@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+type ReconcileConfig struct {
+	// Interval is how often NFT owners are compared with the chain; 0
+	// disables the scheduled runs.
+	Interval time.Duration
+	// AutoFix corrects the drift found; otherwise it is only reported.
+	AutoFix bool
+}
+
+func LoadReconcileConfig() *ReconcileConfig {
+	return &ReconcileConfig{
+		Interval: getDuration("RECONCILE_INTERVAL", time.Hour),
+		AutoFix:  getEnv("RECONCILE_AUTO_FIX", "true") == "true",
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import "time"
+
+type WebhookConfig struct {
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration
+	// MaxAttempts is after how many failed attempts a delivery is dead.
+	MaxAttempts int
+	// RetryBase is the delay before the first retry; it doubles with every
+	// further attempt up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// PollInterval is how often the outbox and due deliveries are checked.
+	PollInterval time.Duration
+	// Concurrency is how many deliveries a replica sends at once.
+	Concurrency int
+	// Retention is how long finished deliveries are kept in the log.
+	Retention time.Duration
+	// AllowHTTP permits plain http webhook URLs, e.g. for local development.
+	AllowHTTP bool
+	// AllowPrivate permits webhooks to loopback, private and link-local
+	// addresses, e.g. a receiver on localhost during development.
+	AllowPrivate bool
+}
+
+func LoadWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		Timeout:      getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:  int(getInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		RetryBase:    getDuration("WEBHOOK_RETRY_BASE", 30*time.Second),
+		RetryMax:     getDuration("WEBHOOK_RETRY_MAX", 6*time.Hour),
+		PollInterval: getDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		Concurrency:  int(getInt64("WEBHOOK_CONCURRENCY", 8)),
+		Retention:    getDuration("WEBHOOK_RETENTION", 30*24*time.Hour),
+		AllowHTTP:    getEnv("WEBHOOK_ALLOW_HTTP", "false") == "true",
+		AllowPrivate: getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true",
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import "time"
+
+// APIScope limits what an API key may do on behalf of its user. The user's
+// role still applies on top of the key's scopes.
+type APIScope string
+
+const (
+	ScopeRead     APIScope = "read"
+	ScopeList     APIScope = "list"
+	ScopeTrade    APIScope = "trade"
+	ScopeMint     APIScope = "mint"
+	ScopeWebhooks APIScope = "webhooks"
+)
+
+func ParseScopes(values []string) ([]APIScope, error) {
+	scopes := make([]APIScope, 0, len(values))
+	for _, v := range values {
+		switch s := APIScope(v); s {
+		case ScopeRead, ScopeList, ScopeTrade, ScopeMint, ScopeWebhooks:
+			scopes = append(scopes, s)
+		default:
+			return nil, Validation("invalid_scope", "unknown scope %q", v)
+		}
+	}
+	return scopes, nil
+}
+
+// APIKey authenticates a server-to-server integration as its user. Only a
+// hash of the key is stored; Prefix identifies the key in listings.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     []APIScope `gorm:"type:text;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UsageCount int64      `gorm:"not null;default:0" json:"usage_count"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope APIScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyUsage counts the requests made with a key per UTC day.
+type APIKeyUsage struct {
+	APIKeyID uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Day      time.Time `gorm:"primaryKey;type:date" json:"day"`
+	Requests int64     `gorm:"not null;default:0" json:"requests"`
+}
@@ -0,0 +1,52 @@
+package core
+
+import "time"
+
+// AuthNonce is a single-use nonce handed out for a SIWE message.
+type AuthNonce struct {
+	ID        uint      `gorm:"primaryKey"`
+	Nonce     string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Session is one refresh token of a login. Refreshing rotates the token:
+// the old session is revoked and replaced by a new one in the same family,
+// so presenting a rotated token again reveals reuse and revokes the family.
+type Session struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID         string     `gorm:"not null;index" json:"family_id"`
+	RefreshTokenHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	UserAgent        string     `json:"user_agent"`
+	IP               string     `json:"ip"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID     *uint      `json:"replaced_by_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Principal is the authenticated actor of a request, signed in either with a
+// session or with an API key.
+type Principal struct {
+	UserID    uint
+	SessionID uint
+	Role      Role
+	APIKey    *APIKey
+}
+
+// HasScope reports whether the principal may act within scope; sessions
+// carry every scope.
+func (p *Principal) HasScope(scope APIScope) bool {
+	return p.APIKey == nil || p.APIKey.HasScope(scope)
+}
+
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int       `json:"expires_in"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             *User     `json:"user"`
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrNonceUsed means another transaction took a signed transaction's
+	// nonce, so it can never be mined and must be signed again.
+	ErrNonceUsed             = errors.New("nonce already used")
+	ErrInsufficientAllowance = errors.New("insufficient token allowance for the marketplace")
+	ErrInsufficientBalance   = errors.New("insufficient token balance")
+)
+
+// SignedTx is a transaction signed but not yet broadcast. Raw is its hex
+// encoding, for broadcasting, possibly again, with SendRaw.
+type SignedTx struct {
+	Hash string
+	Raw  string
+}
+
+// TokenOwner is a token of our NFT contract and the wallet holding it.
+type TokenOwner struct {
+	TokenID string
+	Owner   string
+}
+
+// ChainClient is what the services read from and send to the chain.
+// eth.Client talks to a node and eth.MemoryChain simulates one.
+type ChainClient interface {
+	GetNFTAddress() string
+
+	// SignMint signs, without sending, a mint of a token to to. Burns are
+	// sent by the owner's wallet, which the contract requires.
+	SignMint(to string, tokenURI string) (*SignedTx, error)
+	// SendRaw broadcasts a signed transaction; broadcasting it again is
+	// not an error. It fails with ErrNonceUsed when it can never be mined.
+	SendRaw(raw string) error
+	// Receipt returns the receipt of a mined transaction, or nil if it has
+	// not been mined.
+	Receipt(ctx context.Context, txHash string) (*types.Receipt, error)
+	WaitReceipt(ctx context.Context, txHash string) (*types.Receipt, error)
+	// MintedTokenID reads the id of the token minted by a transaction.
+	MintedTokenID(receipt *types.Receipt) (string, error)
+	// BurnedTokenID reads the id of the token burned by a transaction.
+	BurnedTokenID(receipt *types.Receipt) (string, error)
+
+	OwnersOf(ctx context.Context, tokenIds []string) ([]string, error)
+	MarketplaceApproved(ctx context.Context, tokens []TokenOwner) ([]bool, error)
+	MarketListings(ctx context.Context, tokenIds []string) ([]ListingInfo, error)
+
+	TokenDecimals(token string) (uint8, error)
+	// CheckTokenPayment fails with ErrInsufficientAllowance or
+	// ErrInsufficientBalance when buyer cannot pay price in token.
+	CheckTokenPayment(token, buyer string, price Wei) error
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorKind classifies domain errors; each kind maps to one HTTP status.
+type ErrorKind string
+
+const (
+	KindValidation    ErrorKind = "validation"
+	KindUnauthorized  ErrorKind = "unauthorized"
+	KindForbidden     ErrorKind = "forbidden"
+	KindNotFound      ErrorKind = "not_found"
+	KindConflict      ErrorKind = "conflict"
+	KindInvalidState  ErrorKind = "invalid_state"
+	KindUnprocessable ErrorKind = "unprocessable"
+	KindTooLarge      ErrorKind = "too_large"
+	KindRateLimited   ErrorKind = "rate_limited"
+	KindChain         ErrorKind = "chain_failure"
+	KindUnavailable   ErrorKind = "unavailable"
+	KindInternal      ErrorKind = "internal"
+)
+
+var kindStatus = map[ErrorKind]int{
+	KindValidation:    http.StatusBadRequest,
+	KindUnauthorized:  http.StatusUnauthorized,
+	KindForbidden:     http.StatusForbidden,
+	KindNotFound:      http.StatusNotFound,
+	KindConflict:      http.StatusConflict,
+	KindInvalidState:  http.StatusConflict,
+	KindUnprocessable: http.StatusUnprocessableEntity,
+	KindTooLarge:      http.StatusRequestEntityTooLarge,
+	KindRateLimited:   http.StatusTooManyRequests,
+	KindChain:         http.StatusBadGateway,
+	KindUnavailable:   http.StatusServiceUnavailable,
+	KindInternal:      http.StatusInternalServerError,
+}
+
+// Status is the HTTP status of errors of kind k.
+func (k ErrorKind) Status() int {
+	if s, ok := kindStatus[k]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a domain error. Code is a stable machine-readable identifier and
+// Message is safe to show to clients; the underlying cause, which may hold
+// internal details, is kept in Err for logging only.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// RetryAt is when the state behind the error is expected to clear, such
+	// as the end of a reservation; zero when unknown.
+	RetryAt time.Time
+	Err     error
+}
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same kind and, if target has one, the same code,
+// so errors.Is(err, core.ErrNotFound) holds for every not-found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && (t.Code == "" || t.Code == e.Code)
+}
+
+// Withf returns a copy of e with a more specific message.
+func (e *Error) Withf(format string, args ...interface{}) *Error {
+	copied := *e
+	copied.Message = fmt.Sprintf(format, args...)
+	return &copied
+}
+
+// RetryingAt returns a copy of e that clears at t.
+func (e *Error) RetryingAt(t time.Time) *Error {
+	copied := *e
+	copied.RetryAt = t
+	return &copied
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+func newError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Kind sentinels, for errors.Is checks against any error of a kind.
+var (
+	ErrValidation   = &Error{Kind: KindValidation}
+	ErrUnauthorized = &Error{Kind: KindUnauthorized}
+	ErrForbidden    = &Error{Kind: KindForbidden}
+	ErrNotFound     = &Error{Kind: KindNotFound}
+	ErrConflict     = &Error{Kind: KindConflict}
+	ErrInvalidState = &Error{Kind: KindInvalidState}
+	ErrChain        = &Error{Kind: KindChain}
+)
+
+func Validation(code, format string, args ...interface{}) *Error {
+	return newError(KindValidation, code, fmt.Sprintf(format, args...))
+}
+
+func Unauthorized(code, message string) *Error {
+	return newError(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return newError(KindForbidden, code, message)
+}
+
+func NotFound(what string) *Error {
+	return newError(KindNotFound, what+"_not_found", what+" not found")
+}
+
+func Conflict(code, message string) *Error {
+	return newError(KindConflict, code, message)
+}
+
+func InvalidState(code, message string) *Error {
+	return newError(KindInvalidState, code, message)
+}
+
+// ChainFailure reports a failed blockchain call; the RPC error is kept as
+// the cause and never shown to clients.
+func ChainFailure(message string, err error) *Error {
+	return &Error{Kind: KindChain, Code: "chain_failure", Message: message, Err: err}
+}
+
+// Internal wraps an unexpected error; clients only see a generic message.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "internal server error", Err: err}
+}
+
+func Unprocessable(code, message string) *Error {
+	return newError(KindUnprocessable, code, message)
+}
+
+func TooLarge(message string) *Error {
+	return newError(KindTooLarge, "too_large", message)
+}
+
+func RateLimited(message string) *Error {
+	return newError(KindRateLimited, "rate_limited", message)
+}
+
+func Unavailable(code, message string) *Error {
+	return newError(KindUnavailable, code, message)
+}
@@ -0,0 +1,102 @@
+package core
+
+import "time"
+
+// EventType names a domain event.
+type EventType string
+
+const (
+	EventListingCreated   EventType = "listing.created"
+	EventListingCancelled EventType = "listing.cancelled"
+	EventListingSold      EventType = "listing.sold"
+	// EventListingInvalidated and EventListingRestored are recorded when
+	// a listing becomes INVALID and when it is active again.
+	EventListingInvalidated EventType = "listing.invalidated"
+	EventListingRestored    EventType = "listing.restored"
+	EventListingExpired     EventType = "listing.expired"
+	EventOrderCreated       EventType = "order.created"
+	EventOrderConfirmed     EventType = "order.confirmed"
+	EventOrderFailed        EventType = "order.failed"
+	EventOrderExpired       EventType = "order.expired"
+	EventTransferIndexed    EventType = "transfer.indexed"
+	EventTxMined            EventType = "tx.mined"
+	EventNFTBurned          EventType = "nft.burned"
+)
+
+var eventTypes = []EventType{
+	EventListingCreated, EventListingCancelled, EventListingSold,
+	EventListingInvalidated, EventListingRestored, EventListingExpired,
+	EventOrderCreated, EventOrderConfirmed, EventOrderFailed, EventOrderExpired,
+	EventTransferIndexed, EventTxMined, EventNFTBurned,
+}
+
+// Event is an entry in the event log, recorded in the same transaction as
+// the change it describes. IDs increase, so clients resume a stream from the
+// last id they saw. The collection, NFT and users are the topics the event is
+// published on; zero when it has none. CounterpartyUserID is the other party
+// of a trade or transfer, such as the buyer of a listing sold by UserID.
+//
+// The log is also the outbox for webhooks: DispatchedAt is set once the
+// event's deliveries have been queued.
+type Event struct {
+	ID                 uint                   `gorm:"primaryKey" json:"id"`
+	Type               EventType              `gorm:"not null;index" json:"type"`
+	CollectionID       uint                   `gorm:"not null;default:0;index" json:"collection_id,omitempty"`
+	NFTID              uint                   `gorm:"not null;default:0;index" json:"nft_id,omitempty"`
+	UserID             uint                   `gorm:"not null;default:0;index" json:"user_id,omitempty"`
+	CounterpartyUserID uint                   `gorm:"not null;default:0;index" json:"counterparty_user_id,omitempty"`
+	Data               map[string]interface{} `gorm:"type:text;serializer:json" json:"data"`
+	CreatedAt          time.Time              `gorm:"index" json:"created_at"`
+	DispatchedAt       *time.Time             `gorm:"index" json:"-"`
+}
+
+// EventFilter selects events by type and topic, and when read from the log,
+// by when they were logged. Zero fields match any event.
+type EventFilter struct {
+	Types        []EventType
+	CollectionID uint
+	NFTID        uint
+	UserID       uint
+	Since        time.Time
+}
+
+func (f EventFilter) Matches(e *Event) bool {
+	if len(f.Types) > 0 && !containsEventType(f.Types, e.Type) {
+		return false
+	}
+	if f.CollectionID != 0 && e.CollectionID != f.CollectionID {
+		return false
+	}
+	if f.NFTID != 0 && e.NFTID != f.NFTID {
+		return false
+	}
+	if f.UserID != 0 && e.UserID != f.UserID && e.CounterpartyUserID != f.UserID {
+		return false
+	}
+	if !f.Since.IsZero() && e.CreatedAt.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// ParseEventTypes validates event type names.
+func ParseEventTypes(names []string) ([]EventType, error) {
+	types := make([]EventType, 0, len(names))
+	for _, n := range names {
+		t := EventType(n)
+		if !containsEventType(eventTypes, t) {
+			return nil, Validation("invalid_event_type", "unknown event type %q", n)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func containsEventType(types []EventType, t EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
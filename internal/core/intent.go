@@ -0,0 +1,62 @@
+package core
+
+import "time"
+
+type IntentKind string
+
+const (
+	IntentMint IntentKind = "MINT"
+	IntentBurn IntentKind = "BURN"
+)
+
+type IntentStatus string
+
+const (
+	// IntentPending intents have no transaction signed yet.
+	IntentPending IntentStatus = "PENDING"
+	// IntentSent intents have a transaction and wait for its receipt: a
+	// signed one, stored before it was broadcast, or for burns one the
+	// owner's wallet sent.
+	IntentSent      IntentStatus = "SENT"
+	IntentConfirmed IntentStatus = "CONFIRMED"
+	IntentFailed    IntentStatus = "FAILED"
+)
+
+// ChainIntent is a chain transaction the API sends on a user's behalf, or
+// for burns one the user's wallet sent. It is stored before anything is
+// sent, and the signed transaction before it is broadcast, so an intent
+// interrupted at any point is finished by rebroadcasting the same
+// transaction or reading its receipt, never by sending a second one. The
+// database changes it makes are applied in the transaction that marks it
+// confirmed.
+type ChainIntent struct {
+	ID     uint         `gorm:"primaryKey" json:"id"`
+	Kind   IntentKind   `gorm:"not null" json:"kind"`
+	Status IntentStatus `gorm:"not null;index" json:"status"`
+	UserID uint         `gorm:"not null;index" json:"user_id"`
+	// NFTID is the NFT burned, or minted once the mint is confirmed.
+	NFTID       *uint       `gorm:"index" json:"nft_id,omitempty"`
+	Mint        *MintIntent `gorm:"type:text;serializer:json" json:"-"`
+	TxHash      string      `json:"tx_hash,omitempty"`
+	RawTx       string      `gorm:"type:text" json:"-"`
+	Attempts    int         `gorm:"not null;default:0" json:"attempts"`
+	Error       string      `json:"error,omitempty"`
+	LockedUntil *time.Time  `json:"-"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// IsOpen reports whether the intent still has work to do.
+func (i *ChainIntent) IsOpen() bool {
+	return i.Status == IntentPending || i.Status == IntentSent
+}
+
+// MintIntent holds what a mint registers once its token id is known.
+type MintIntent struct {
+	To           string         `json:"to"`
+	TokenURI     string         `json:"token_uri"`
+	CollectionID uint           `json:"collection_id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Attributes   []NFTAttribute `json:"attributes"`
+}
@@ -8,30 +8,79 @@ type User struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
 	WalletAddress string    `gorm:"" json:"wallet_address"`
 	Name          string    `json:"name"`
+	Role          Role      `gorm:"not null;default:'user'" json:"role"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 type Collection struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	CreatorUserID uint      `gorm:"not null" json:"creator_user_id"`
-	Name          string    `gorm:"not null" json:"name"`
-	Symbol        string    `gorm:"not null" json:"symbol"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	CreatorUserID uint   `gorm:"not null" json:"creator_user_id"`
+	Name          string `gorm:"not null" json:"name"`
+	Symbol        string `gorm:"not null" json:"symbol"`
+	Verified      bool   `gorm:"not null;default:false" json:"verified"`
+	// RarityStale is set when a token is minted, registered or burned, until
+	// the collection is rescored in the background.
+	RarityStale bool      `gorm:"not null;default:false" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type NFT struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	TokenID         string    `gorm:"not null" json:"token_id"`
-	ContractAddress string    `gorm:"not null" json:"contract_address"`
-	Chain           string    `gorm:"not null" json:"chain"`
-	CollectionID    uint      `gorm:"not null" json:"collection_id"`
-	OwnerUserID     uint      `gorm:"not null" json:"owner_user_id"`
-	MetadataURL     string    `json:"metadata_url"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	TokenID         string     `gorm:"not null" json:"token_id"`
+	ContractAddress string     `gorm:"not null" json:"contract_address"`
+	Chain           string     `gorm:"not null" json:"chain"`
+	CollectionID    uint       `gorm:"not null" json:"collection_id"`
+	OwnerUserID     uint       `gorm:"not null" json:"owner_user_id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	MetadataURL     string     `json:"metadata_url"`
+	RarityScore     float64    `gorm:"not null;default:0" json:"rarity_score"`
+	RarityRank      int        `gorm:"not null;default:0;index" json:"rarity_rank"`
+	BurnedAt        *time.Time `json:"burned_at,omitempty"`
+	// Version increments whenever the owner or burn state changes.
+	Version   uint      `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
 
 	// Relations
-	Collection Collection `gorm:"foreignKey:CollectionID" json:"collection"`
-	Owner      User       `gorm:"foreignKey:OwnerUserID" json:"owner"`
+	Collection Collection     `gorm:"foreignKey:CollectionID" json:"collection"`
+	Owner      User           `gorm:"foreignKey:OwnerUserID" json:"owner"`
+	Attributes []NFTAttribute `gorm:"foreignKey:NFTID" json:"attributes"`
+}
+
+type NFTAttribute struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	NFTID     uint   `gorm:"not null;index" json:"-"`
+	TraitType string `gorm:"not null" json:"trait_type"`
+	Value     string `gorm:"not null" json:"value"`
+}
+
+// TraitCountType is the pseudo trait used to count how many attributes a token has,
+// so that tokens with unusually few or many traits are scored accordingly.
+const TraitCountType = "trait_count"
+
+// TraitCount is the number of live (unburned) tokens in a collection carrying a trait value.
+type TraitCount struct {
+	ID           uint   `gorm:"primaryKey" json:"-"`
+	CollectionID uint   `gorm:"not null;uniqueIndex:idx_trait_count_key" json:"collection_id"`
+	TraitType    string `gorm:"not null;uniqueIndex:idx_trait_count_key" json:"trait_type"`
+	Value        string `gorm:"not null;uniqueIndex:idx_trait_count_key" json:"value"`
+	TokenCount   int    `gorm:"not null;default:0" json:"token_count"`
+}
+
+// Currency is a payment currency accepted for listings on a chain. Native
+// currencies have an empty TokenAddress; others are ERC20 tokens.
+type Currency struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Symbol       string    `gorm:"not null;uniqueIndex:idx_currency_chain_symbol" json:"symbol"`
+	Chain        string    `gorm:"not null;uniqueIndex:idx_currency_chain_symbol" json:"chain"`
+	TokenAddress string    `json:"token_address,omitempty"`
+	Decimals     uint8     `gorm:"not null" json:"decimals"`
+	Enabled      bool      `gorm:"not null" json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (c *Currency) IsNative() bool {
+	return c.TokenAddress == ""
 }
 
 type ListingStatus string
@@ -40,28 +89,78 @@ const (
 	ListingActive    ListingStatus = "ACTIVE"
 	ListingSold      ListingStatus = "SOLD"
 	ListingCancelled ListingStatus = "CANCELLED"
+	// ListingInvalid is a listing the chain no longer backs; it is active
+	// again once the reason goes away.
+	ListingInvalid ListingStatus = "INVALID"
+	ListingExpired ListingStatus = "EXPIRED"
+)
+
+// Reasons a listing is INVALID.
+const (
+	InvalidSellerNotOwner = "seller_not_owner"
+	InvalidNotApproved    = "marketplace_not_approved"
+	InvalidNotListed      = "not_listed_on_chain"
 )
 
 type Listing struct {
 	ID           uint          `gorm:"primaryKey" json:"id"`
 	NFTID        uint          `gorm:"not null" json:"nft_id"`
 	SellerUserID uint          `gorm:"not null" json:"seller_user_id"`
-	PriceWei     string        `gorm:"not null" json:"price_wei"`
+	PriceWei     Wei           `gorm:"not null" json:"price_wei"`
+	Price        Amount        `gorm:"-" json:"price"`
 	Currency     string        `gorm:"default:'ETH'" json:"currency"`
 	Status       ListingStatus `gorm:"default:'ACTIVE'" json:"status"`
-	CreatedAt    time.Time     `json:"created_at"`
+	// InvalidReason tells why an INVALID listing cannot be bought.
+	InvalidReason string `json:"invalid_reason,omitempty"`
+	// CheckedAt is when the listing was last validated against the chain.
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	// ExpiresAt is when an open listing expires; never when nil.
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	// ReservedByOrderID is the order holding the listing until
+	// ReservedUntil; other buyers cannot order it meanwhile.
+	ReservedByOrderID *uint      `json:"reserved_by_order_id,omitempty"`
+	ReservedUntil     *time.Time `gorm:"index" json:"reserved_until,omitempty"`
+	// Version increments whenever the status or reservation changes.
+	Version   uint      `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
 
 	// Relations
 	NFT    NFT  `gorm:"foreignKey:NFTID" json:"nft"`
 	Seller User `gorm:"foreignKey:SellerUserID" json:"seller"`
 }
 
+// IsOpen tells whether the listing can still be sold or cancelled.
+func (l *Listing) IsOpen() bool {
+	return l.Status == ListingActive || l.Status == ListingInvalid
+}
+
+// ReservedByOther tells whether an order other than orderID holds the
+// listing at now.
+func (l *Listing) ReservedByOther(orderID uint, now time.Time) bool {
+	return l.ReservedByOrderID != nil && *l.ReservedByOrderID != orderID &&
+		l.ReservedUntil != nil && now.Before(*l.ReservedUntil)
+}
+
+// ListingReserved is the error for buyers of a listing another order holds
+// until the given time.
+func ListingReserved(until time.Time) *Error {
+	return Conflict("listing_reserved", "listing is reserved by another order until "+until.UTC().Format(time.RFC3339)).
+		RetryingAt(until)
+}
+
+// IsExpired tells whether the listing is past its expiry, even if it has not
+// been moved to EXPIRED yet.
+func (l *Listing) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
 type OrderStatus string
 
 const (
 	OrderPending   OrderStatus = "PENDING"
 	OrderConfirmed OrderStatus = "CONFIRMED"
 	OrderFailed    OrderStatus = "FAILED"
+	OrderExpired   OrderStatus = "EXPIRED"
 )
 
 type Order struct {
@@ -70,9 +169,19 @@ type Order struct {
 	BuyerUserID uint        `gorm:"not null" json:"buyer_user_id"`
 	TxHash      *string     `json:"tx_hash"`
 	Status      OrderStatus `gorm:"default:'PENDING'" json:"status"`
-	CreatedAt   time.Time   `json:"created_at"`
+	// ExpiresAt is when a pending order expires; never when nil.
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	// Version increments whenever the status changes.
+	Version   uint      `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time `json:"created_at"`
 
 	// Relations
 	Listing Listing `gorm:"foreignKey:ListingID" json:"listing"`
 	Buyer   User    `gorm:"foreignKey:BuyerUserID" json:"buyer"`
 }
+
+// IsExpired tells whether the order is past its expiry, even if it has not
+// been moved to EXPIRED yet.
+func (o *Order) IsExpired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
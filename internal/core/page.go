@@ -0,0 +1,60 @@
+package core
+
+var (
+	ErrInvalidSort   = newError(KindValidation, "invalid_sort", "invalid sort")
+	ErrInvalidCursor = newError(KindValidation, "invalid_cursor", "invalid cursor")
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest asks for one page of a list endpoint. Sort is a sort key,
+// prefixed with "-" for descending order; Cursor is the NextCursor of the
+// previous page.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ListingFilter struct {
+	MinPrice     *Wei
+	MaxPrice     *Wei
+	Currency     string
+	SellerID     uint
+	CollectionID uint
+	Chain        string
+	// IncludeInvalid returns INVALID listings along with the active ones.
+	IncludeInvalid bool
+}
+
+type NFTFilter struct {
+	OwnerID      uint
+	CollectionID uint
+	Chain        string
+}
+
+type CollectionFilter struct {
+	CreatorID uint
+}
+
+type UserFilter struct {
+	Role Role
+}
+
+type AuditFilter struct {
+	ActorID    uint
+	TargetType string
+	TargetID   uint
+}
+
+type DeliveryFilter struct {
+	Status DeliveryStatus
+}
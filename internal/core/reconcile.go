@@ -0,0 +1,40 @@
+package core
+
+import "time"
+
+// ReconciliationRun is one pass comparing the recorded owner of every NFT
+// minted by our contract with its owner on chain.
+type ReconciliationRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	StartedAt  time.Time  `gorm:"not null;index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// StartedByUserID is the admin who started the run; scheduled runs
+	// have none.
+	StartedByUserID *uint `json:"started_by_user_id,omitempty"`
+	// AutoFix tells whether drift was corrected or only reported.
+	AutoFix bool `gorm:"not null" json:"auto_fix"`
+	Checked int  `gorm:"not null;default:0" json:"checked"`
+	Drifted int  `gorm:"not null;default:0" json:"drifted"`
+	Fixed   int  `gorm:"not null;default:0" json:"fixed"`
+	// ListingsCancelled counts open listings whose seller no longer owns
+	// the token or no longer lets the marketplace transfer it.
+	ListingsCancelled int    `gorm:"not null;default:0" json:"listings_cancelled"`
+	Error             string `json:"error,omitempty"`
+
+	// Relations
+	Drifts []OwnershipDrift `gorm:"foreignKey:RunID" json:"drifts,omitempty"`
+}
+
+// OwnershipDrift is an NFT whose recorded owner differed from the chain.
+// ChainOwner is empty when the token no longer exists on chain.
+type OwnershipDrift struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RunID           uint      `gorm:"not null;index" json:"run_id"`
+	NFTID           uint      `gorm:"not null;index" json:"nft_id"`
+	TokenID         string    `gorm:"not null" json:"token_id"`
+	RecordedOwnerID uint      `gorm:"not null" json:"recorded_owner_id"`
+	ChainOwner      string    `json:"chain_owner,omitempty"`
+	NewOwnerID      *uint     `json:"new_owner_id,omitempty"`
+	Fixed           bool      `gorm:"not null" json:"fixed"`
+	CreatedAt       time.Time `json:"created_at"`
+}
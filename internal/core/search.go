@@ -0,0 +1,52 @@
+package core
+
+type SearchQuery struct {
+	Text         string
+	CollectionID uint
+	Chain        string
+	// Status is "listed", "unlisted" or empty for both.
+	Status   string
+	Traits   []NFTAttribute
+	MinPrice *Wei
+	MaxPrice *Wei
+	Limit    int
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type TraitFacet struct {
+	TraitType string `json:"trait_type"`
+	Value     string `json:"value"`
+	Count     int64  `json:"count"`
+}
+
+// PriceBucket is a half-open wei range [Min, Max); a nil Max is unbounded.
+type PriceBucket struct {
+	Min   Wei   `json:"min_wei"`
+	Max   *Wei  `json:"max_wei,omitempty"`
+	Count int64 `json:"count"`
+}
+
+type Facets struct {
+	Collections   []FacetCount  `json:"collections"`
+	Chains        []FacetCount  `json:"chains"`
+	Traits        []TraitFacet  `json:"traits"`
+	ListingStatus []FacetCount  `json:"listing_status"`
+	PriceBuckets  []PriceBucket `json:"price_buckets"`
+}
+
+type SearchResult struct {
+	NFTs        []NFT        `json:"nfts"`
+	Collections []Collection `json:"collections"`
+	Facets      Facets       `json:"facets"`
+}
+
+type Suggestion struct {
+	Type  string `json:"type"`
+	ID    uint   `json:"id"`
+	Label string `json:"label"`
+}
@@ -0,0 +1,168 @@
+package core
+
+import "time"
+
+// The stores persist the marketplace's state. repository.Repository keeps it
+// in a SQL database and repository.MemoryRepository in process memory; both
+// honour the same contracts, documented on the SQL implementation.
+
+type UserStore interface {
+	CreateUser(user *User) error
+	GetUserByID(id uint) (*User, error)
+	GetUserByWallet(wallet string) (*User, error)
+	FindOrCreateUserByWallet(wallet string) (*User, error)
+	ListUsers(filter UserFilter, page PageRequest) (*Page[User], error)
+	// SetUserRole logs the change as made by actorID, or by the system
+	// when it is nil.
+	SetUserRole(actorID *uint, userID uint, role Role, reason string) (*User, error)
+}
+
+type CollectionStore interface {
+	CreateCollection(collection *Collection) error
+	ListCollections(filter CollectionFilter, page PageRequest) (*Page[Collection], error)
+	FindCollectionByOwner(ownerID uint) (*Collection, error)
+	FindCollectionByName(ownerID uint, name string) (*Collection, error)
+	SetCollectionVerified(actorID, collectionID uint, verified bool, reason string) (*Collection, error)
+}
+
+type NFTStore interface {
+	CreateNFT(nft *NFT) error
+	GetNFTByID(id uint) (*NFT, error)
+	ListNFTs(filter NFTFilter, page PageRequest) (*Page[NFT], error)
+	ListTraitCounts(collectionID uint) ([]TraitCount, error)
+	ListCollectionNFTs(collectionID uint) ([]NFT, error)
+	UpdateNFTRarity(scores map[uint]float64, ranks map[uint]int) error
+	// ClaimStaleRarity clears the stale rarity flag of up to limit
+	// collections and returns their ids; MarkRarityStale sets it again.
+	ClaimStaleRarity(limit int) ([]uint, error)
+	MarkRarityStale(collectionID uint) error
+	ListChainNFTs(chain, contract string, afterID uint, limit int) ([]NFT, error)
+}
+
+type CurrencyStore interface {
+	CreateCurrency(currency *Currency) error
+	EnsureCurrency(currency *Currency) error
+	GetCurrency(chain, symbol string) (*Currency, error)
+	ListCurrencies(chain string) ([]Currency, error)
+	SetCurrencyEnabled(id uint, enabled bool) (*Currency, error)
+}
+
+type ListingStore interface {
+	CreateListing(listing *Listing) error
+	GetListingByID(id uint) (*Listing, error)
+	ListActiveListings(filter ListingFilter, page PageRequest) (*Page[Listing], error)
+	UpdateListingStatus(listing *Listing, status ListingStatus) error
+	ForceCancelListing(actorID, listingID uint, reason string) error
+	ListChainListings(chain, contract string, afterID uint, limit int) ([]Listing, error)
+	SetListingValidity(listing *Listing, reason string) error
+	ExpireListings(now time.Time) (int, error)
+	ReleaseReservations(now time.Time) (int, error)
+}
+
+type OrderStore interface {
+	CreateOrder(order *Order, reservedUntil *time.Time) error
+	GetOrderByID(id uint) (*Order, error)
+	// ConfirmOrder marks the order confirmed, its listing sold and the NFT
+	// owned by the buyer, all or nothing.
+	ConfirmOrder(orderID uint, txHash string) error
+	MarkOrderFailed(actorID, orderID uint, reason string) error
+	ExpireOrders(now time.Time) (int, error)
+}
+
+type IntentStore interface {
+	CreateIntent(intent *ChainIntent, lease time.Duration) error
+	GetIntent(userID, id uint) (*ChainIntent, error)
+	ClaimIntents(limit int, lease time.Duration) ([]ChainIntent, error)
+	ReleaseIntent(id uint) error
+	SaveIntentTx(intent *ChainIntent, txHash, rawTx string) error
+	ResetIntent(intent *ChainIntent, reason string) error
+	RecordIntentAttempt(intent *ChainIntent, reason string) error
+	FailIntent(intent *ChainIntent, reason string) error
+	ConfirmMintIntent(intent *ChainIntent, contract, chain, tokenID string) (*NFT, error)
+	ConfirmBurnIntent(intent *ChainIntent) (*NFT, error)
+}
+
+type ReconciliationStore interface {
+	CreateReconciliationRun(run *ReconciliationRun) error
+	FinishReconciliationRun(run *ReconciliationRun) error
+	ListReconciliationRuns(page PageRequest) (*Page[ReconciliationRun], error)
+	GetReconciliationRun(id uint) (*ReconciliationRun, error)
+	RecordDrift(drift *OwnershipDrift) error
+	FixTransferredNFT(drift *OwnershipDrift, newOwnerID uint) (int, error)
+	FixBurnedNFT(drift *OwnershipDrift) (int, error)
+	// CancelUnapprovedListings cancels the open listings of an NFT by
+	// ownerID, who no longer lets the marketplace transfer it, and returns
+	// how many it cancelled.
+	CancelUnapprovedListings(nftID, ownerID uint) (int, error)
+}
+
+type SearchStore interface {
+	Search(q SearchQuery) (*SearchResult, error)
+	Suggest(text string, limit int) ([]Suggestion, error)
+}
+
+type AuditStore interface {
+	ListAuditLog(filter AuditFilter, page PageRequest) (*Page[AuditLog], error)
+}
+
+type SessionStore interface {
+	CreateAuthNonce(nonce *AuthNonce) error
+	ConsumeAuthNonce(nonce string) error
+	CreateSession(session *Session) error
+	GetSession(id uint) (*Session, error)
+	GetSessionByRefreshHash(hash string) (*Session, error)
+	RotateSession(old, next *Session) error
+	RevokeSession(id uint) error
+	RevokeSessionFamily(familyID string) error
+	RevokeUserSessions(userID uint) error
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(key *APIKey) error
+	GetAPIKeyByHash(hash string) (*APIKey, error)
+	GetAPIKey(userID, id uint) (*APIKey, error)
+	ListAPIKeys(userID uint) ([]APIKey, error)
+	RevokeAPIKey(userID, id uint) error
+	RecordAPIKeyUse(id uint, now time.Time) error
+	ListAPIKeyUsage(id uint, since time.Time) ([]APIKeyUsage, error)
+}
+
+type EventStore interface {
+	RecordEvents(events ...*Event) error
+	ListEvents(filter EventFilter, afterID uint, limit int) ([]Event, error)
+	PruneEvents(cutoff time.Time) error
+}
+
+type WebhookStore interface {
+	CreateWebhook(hook *Webhook) error
+	CountWebhooks(userID uint) (int64, error)
+	GetWebhook(owner WebhookOwner, id uint) (*Webhook, error)
+	ListWebhooks(owner WebhookOwner) ([]Webhook, error)
+	DeleteWebhook(owner WebhookOwner, id uint) error
+	ListWebhookDeliveries(webhookID uint, filter DeliveryFilter, page PageRequest) (*Page[WebhookDelivery], error)
+	GetWebhookDelivery(owner WebhookOwner, id uint) (*WebhookDelivery, error)
+	CreateWebhookDelivery(delivery *WebhookDelivery) error
+	DispatchEvents(limit int) (int, error)
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]WebhookDelivery, error)
+	RecordWebhookAttempt(delivery *WebhookDelivery, attempt *WebhookAttempt) error
+	PruneWebhookDeliveries(cutoff time.Time) error
+}
+
+// Store is everything the services persist.
+type Store interface {
+	UserStore
+	CollectionStore
+	NFTStore
+	CurrencyStore
+	ListingStore
+	OrderStore
+	IntentStore
+	ReconciliationStore
+	SearchStore
+	AuditStore
+	SessionStore
+	APIKeyStore
+	EventStore
+	WebhookStore
+	Ping() error
+}
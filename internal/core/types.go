@@ -14,12 +14,12 @@ type ApproveRequest struct {
 
 type ListRequest struct {
 	TokenID  string `json:"token_id" binding:"required"`
-	PriceWei string `json:"price_wei" binding:"required"`
+	PriceWei *Wei   `json:"price_wei" binding:"required"`
 }
 
 type BuyRequest struct {
 	TokenID  string `json:"token_id" binding:"required"`
-	PriceWei string `json:"price_wei" binding:"required"`
+	PriceWei *Wei   `json:"price_wei" binding:"required"`
 }
 
 type BurnRequest struct {
@@ -44,10 +44,11 @@ type AddressResponse struct {
 }
 
 type ListingInfo struct {
-	TokenID string `json:"token_id"`
-	Price   string `json:"price"`
-	Seller  string `json:"seller"`
-	Active  bool   `json:"active"`
+	TokenID  string `json:"token_id"`
+	Price    Wei    `json:"price"`
+	Seller   string `json:"seller"`
+	Active   bool   `json:"active"`
+	Currency string `json:"currency,omitempty"` // ERC20 address; empty for the native currency
 }
 
 type NFTOwnerResponse struct {
@@ -0,0 +1,82 @@
+package core
+
+import "time"
+
+// Webhook posts the events of the given types to URL, signed with Secret.
+// Webhooks created with an API key belong to that key and stop receiving
+// events once it is revoked or expires.
+type Webhook struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	UserID     uint        `gorm:"not null;index" json:"user_id"`
+	APIKeyID   *uint       `gorm:"index" json:"api_key_id,omitempty"`
+	URL        string      `gorm:"not null" json:"url"`
+	Secret     string      `gorm:"not null" json:"-"`
+	EventTypes []EventType `gorm:"type:text;serializer:json" json:"event_types"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Matches reports whether the webhook subscribes to e; no types means all.
+func (w *Webhook) Matches(e *Event) bool {
+	return len(w.EventTypes) == 0 || containsEventType(w.EventTypes, e.Type)
+}
+
+// WebhookOwner scopes webhook management: sessions manage all of the user's
+// webhooks, API keys only those created with them.
+type WebhookOwner struct {
+	UserID   uint
+	APIKeyID uint
+}
+
+func (p *Principal) WebhookOwner() WebhookOwner {
+	owner := WebhookOwner{UserID: p.UserID}
+	if p.APIKey != nil {
+		owner.APIKeyID = p.APIKey.ID
+	}
+	return owner
+}
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are retried until they succeed or run out
+	// of attempts.
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliverySucceeded DeliveryStatus = "SUCCEEDED"
+	// DeliveryDead deliveries failed every attempt; they can be redelivered
+	// by hand.
+	DeliveryDead DeliveryStatus = "DEAD"
+)
+
+// WebhookDelivery is one event to be posted to one webhook. Payload is the
+// event as posted, so deliveries outlive the event log.
+type WebhookDelivery struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	WebhookID      uint           `gorm:"not null;index" json:"webhook_id"`
+	EventID        uint           `gorm:"not null" json:"event_id"`
+	EventType      EventType      `gorm:"not null" json:"event_type"`
+	Payload        string         `gorm:"type:text;not null" json:"-"`
+	Status         DeliveryStatus `gorm:"not null;index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time      `gorm:"not null;index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	RedeliveryOf   *uint          `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+
+	// Relations
+	Webhook Webhook          `gorm:"foreignKey:WebhookID" json:"-"`
+	History []WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"history,omitempty"`
+}
+
+// WebhookAttempt logs one attempt at a delivery.
+type WebhookAttempt struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	DeliveryID uint   `gorm:"not null;index" json:"-"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// ResponseBytes is the length of the response body; the body is not kept.
+	ResponseBytes int64     `json:"response_bytes"`
+	DurationMS    int64     `json:"duration_ms"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// NativeDecimals is the number of decimals of the chain's native currency.
+const NativeDecimals = 18
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+var ErrInvalidWei = newError(KindValidation, "invalid_amount", "amount must be a non-negative integer number of wei")
+
+// Wei is an exact, non-negative integer amount in a token's smallest unit.
+// It is stored as NUMERIC(78,0), which holds any uint256, and is encoded in
+// JSON as a decimal string so no precision is lost in JavaScript clients.
+type Wei struct {
+	i *big.Int
+}
+
+func NewWei(i *big.Int) Wei {
+	if i == nil {
+		return Wei{}
+	}
+	return Wei{i: new(big.Int).Set(i)}
+}
+
+func ParseWei(s string) (Wei, error) {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok || i.Sign() < 0 || i.Cmp(maxUint256) > 0 {
+		return Wei{}, ErrInvalidWei.Withf("%s: %q", ErrInvalidWei.Message, s)
+	}
+	return Wei{i: i}, nil
+}
+
+// BigInt returns a copy of the amount.
+func (w Wei) BigInt() *big.Int {
+	if w.i == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(w.i)
+}
+
+func (w Wei) String() string {
+	if w.i == nil {
+		return "0"
+	}
+	return w.i.String()
+}
+
+func (w Wei) Sign() int {
+	if w.i == nil {
+		return 0
+	}
+	return w.i.Sign()
+}
+
+func (w Wei) Cmp(o Wei) int {
+	return w.BigInt().Cmp(o.BigInt())
+}
+
+func (w Wei) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+// UnmarshalJSON accepts a decimal string ("1000") or an integer JSON number (1000).
+func (w *Wei) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseWei(s)
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+func (Wei) GormDataType() string {
+	return "numeric(78,0)"
+}
+
+func (w Wei) Value() (driver.Value, error) {
+	return w.String(), nil
+}
+
+func (w *Wei) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*w = Wei{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Wei", src)
+	}
+	parsed, err := ParseWei(s)
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// FormatUnits renders an amount in whole units, e.g. 1500000000000000000 with
+// 18 decimals becomes "1.5".
+func FormatUnits(w Wei, decimals uint8) string {
+	s := w.String()
+	if decimals == 0 {
+		return s
+	}
+	if len(s) <= int(decimals) {
+		s = strings.Repeat("0", int(decimals)-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-int(decimals)], strings.TrimRight(s[len(s)-int(decimals):], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// Amount is a Wei value together with the decimals of its currency, rendered
+// in responses both in wei and in whole units.
+type Amount struct {
+	Wei      Wei
+	Decimals uint8
+}
+
+func NewAmount(w Wei, decimals uint8) Amount {
+	return Amount{Wei: w, Decimals: decimals}
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Wei      Wei    `json:"wei"`
+		Decimal  string `json:"decimal"`
+		Decimals uint8  `json:"decimals"`
+	}{a.Wei, FormatUnits(a.Wei, a.Decimals), a.Decimals})
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const maxUint256String = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+
+func TestParseWei(t *testing.T) {
+	for _, s := range []string{"0", "1", "1500000000000000000", maxUint256String} {
+		w, err := ParseWei(s)
+		if err != nil || w.String() != s {
+			t.Errorf("ParseWei(%q) = %v, %v", s, w, err)
+		}
+	}
+	for _, s := range []string{"", "-1", "1.5", "1e18", "0x10", " 1", maxUint256String[:len(maxUint256String)-1] + "6"} {
+		if _, err := ParseWei(s); !errors.Is(err, ErrInvalidWei) {
+			t.Errorf("ParseWei(%q): err = %v, want invalid_amount", s, err)
+		}
+	}
+}
+
+func TestWeiJSON(t *testing.T) {
+	var v struct{ Price Wei }
+	for _, body := range []string{`{"Price":"1000"}`, `{"Price":1000}`} {
+		if err := json.Unmarshal([]byte(body), &v); err != nil || v.Price.String() != "1000" {
+			t.Errorf("decode %s: %v, %v", body, v.Price, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"Price":"-5"}`), &v); !errors.Is(err, ErrInvalidWei) {
+		t.Errorf("decode a negative amount: err = %v, want invalid_amount", err)
+	}
+	if b, _ := json.Marshal(v); string(b) != `{"Price":"1000"}` {
+		t.Errorf("encoded %s, want the amount as a string", b)
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		wei      string
+		decimals uint8
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1500000000000000000", 18, "1.5"},
+		{"1000000000000000000", 18, "1"},
+		{"123456789", 6, "123.456789"},
+		{"100000000", 6, "100"},
+		{"42", 0, "42"},
+		{maxUint256String, 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+	for _, tt := range tests {
+		w, _ := ParseWei(tt.wei)
+		if got := FormatUnits(w, tt.decimals); got != tt.want {
+			t.Errorf("FormatUnits(%s, %d) = %s, want %s", tt.wei, tt.decimals, got, tt.want)
+		}
+	}
+	if got := FormatUnits(Wei{}, 18); got != "0" {
+		t.Errorf("FormatUnits of the zero value = %s", got)
+	}
+}
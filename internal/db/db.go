@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"path/filepath"
@@ -12,43 +13,53 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
-	"github.com/user/nft-marketplace/internal/core"
 )
 
-func autoMigrate(db *gorm.DB, models ...interface{}) {
-	for _, m := range models {
-		if err := db.AutoMigrate(m); err != nil {
-			logrus.Fatalf("Failed to migrate %T: %v", m, err)
-		}
-	}
-}
+// The drivers DB_DRIVER selects between.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
 
 func InitDB(cfg *config.DBConfig) *gorm.DB {
-	sqlDB := setupDB(cfg)
+	sqlDB := Connect(cfg)
 
-	gormDB, err := gorm.Open(postgres.New(postgres.Config{
-		Conn: sqlDB,
-	}), &gorm.Config{
+	gormConfig := &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true,
 		},
-	})
+	}
+	var dialector gorm.Dialector = postgres.New(postgres.Config{Conn: sqlDB})
+	if cfg.Driver == DriverSQLite {
+		dialector = sqlite.New(sqlite.Config{DriverName: sqliteDriverName, Conn: sqlDB})
+		// Unique violations come as gorm.ErrDuplicatedKey rather than as
+		// errors of the SQLite driver, which only exist in cgo builds
+		gormConfig.TranslateError = true
+	}
+	gormDB, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		logrus.Fatalf("Failed to open GORM DB: %v", err)
 	}
 
-	if err := gormDB.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`).Error; err != nil {
-		logrus.Fatalf("Failed to enable uuid-ossp extension: %v", err)
+	if cfg.MigrateOnStart {
+		migrator, err := NewMigrator(sqlDB, cfg.Driver)
+		if err != nil {
+			logrus.Fatalf("Failed to load migrations: %v", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			logrus.Fatalf("Failed to migrate the database: %v", err)
+		}
+		for _, m := range applied {
+			logrus.Infof("Applied migration %s", m)
+		}
 	}
 
-	autoMigrate(
-		gormDB, &core.User{}, &core.Collection{}, &core.NFT{}, &core.Listing{}, &core.Order{},
-	)
-
 	if cfg.AppEnv == "debug" {
 		gormDB = gormDB.Debug()
 		logrus.Info("GORM debug mode enabled")
@@ -57,7 +68,19 @@ func InitDB(cfg *config.DBConfig) *gorm.DB {
 	return gormDB
 }
 
-func setupDB(cfg *config.DBConfig) *sql.DB {
+// Connect opens the database described by cfg.
+func Connect(cfg *config.DBConfig) *sql.DB {
+	switch cfg.Driver {
+	case DriverPostgres:
+		return connectPostgres(cfg)
+	case DriverSQLite:
+		return connectSQLite(cfg)
+	}
+	logrus.Fatalf("Unknown DB_DRIVER %q", cfg.Driver)
+	return nil
+}
+
+func connectPostgres(cfg *config.DBConfig) *sql.DB {
 	dataSourceName := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SslMode,
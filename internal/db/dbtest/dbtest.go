@@ -0,0 +1,74 @@
+// Package dbtest opens migrated databases for the tests of the stores and
+// of the services built on them.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLite opens a new SQLite database with every migration applied.
+func SQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	return open(t, &config.DBConfig{
+		Driver:         db.DriverSQLite,
+		Name:           filepath.Join(t.TempDir(), "test.db"),
+		DBMaxOpenConns: 1,
+		DBMaxIdleConns: 1,
+		DBConnMaxLife:  60,
+	})
+}
+
+// Postgres opens the database named by TEST_POSTGRES_DB, reached with the
+// API's DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_SSL, wiped and
+// migrated again. It skips the test when TEST_POSTGRES_DB is not set.
+func Postgres(t testing.TB) *gorm.DB {
+	t.Helper()
+	name := os.Getenv("TEST_POSTGRES_DB")
+	if name == "" {
+		t.Skip("TEST_POSTGRES_DB is not set")
+	}
+	return open(t, &config.DBConfig{
+		Driver:         db.DriverPostgres,
+		Name:           name,
+		Host:           os.Getenv("DB_HOST"),
+		Port:           os.Getenv("DB_PORT"),
+		User:           os.Getenv("DB_USER"),
+		Password:       os.Getenv("DB_PASSWORD"),
+		SslMode:        os.Getenv("DB_SSL"),
+		DBMaxOpenConns: 20,
+		DBMaxIdleConns: 20,
+		DBConnMaxLife:  60,
+	})
+}
+
+// open opens cfg's database with every migration applied afresh.
+func open(t testing.TB, cfg *config.DBConfig) *gorm.DB {
+	t.Helper()
+	gormDB := db.InitDB(cfg)
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := db.NewMigrator(sqlDB, cfg.Driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := migrator.To(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return gormDB.Session(&gorm.Session{Logger: logger.Discard})
+}
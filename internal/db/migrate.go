@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migrations are versioned SQL files embedded in the binary: each version has
+// a <version>_<name>.up.sql applying it and a .down.sql reverting it. Every
+// migration runs in its own transaction with its row in schema_migration.
+// Files are written for Postgres; where SQLite needs other statements, a
+// <version>_<name>.sqlite.up|down.sql replaces the file on SQLite.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)(?:\.(sqlite))?\.(up|down)\.sql$`)
+
+// migrationLockID keys the advisory lock held while migrating, so replicas
+// starting together migrate one at a time.
+const migrationLockID = 4_801_297_311
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is a migration with when it was applied, nil if it was not.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for db, opened with Connect for driver.
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// loadMigrations reads the migrations for driver in fsys's migrations
+// directory, in version order.
+func loadMigrations(fsys fs.FS, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	// replaced are the Postgres files replaced by one for driver
+	replaced := map[string]bool{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name is not <version>_<name>[.sqlite].up|down.sql", entry.Name())
+		}
+		variant, direction := match[3], match[4]
+		if variant != "" && variant != driver {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: named both %s and %s", version, m.Name, match[2])
+		}
+		if variant != "" {
+			replaced[fmt.Sprintf("%s_%s.%s.sql", match[1], match[2], direction)] = true
+		} else if replaced[entry.Name()] {
+			continue
+		}
+		if direction == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s: needs both an up and a down file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns those it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the latest applied migration and returns it, or nil when none
+// is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		latest, ok := latestApplied(applied)
+		if !ok {
+			return nil
+		}
+		reverted, err = m.migrate(ctx, conn, applied, previousVersion(m.migrations, latest))
+		return err
+	})
+	if err != nil || len(reverted) == 0 {
+		return nil, err
+	}
+	return &reverted[0], nil
+}
+
+// To applies or reverts migrations until version is the latest applied, 0
+// reverting them all, and returns those it applied or reverted in order.
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("no migration %d", version)
+	}
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		done, err = m.migrate(ctx, conn, applied, version)
+		return err
+	})
+	return done, err
+}
+
+// Status lists the known migrations, with when each was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			s := MigrationStatus{Migration: migration}
+			if at, ok := applied[migration.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+	return status, err
+}
+
+// migrate reverts the applied migrations after target, latest first, then
+// applies the pending ones up to it.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, applied map[int64]time.Time, target int64) ([]Migration, error) {
+	for version := range applied {
+		if version > target && m.find(version) == nil {
+			return nil, fmt.Errorf("migration %d is applied but unknown to this build", version)
+		}
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+			continue
+		}
+		if err := run(ctx, conn, migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > target {
+			continue
+		}
+		if err := run(ctx, conn, migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// locked runs f on a connection holding the migration lock, with the
+// schema_migration table in place.
+func (m *Migrator) locked(ctx context.Context, f func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// SQLite has no advisory locks, nor replicas sharing its database file
+	timeType := "DATETIME"
+	if m.driver != DriverSQLite {
+		// The lock belongs to the session, so it is taken and released on the
+		// same connection
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		timeType = "TIMESTAMPTZ"
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migration (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at `+timeType+` NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+	return f(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migration`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func latestApplied(applied map[int64]time.Time) (int64, bool) {
+	var latest int64
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, len(applied) > 0
+}
+
+// previousVersion is the version before version in migrations, 0 for the
+// first.
+func previousVersion(migrations []Migration, version int64) int64 {
+	var previous int64
+	for _, m := range migrations {
+		if m.Version >= version {
+			break
+		}
+		previous = m.Version
+	}
+	return previous
+}
+
+// run applies or reverts migration in a transaction with its record.
+func run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	body, record := migration.down, `DELETE FROM schema_migration WHERE version = $1`
+	args := []interface{}{migration.Version}
+	if up {
+		body, record = migration.up, `INSERT INTO schema_migration (version, name) VALUES ($1, $2)`
+		args = append(args, migration.Name)
+	}
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		direction := "revert"
+		if up {
+			direction = "apply"
+		}
+		return fmt.Errorf("%s migration %s: %w", direction, migration, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
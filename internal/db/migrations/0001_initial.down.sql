@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS
+    ownership_drift, reconciliation_run, chain_intent, webhook_attempt, webhook_delivery, webhook, event,
+    api_key_usage, api_key, audit_log, session, auth_nonce, "order", listing, currency, trait_count,
+    nft_attribute, nft, collection, "user";
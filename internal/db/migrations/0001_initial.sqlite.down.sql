@@ -0,0 +1,24 @@
+-- Foreign keys are checked when the transaction commits, once every table
+-- is gone, so the tables referencing each other can be dropped in turn
+PRAGMA defer_foreign_keys = ON;
+
+DROP TABLE ownership_drift;
+DROP TABLE reconciliation_run;
+DROP TABLE chain_intent;
+DROP TABLE webhook_attempt;
+DROP TABLE webhook_delivery;
+DROP TABLE webhook;
+DROP TABLE event;
+DROP TABLE api_key_usage;
+DROP TABLE api_key;
+DROP TABLE audit_log;
+DROP TABLE session;
+DROP TABLE auth_nonce;
+DROP TABLE "order";
+DROP TABLE listing;
+DROP TABLE currency;
+DROP TABLE trait_count;
+DROP TABLE nft_attribute;
+DROP TABLE nft;
+DROP TABLE collection;
+DROP TABLE "user";
@@ -0,0 +1,271 @@
+-- The initial schema on SQLite. There is no database built by AutoMigrate to
+-- bring up to date, and SQLite cannot add constraints to a table once it is
+-- created, so the tables come with the foreign keys Postgres gets in 0002.
+-- Prices are decimal text: SQLite has no exact numeric type wide enough for
+-- wei. Search needs no indexes: it scans with functions db registers.
+
+CREATE TABLE "user" (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    wallet_address TEXT,
+    name           TEXT,
+    role           TEXT NOT NULL DEFAULT 'user',
+    created_at     DATETIME
+);
+
+CREATE TABLE collection (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    creator_user_id INTEGER NOT NULL REFERENCES "user" (id),
+    name            TEXT NOT NULL,
+    symbol          TEXT NOT NULL,
+    verified        BOOLEAN NOT NULL DEFAULT false,
+    rarity_stale    BOOLEAN NOT NULL DEFAULT false,
+    created_at      DATETIME
+);
+
+CREATE TABLE nft (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_id         TEXT NOT NULL,
+    contract_address TEXT NOT NULL,
+    chain            TEXT NOT NULL,
+    collection_id    INTEGER NOT NULL REFERENCES collection (id),
+    owner_user_id    INTEGER NOT NULL REFERENCES "user" (id),
+    name             TEXT,
+    description      TEXT,
+    metadata_url     TEXT,
+    rarity_score     REAL NOT NULL DEFAULT 0,
+    rarity_rank      INTEGER NOT NULL DEFAULT 0,
+    burned_at        DATETIME,
+    version          INTEGER NOT NULL DEFAULT 1,
+    created_at       DATETIME
+);
+
+CREATE TABLE nft_attribute (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    nft_id     INTEGER NOT NULL REFERENCES nft (id),
+    trait_type TEXT NOT NULL,
+    value      TEXT NOT NULL
+);
+
+CREATE TABLE trait_count (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    collection_id INTEGER NOT NULL REFERENCES collection (id),
+    trait_type    TEXT NOT NULL,
+    value         TEXT NOT NULL,
+    token_count   INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE currency (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol        TEXT NOT NULL,
+    chain         TEXT NOT NULL,
+    token_address TEXT,
+    decimals      INTEGER NOT NULL,
+    enabled       BOOLEAN NOT NULL,
+    created_at    DATETIME
+);
+
+CREATE TABLE listing (
+    id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+    nft_id               INTEGER NOT NULL REFERENCES nft (id),
+    seller_user_id       INTEGER NOT NULL REFERENCES "user" (id),
+    price_wei            TEXT NOT NULL,
+    currency             TEXT DEFAULT 'ETH',
+    status               TEXT DEFAULT 'ACTIVE',
+    invalid_reason       TEXT,
+    checked_at           DATETIME,
+    expires_at           DATETIME,
+    reserved_by_order_id INTEGER REFERENCES "order" (id),
+    reserved_until       DATETIME,
+    version              INTEGER NOT NULL DEFAULT 1,
+    created_at           DATETIME
+);
+
+CREATE TABLE "order" (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    listing_id    INTEGER NOT NULL REFERENCES listing (id),
+    buyer_user_id INTEGER NOT NULL REFERENCES "user" (id),
+    tx_hash       TEXT,
+    status        TEXT DEFAULT 'PENDING',
+    expires_at    DATETIME,
+    version       INTEGER NOT NULL DEFAULT 1,
+    created_at    DATETIME
+);
+
+CREATE TABLE auth_nonce (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    nonce      TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME,
+    created_at DATETIME
+);
+
+CREATE TABLE session (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id            INTEGER NOT NULL REFERENCES "user" (id),
+    family_id          TEXT NOT NULL,
+    refresh_token_hash TEXT NOT NULL,
+    user_agent         TEXT,
+    ip                 TEXT,
+    expires_at         DATETIME NOT NULL,
+    revoked_at         DATETIME,
+    replaced_by_id     INTEGER REFERENCES session (id),
+    created_at         DATETIME
+);
+
+CREATE TABLE audit_log (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_user_id INTEGER REFERENCES "user" (id),
+    action        TEXT NOT NULL,
+    target_type   TEXT NOT NULL,
+    target_id     INTEGER NOT NULL,
+    reason        TEXT,
+    details       TEXT,
+    created_at    DATETIME
+);
+
+CREATE TABLE api_key (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES "user" (id),
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL,
+    scopes       TEXT,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    usage_count  INTEGER NOT NULL DEFAULT 0,
+    revoked_at   DATETIME,
+    created_at   DATETIME
+);
+
+CREATE TABLE api_key_usage (
+    api_key_id INTEGER REFERENCES api_key (id),
+    day        DATE,
+    requests   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, day)
+);
+
+CREATE TABLE event (
+    id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+    type                 TEXT NOT NULL,
+    collection_id        INTEGER NOT NULL DEFAULT 0,
+    nft_id               INTEGER NOT NULL DEFAULT 0,
+    user_id              INTEGER NOT NULL DEFAULT 0,
+    counterparty_user_id INTEGER NOT NULL DEFAULT 0,
+    data                 TEXT,
+    created_at           DATETIME,
+    dispatched_at        DATETIME
+);
+
+CREATE TABLE webhook (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER NOT NULL REFERENCES "user" (id),
+    api_key_id  INTEGER REFERENCES api_key (id),
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT,
+    created_at  DATETIME
+);
+
+-- Deliveries go with their webhook and history with its delivery, as on
+-- Postgres
+CREATE TABLE webhook_delivery (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id       INTEGER NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+    event_id         INTEGER NOT NULL,
+    event_type       TEXT NOT NULL,
+    payload          TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  DATETIME NOT NULL,
+    last_attempt_at  DATETIME,
+    last_status_code INTEGER,
+    last_error       TEXT,
+    redelivery_of    INTEGER REFERENCES webhook_delivery (id) ON DELETE SET NULL,
+    created_at       DATETIME
+);
+
+CREATE TABLE webhook_attempt (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id    INTEGER NOT NULL REFERENCES webhook_delivery (id) ON DELETE CASCADE,
+    status_code    INTEGER,
+    error          TEXT,
+    response_bytes INTEGER,
+    duration_ms    INTEGER,
+    created_at     DATETIME
+);
+
+CREATE TABLE chain_intent (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind         TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    user_id      INTEGER NOT NULL REFERENCES "user" (id),
+    nft_id       INTEGER REFERENCES nft (id),
+    mint         TEXT,
+    tx_hash      TEXT,
+    raw_tx       TEXT,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    error        TEXT,
+    locked_until DATETIME,
+    created_at   DATETIME,
+    updated_at   DATETIME
+);
+
+CREATE TABLE reconciliation_run (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at         DATETIME NOT NULL,
+    finished_at        DATETIME,
+    started_by_user_id INTEGER REFERENCES "user" (id),
+    auto_fix           BOOLEAN NOT NULL,
+    checked            INTEGER NOT NULL DEFAULT 0,
+    drifted            INTEGER NOT NULL DEFAULT 0,
+    fixed              INTEGER NOT NULL DEFAULT 0,
+    listings_cancelled INTEGER NOT NULL DEFAULT 0,
+    error              TEXT
+);
+
+CREATE TABLE ownership_drift (
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id            INTEGER NOT NULL REFERENCES reconciliation_run (id),
+    nft_id            INTEGER NOT NULL REFERENCES nft (id),
+    token_id          TEXT NOT NULL,
+    recorded_owner_id INTEGER NOT NULL REFERENCES "user" (id),
+    chain_owner       TEXT,
+    new_owner_id      INTEGER REFERENCES "user" (id),
+    fixed             BOOLEAN NOT NULL,
+    created_at        DATETIME
+);
+
+CREATE INDEX idx_nft_rarity_rank ON nft (rarity_rank);
+CREATE INDEX idx_nft_attribute_nft_id ON nft_attribute (nft_id);
+CREATE UNIQUE INDEX idx_trait_count_key ON trait_count (collection_id, trait_type, value);
+CREATE UNIQUE INDEX idx_currency_chain_symbol ON currency (symbol, chain);
+CREATE INDEX idx_listing_expires_at ON listing (expires_at);
+CREATE INDEX idx_listing_reserved_until ON listing (reserved_until);
+CREATE INDEX idx_order_expires_at ON "order" (expires_at);
+CREATE UNIQUE INDEX idx_auth_nonce_nonce ON auth_nonce (nonce);
+CREATE INDEX idx_session_user_id ON session (user_id);
+CREATE INDEX idx_session_family_id ON session (family_id);
+CREATE UNIQUE INDEX idx_session_refresh_token_hash ON session (refresh_token_hash);
+CREATE INDEX idx_audit_log_actor_user_id ON audit_log (actor_user_id);
+CREATE INDEX idx_audit_log_action ON audit_log (action);
+CREATE INDEX idx_audit_log_target ON audit_log (target_type, target_id);
+CREATE INDEX idx_api_key_user_id ON api_key (user_id);
+CREATE UNIQUE INDEX idx_api_key_key_hash ON api_key (key_hash);
+CREATE INDEX idx_event_type ON event (type);
+CREATE INDEX idx_event_collection_id ON event (collection_id);
+CREATE INDEX idx_event_nft_id ON event (nft_id);
+CREATE INDEX idx_event_user_id ON event (user_id);
+CREATE INDEX idx_event_counterparty_user_id ON event (counterparty_user_id);
+CREATE INDEX idx_event_created_at ON event (created_at);
+CREATE INDEX idx_event_dispatched_at ON event (dispatched_at);
+CREATE INDEX idx_webhook_user_id ON webhook (user_id);
+CREATE INDEX idx_webhook_api_key_id ON webhook (api_key_id);
+CREATE INDEX idx_webhook_delivery_webhook_id ON webhook_delivery (webhook_id);
+CREATE INDEX idx_webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX idx_webhook_attempt_delivery_id ON webhook_attempt (delivery_id);
+CREATE INDEX idx_chain_intent_status ON chain_intent (status);
+CREATE INDEX idx_chain_intent_user_id ON chain_intent (user_id);
+CREATE INDEX idx_chain_intent_nft_id ON chain_intent (nft_id);
+CREATE INDEX idx_reconciliation_run_started_at ON reconciliation_run (started_at);
+CREATE INDEX idx_ownership_drift_run_id ON ownership_drift (run_id);
+CREATE INDEX idx_ownership_drift_nft_id ON ownership_drift (nft_id);
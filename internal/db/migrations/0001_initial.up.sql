@@ -0,0 +1,315 @@
+-- The schema as AutoMigrate last built it. Every statement is a no-op on a
+-- database it already migrated, so this applies to those too.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS "user" (
+    id             BIGSERIAL PRIMARY KEY,
+    wallet_address TEXT,
+    name           TEXT,
+    role           TEXT NOT NULL DEFAULT 'user',
+    created_at     TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS collection (
+    id              BIGSERIAL PRIMARY KEY,
+    creator_user_id BIGINT NOT NULL,
+    name            TEXT NOT NULL,
+    symbol          TEXT NOT NULL,
+    verified        BOOLEAN NOT NULL DEFAULT false,
+    rarity_stale    BOOLEAN NOT NULL DEFAULT false,
+    created_at      TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS nft (
+    id               BIGSERIAL PRIMARY KEY,
+    token_id         TEXT NOT NULL,
+    contract_address TEXT NOT NULL,
+    chain            TEXT NOT NULL,
+    collection_id    BIGINT NOT NULL,
+    owner_user_id    BIGINT NOT NULL,
+    name             TEXT,
+    description      TEXT,
+    metadata_url     TEXT,
+    rarity_score     DECIMAL NOT NULL DEFAULT 0,
+    rarity_rank      BIGINT NOT NULL DEFAULT 0,
+    burned_at        TIMESTAMPTZ,
+    version          BIGINT NOT NULL DEFAULT 1,
+    created_at       TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS nft_attribute (
+    id         BIGSERIAL PRIMARY KEY,
+    nft_id     BIGINT NOT NULL,
+    trait_type TEXT NOT NULL,
+    value      TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trait_count (
+    id            BIGSERIAL PRIMARY KEY,
+    collection_id BIGINT NOT NULL,
+    trait_type    TEXT NOT NULL,
+    value         TEXT NOT NULL,
+    token_count   BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS currency (
+    id            BIGSERIAL PRIMARY KEY,
+    symbol        TEXT NOT NULL,
+    chain         TEXT NOT NULL,
+    token_address TEXT,
+    decimals      SMALLINT NOT NULL,
+    enabled       BOOLEAN NOT NULL,
+    created_at    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS listing (
+    id                   BIGSERIAL PRIMARY KEY,
+    nft_id               BIGINT NOT NULL,
+    seller_user_id       BIGINT NOT NULL,
+    price_wei            NUMERIC(78,0) NOT NULL,
+    currency             TEXT DEFAULT 'ETH',
+    status               TEXT DEFAULT 'ACTIVE',
+    invalid_reason       TEXT,
+    checked_at           TIMESTAMPTZ,
+    expires_at           TIMESTAMPTZ,
+    reserved_by_order_id BIGINT,
+    reserved_until       TIMESTAMPTZ,
+    version              BIGINT NOT NULL DEFAULT 1,
+    created_at           TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS "order" (
+    id            BIGSERIAL PRIMARY KEY,
+    listing_id    BIGINT NOT NULL,
+    buyer_user_id BIGINT NOT NULL,
+    tx_hash       TEXT,
+    status        TEXT DEFAULT 'PENDING',
+    expires_at    TIMESTAMPTZ,
+    version       BIGINT NOT NULL DEFAULT 1,
+    created_at    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS auth_nonce (
+    id         BIGSERIAL PRIMARY KEY,
+    nonce      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS session (
+    id                 BIGSERIAL PRIMARY KEY,
+    user_id            BIGINT NOT NULL,
+    family_id          TEXT NOT NULL,
+    refresh_token_hash TEXT NOT NULL,
+    user_agent         TEXT,
+    ip                 TEXT,
+    expires_at         TIMESTAMPTZ NOT NULL,
+    revoked_at         TIMESTAMPTZ,
+    replaced_by_id     BIGINT,
+    created_at         TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id            BIGSERIAL PRIMARY KEY,
+    actor_user_id BIGINT,
+    action        TEXT NOT NULL,
+    target_type   TEXT NOT NULL,
+    target_id     BIGINT NOT NULL,
+    reason        TEXT,
+    details       TEXT,
+    created_at    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL,
+    scopes       TEXT,
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    usage_count  BIGINT NOT NULL DEFAULT 0,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    api_key_id BIGINT,
+    day        DATE,
+    requests   BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS event (
+    id                   BIGSERIAL PRIMARY KEY,
+    type                 TEXT NOT NULL,
+    collection_id        BIGINT NOT NULL DEFAULT 0,
+    nft_id               BIGINT NOT NULL DEFAULT 0,
+    user_id              BIGINT NOT NULL DEFAULT 0,
+    counterparty_user_id BIGINT NOT NULL DEFAULT 0,
+    data                 TEXT,
+    created_at           TIMESTAMPTZ,
+    dispatched_at        TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL,
+    api_key_id  BIGINT,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT,
+    created_at  TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       BIGINT NOT NULL,
+    event_id         BIGINT NOT NULL,
+    event_type       TEXT NOT NULL,
+    payload          TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    attempts         BIGINT NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL,
+    last_attempt_at  TIMESTAMPTZ,
+    last_status_code BIGINT,
+    last_error       TEXT,
+    redelivery_of    BIGINT,
+    created_at       TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webhook_attempt (
+    id             BIGSERIAL PRIMARY KEY,
+    delivery_id    BIGINT NOT NULL,
+    status_code    BIGINT,
+    error          TEXT,
+    response_bytes BIGINT,
+    duration_ms    BIGINT,
+    created_at     TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS chain_intent (
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    user_id      BIGINT NOT NULL,
+    nft_id       BIGINT,
+    mint         TEXT,
+    tx_hash      TEXT,
+    raw_tx       TEXT,
+    attempts     BIGINT NOT NULL DEFAULT 0,
+    error        TEXT,
+    locked_until TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_run (
+    id                 BIGSERIAL PRIMARY KEY,
+    started_at         TIMESTAMPTZ NOT NULL,
+    finished_at        TIMESTAMPTZ,
+    started_by_user_id BIGINT,
+    auto_fix           BOOLEAN NOT NULL,
+    checked            BIGINT NOT NULL DEFAULT 0,
+    drifted            BIGINT NOT NULL DEFAULT 0,
+    fixed              BIGINT NOT NULL DEFAULT 0,
+    listings_cancelled BIGINT NOT NULL DEFAULT 0,
+    error              TEXT
+);
+
+CREATE TABLE IF NOT EXISTS ownership_drift (
+    id                BIGSERIAL PRIMARY KEY,
+    run_id            BIGINT NOT NULL,
+    nft_id            BIGINT NOT NULL,
+    token_id          TEXT NOT NULL,
+    recorded_owner_id BIGINT NOT NULL,
+    chain_owner       TEXT,
+    new_owner_id      BIGINT,
+    fixed             BOOLEAN NOT NULL,
+    created_at        TIMESTAMPTZ
+);
+
+-- Columns added after the first release, missing from databases AutoMigrate
+-- has not run on since
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE collection
+    ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS rarity_stale BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE nft
+    ADD COLUMN IF NOT EXISTS name TEXT,
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS rarity_score DECIMAL NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rarity_rank BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS burned_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE listing
+    ADD COLUMN IF NOT EXISTS invalid_reason TEXT,
+    ADD COLUMN IF NOT EXISTS checked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS reserved_by_order_id BIGINT,
+    ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE event ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMPTZ;
+
+-- Prices were text before they were exact numerics. Active listings whose
+-- price is not a valid wei amount are cancelled and their price zeroed,
+-- since they could never have been bought.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'listing' AND column_name = 'price_wei') <> 'numeric' THEN
+        UPDATE listing SET status = 'CANCELLED', price_wei = '0' WHERE price_wei !~ '^[0-9]{1,78}$';
+        ALTER TABLE listing ALTER COLUMN price_wei TYPE NUMERIC(78,0) USING price_wei::NUMERIC(78,0);
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_nft_rarity_rank ON nft (rarity_rank);
+CREATE INDEX IF NOT EXISTS idx_nft_attribute_nft_id ON nft_attribute (nft_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trait_count_key ON trait_count (collection_id, trait_type, value);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_currency_chain_symbol ON currency (symbol, chain);
+CREATE INDEX IF NOT EXISTS idx_listing_expires_at ON listing (expires_at);
+CREATE INDEX IF NOT EXISTS idx_listing_reserved_until ON listing (reserved_until);
+CREATE INDEX IF NOT EXISTS idx_order_expires_at ON "order" (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auth_nonce_nonce ON auth_nonce (nonce);
+CREATE INDEX IF NOT EXISTS idx_session_user_id ON session (user_id);
+CREATE INDEX IF NOT EXISTS idx_session_family_id ON session (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_refresh_token_hash ON session (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_user_id ON audit_log (actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_api_key_user_id ON api_key (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_key_key_hash ON api_key (key_hash);
+CREATE INDEX IF NOT EXISTS idx_event_type ON event (type);
+CREATE INDEX IF NOT EXISTS idx_event_collection_id ON event (collection_id);
+CREATE INDEX IF NOT EXISTS idx_event_nft_id ON event (nft_id);
+CREATE INDEX IF NOT EXISTS idx_event_user_id ON event (user_id);
+CREATE INDEX IF NOT EXISTS idx_event_counterparty_user_id ON event (counterparty_user_id);
+CREATE INDEX IF NOT EXISTS idx_event_created_at ON event (created_at);
+CREATE INDEX IF NOT EXISTS idx_event_dispatched_at ON event (dispatched_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_user_id ON webhook (user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_api_key_id ON webhook (api_key_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_attempt_delivery_id ON webhook_attempt (delivery_id);
+CREATE INDEX IF NOT EXISTS idx_chain_intent_status ON chain_intent (status);
+CREATE INDEX IF NOT EXISTS idx_chain_intent_user_id ON chain_intent (user_id);
+CREATE INDEX IF NOT EXISTS idx_chain_intent_nft_id ON chain_intent (nft_id);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_run_started_at ON reconciliation_run (started_at);
+CREATE INDEX IF NOT EXISTS idx_ownership_drift_run_id ON ownership_drift (run_id);
+CREATE INDEX IF NOT EXISTS idx_ownership_drift_nft_id ON ownership_drift (nft_id);
+
+-- Full-text and trigram indexes used by repository.Search. The tsvector
+-- expressions must stay in sync with the ones in repository/search.go.
+CREATE INDEX IF NOT EXISTS idx_nft_search ON nft USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(description, '')));
+CREATE INDEX IF NOT EXISTS idx_collection_search ON collection USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(symbol, '')));
+CREATE INDEX IF NOT EXISTS idx_user_search ON "user" USING GIN (to_tsvector('simple', coalesce(name, '')));
+CREATE INDEX IF NOT EXISTS idx_nft_name_trgm ON nft USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_collection_name_trgm ON collection USING GIN (name gin_trgm_ops);
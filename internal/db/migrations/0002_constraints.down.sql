@@ -0,0 +1,29 @@
+-- Merged users are not split again
+ALTER TABLE ownership_drift
+    DROP CONSTRAINT fk_reconciliation_run_drifts,
+    DROP CONSTRAINT fk_ownership_drift_nft,
+    DROP CONSTRAINT fk_ownership_drift_recorded_owner,
+    DROP CONSTRAINT fk_ownership_drift_new_owner;
+ALTER TABLE reconciliation_run DROP CONSTRAINT fk_reconciliation_run_started_by;
+ALTER TABLE chain_intent DROP CONSTRAINT fk_chain_intent_user, DROP CONSTRAINT fk_chain_intent_nft;
+ALTER TABLE webhook_attempt DROP CONSTRAINT fk_webhook_delivery_history;
+ALTER TABLE webhook_delivery
+    DROP CONSTRAINT fk_webhook_delivery_webhook,
+    DROP CONSTRAINT fk_webhook_delivery_redelivery_of;
+ALTER TABLE webhook DROP CONSTRAINT fk_webhook_user, DROP CONSTRAINT fk_webhook_api_key;
+ALTER TABLE api_key_usage DROP CONSTRAINT fk_api_key_usage_api_key;
+ALTER TABLE api_key DROP CONSTRAINT fk_api_key_user;
+ALTER TABLE audit_log DROP CONSTRAINT fk_audit_log_actor;
+ALTER TABLE session DROP CONSTRAINT fk_session_user, DROP CONSTRAINT fk_session_replaced_by;
+ALTER TABLE "order" DROP CONSTRAINT fk_order_listing, DROP CONSTRAINT fk_order_buyer;
+ALTER TABLE listing
+    DROP CONSTRAINT fk_listing_nft,
+    DROP CONSTRAINT fk_listing_seller,
+    DROP CONSTRAINT fk_listing_reserved_by_order;
+ALTER TABLE trait_count DROP CONSTRAINT fk_trait_count_collection;
+ALTER TABLE nft_attribute DROP CONSTRAINT fk_nft_attributes;
+ALTER TABLE nft DROP CONSTRAINT fk_nft_collection, DROP CONSTRAINT fk_nft_owner;
+ALTER TABLE collection DROP CONSTRAINT fk_collection_creator;
+
+DROP INDEX idx_nft_token;
+DROP INDEX idx_user_wallet_address;
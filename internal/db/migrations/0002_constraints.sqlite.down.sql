@@ -0,0 +1,2 @@
+DROP INDEX idx_nft_token;
+DROP INDEX idx_user_wallet_address;
@@ -0,0 +1,6 @@
+-- The foreign keys came with the tables on SQLite, and there are no users
+-- created by AutoMigrate to merge; only the unique indexes are left.
+
+-- Wallets and contract addresses are compared in any letter case
+CREATE UNIQUE INDEX idx_user_wallet_address ON "user" (LOWER(wallet_address));
+CREATE UNIQUE INDEX idx_nft_token ON nft (chain, LOWER(contract_address), token_id);
@@ -0,0 +1,94 @@
+-- Users are looked up by wallet in any letter case, always finding the
+-- oldest, so accounts created for the same wallet in another case were
+-- never signed in to again. Merge them into the oldest before wallets are
+-- made unique.
+CREATE TEMPORARY TABLE user_duplicate ON COMMIT DROP AS
+SELECT u.id, k.keep_id
+FROM "user" u
+JOIN (
+    SELECT LOWER(wallet_address) AS wallet, MIN(id) AS keep_id
+    FROM "user"
+    GROUP BY LOWER(wallet_address)
+    HAVING COUNT(*) > 1
+) k ON LOWER(u.wallet_address) = k.wallet
+WHERE u.id <> k.keep_id;
+
+UPDATE collection SET creator_user_id = d.keep_id FROM user_duplicate d WHERE creator_user_id = d.id;
+UPDATE nft SET owner_user_id = d.keep_id FROM user_duplicate d WHERE owner_user_id = d.id;
+UPDATE listing SET seller_user_id = d.keep_id FROM user_duplicate d WHERE seller_user_id = d.id;
+UPDATE "order" SET buyer_user_id = d.keep_id FROM user_duplicate d WHERE buyer_user_id = d.id;
+UPDATE session SET user_id = d.keep_id FROM user_duplicate d WHERE user_id = d.id;
+UPDATE api_key SET user_id = d.keep_id FROM user_duplicate d WHERE user_id = d.id;
+UPDATE webhook SET user_id = d.keep_id FROM user_duplicate d WHERE user_id = d.id;
+UPDATE chain_intent SET user_id = d.keep_id FROM user_duplicate d WHERE user_id = d.id;
+UPDATE audit_log SET actor_user_id = d.keep_id FROM user_duplicate d WHERE actor_user_id = d.id;
+UPDATE audit_log SET target_id = d.keep_id FROM user_duplicate d WHERE target_type = 'user' AND target_id = d.id;
+UPDATE event SET user_id = d.keep_id FROM user_duplicate d WHERE user_id = d.id;
+UPDATE event SET counterparty_user_id = d.keep_id FROM user_duplicate d WHERE counterparty_user_id = d.id;
+UPDATE reconciliation_run SET started_by_user_id = d.keep_id FROM user_duplicate d WHERE started_by_user_id = d.id;
+UPDATE ownership_drift SET recorded_owner_id = d.keep_id FROM user_duplicate d WHERE recorded_owner_id = d.id;
+UPDATE ownership_drift SET new_owner_id = d.keep_id FROM user_duplicate d WHERE new_owner_id = d.id;
+DELETE FROM "user" USING user_duplicate d WHERE "user".id = d.id;
+
+-- Wallets and contract addresses are compared in any letter case
+CREATE UNIQUE INDEX idx_user_wallet_address ON "user" (LOWER(wallet_address));
+CREATE UNIQUE INDEX idx_nft_token ON nft (chain, LOWER(contract_address), token_id);
+
+-- AutoMigrate created the constraints of the associations it knew about,
+-- under these names; they are recreated with the rest.
+ALTER TABLE collection
+    ADD CONSTRAINT fk_collection_creator FOREIGN KEY (creator_user_id) REFERENCES "user" (id);
+ALTER TABLE nft
+    DROP CONSTRAINT IF EXISTS fk_nft_collection,
+    DROP CONSTRAINT IF EXISTS fk_nft_owner,
+    ADD CONSTRAINT fk_nft_collection FOREIGN KEY (collection_id) REFERENCES collection (id),
+    ADD CONSTRAINT fk_nft_owner FOREIGN KEY (owner_user_id) REFERENCES "user" (id);
+ALTER TABLE nft_attribute
+    DROP CONSTRAINT IF EXISTS fk_nft_attributes,
+    ADD CONSTRAINT fk_nft_attributes FOREIGN KEY (nft_id) REFERENCES nft (id);
+ALTER TABLE trait_count
+    ADD CONSTRAINT fk_trait_count_collection FOREIGN KEY (collection_id) REFERENCES collection (id);
+ALTER TABLE listing
+    DROP CONSTRAINT IF EXISTS fk_listing_nft,
+    DROP CONSTRAINT IF EXISTS fk_listing_seller,
+    ADD CONSTRAINT fk_listing_nft FOREIGN KEY (nft_id) REFERENCES nft (id),
+    ADD CONSTRAINT fk_listing_seller FOREIGN KEY (seller_user_id) REFERENCES "user" (id),
+    ADD CONSTRAINT fk_listing_reserved_by_order FOREIGN KEY (reserved_by_order_id) REFERENCES "order" (id);
+ALTER TABLE "order"
+    DROP CONSTRAINT IF EXISTS fk_order_listing,
+    DROP CONSTRAINT IF EXISTS fk_order_buyer,
+    ADD CONSTRAINT fk_order_listing FOREIGN KEY (listing_id) REFERENCES listing (id),
+    ADD CONSTRAINT fk_order_buyer FOREIGN KEY (buyer_user_id) REFERENCES "user" (id);
+ALTER TABLE session
+    ADD CONSTRAINT fk_session_user FOREIGN KEY (user_id) REFERENCES "user" (id),
+    ADD CONSTRAINT fk_session_replaced_by FOREIGN KEY (replaced_by_id) REFERENCES session (id);
+ALTER TABLE audit_log
+    ADD CONSTRAINT fk_audit_log_actor FOREIGN KEY (actor_user_id) REFERENCES "user" (id);
+ALTER TABLE api_key
+    ADD CONSTRAINT fk_api_key_user FOREIGN KEY (user_id) REFERENCES "user" (id);
+ALTER TABLE api_key_usage
+    ADD CONSTRAINT fk_api_key_usage_api_key FOREIGN KEY (api_key_id) REFERENCES api_key (id);
+ALTER TABLE webhook
+    ADD CONSTRAINT fk_webhook_user FOREIGN KEY (user_id) REFERENCES "user" (id),
+    ADD CONSTRAINT fk_webhook_api_key FOREIGN KEY (api_key_id) REFERENCES api_key (id);
+-- Deliveries go with their webhook and history with its delivery. Events
+-- are pruned independently of the deliveries made of them, which keep their
+-- own payload, so event_id is not a foreign key.
+ALTER TABLE webhook_delivery
+    DROP CONSTRAINT IF EXISTS fk_webhook_delivery_webhook,
+    ADD CONSTRAINT fk_webhook_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_webhook_delivery_redelivery_of FOREIGN KEY (redelivery_of) REFERENCES webhook_delivery (id) ON DELETE SET NULL;
+ALTER TABLE webhook_attempt
+    DROP CONSTRAINT IF EXISTS fk_webhook_delivery_history,
+    ADD CONSTRAINT fk_webhook_delivery_history FOREIGN KEY (delivery_id) REFERENCES webhook_delivery (id) ON DELETE CASCADE;
+ALTER TABLE chain_intent
+    ADD CONSTRAINT fk_chain_intent_user FOREIGN KEY (user_id) REFERENCES "user" (id),
+    ADD CONSTRAINT fk_chain_intent_nft FOREIGN KEY (nft_id) REFERENCES nft (id);
+ALTER TABLE reconciliation_run
+    ADD CONSTRAINT fk_reconciliation_run_started_by FOREIGN KEY (started_by_user_id) REFERENCES "user" (id);
+ALTER TABLE ownership_drift
+    DROP CONSTRAINT IF EXISTS fk_reconciliation_run_drifts,
+    ADD CONSTRAINT fk_reconciliation_run_drifts FOREIGN KEY (run_id) REFERENCES reconciliation_run (id),
+    ADD CONSTRAINT fk_ownership_drift_nft FOREIGN KEY (nft_id) REFERENCES nft (id),
+    ADD CONSTRAINT fk_ownership_drift_recorded_owner FOREIGN KEY (recorded_owner_id) REFERENCES "user" (id),
+    ADD CONSTRAINT fk_ownership_drift_new_owner FOREIGN KEY (new_owner_id) REFERENCES "user" (id);
@@ -0,0 +1,2 @@
+DROP TABLE rate_limit_bucket;
+DROP TABLE idempotency_key;
@@ -0,0 +1,26 @@
+CREATE TABLE idempotency_key (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    scope           TEXT NOT NULL,
+    key             TEXT NOT NULL,
+    request_hash    TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    response_status INTEGER,
+    content_type    TEXT,
+    response_body   BLOB,
+    expires_at      DATETIME NOT NULL,
+    locked_until    DATETIME NOT NULL,
+    created_at      DATETIME
+);
+
+-- Buckets are only used by the Postgres rate limiter, but the schema is the
+-- same on every driver
+CREATE TABLE rate_limit_bucket (
+    key        TEXT PRIMARY KEY,
+    tokens     REAL NOT NULL,
+    allowed    BOOLEAN NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_idempotency_key_scope_key ON idempotency_key (scope, key);
+CREATE INDEX idx_idempotency_key_expires_at ON idempotency_key (expires_at);
+CREATE INDEX idx_rate_limit_bucket_updated_at ON rate_limit_bucket (updated_at);
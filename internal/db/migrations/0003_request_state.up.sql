@@ -0,0 +1,28 @@
+-- The idempotency keys and rate limit buckets, which their stores created
+-- with AutoMigrate when they started. As in 0001, every statement is a no-op
+-- on a database they already created.
+
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    id              BIGSERIAL PRIMARY KEY,
+    scope           TEXT NOT NULL,
+    key             TEXT NOT NULL,
+    request_hash    TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    response_status BIGINT,
+    content_type    TEXT,
+    response_body   BYTEA,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    locked_until    TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    key        TEXT PRIMARY KEY,
+    tokens     DECIMAL NOT NULL,
+    allowed    BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_key_scope_key ON idempotency_key (scope, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_key_expires_at ON idempotency_key (expires_at);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bucket_updated_at ON rate_limit_bucket (updated_at);
@@ -0,0 +1,85 @@
+//go:build cgo
+
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/platform/textsearch"
+)
+
+// sqliteDriverName is the driver SQLite databases are opened with.
+const sqliteDriverName = "sqlite3_marketplace"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqliteDriver{sqlite3.SQLiteDriver{ConnectHook: registerSearchFunctions}})
+}
+
+// registerSearchFunctions provides on a connection the SQL functions the
+// repository searches with, which Postgres has built in or from pg_trgm.
+func registerSearchFunctions(conn *sqlite3.SQLiteConn) error {
+	matches := func(text, document string) bool {
+		return textsearch.MatchesPrefixes(textsearch.Words(text), document)
+	}
+	if err := conn.RegisterFunc("search_prefix_match", matches, true); err != nil {
+		return err
+	}
+	return conn.RegisterFunc("word_similarity", textsearch.WordSimilarity, true)
+}
+
+// sqliteDriver is the mattn driver with times bound in UTC: SQLite keeps
+// them as text and compares them as such, so they must share a zone.
+type sqliteDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type sqliteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *sqliteConn) CheckNamedValue(arg *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC()
+	}
+	arg.Value = value
+	return nil
+}
+
+// connectSQLite opens the database file named by DB_NAME. SQLite has no row
+// locks, so transactions run one at a time on a single connection and take
+// the write lock as they begin, waiting for other processes to release it.
+func connectSQLite(cfg *config.DBConfig) *sql.DB {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_txlock", "immediate")
+	db, err := sql.Open(sqliteDriverName, fmt.Sprintf("file:%s?%s", cfg.Name, params.Encode()))
+	if err != nil {
+		logrus.Fatalf("Failed to open SQLite database: %v", err)
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLife) * time.Second)
+
+	return db
+}
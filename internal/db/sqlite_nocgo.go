@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+	"github.com/user/nft-marketplace/internal/config"
+)
+
+// sqliteDriverName is never opened: the SQLite driver is written in C, so
+// builds without cgo run on Postgres only.
+const sqliteDriverName = "sqlite3"
+
+func connectSQLite(cfg *config.DBConfig) *sql.DB {
+	logrus.Fatal("DB_DRIVER=sqlite needs a build with cgo (CGO_ENABLED=1)")
+	return nil
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+type reasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// bindOptionalJSON binds a request body that may be omitted entirely.
+func bindOptionalJSON(c *gin.Context, req interface{}) error {
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Admin Handlers
+
+type listUsersQuery struct {
+	pageQuery
+	Role string `form:"role" binding:"omitempty,oneof=admin moderator creator user"`
+}
+
+func (h *Handler) AdminListUsers(c *gin.Context) {
+	var q listUsersQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.UserFilter{Role: core.Role(q.Role)}
+	users, err := h.service.ListUsers(filter, q.page())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+type updateUserRequest struct {
+	Role   string `json:"role" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) AdminUpdateUser(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req updateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	role, err := core.ParseRole(req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.service.SetUserRole(actorID(c), id, role, req.Reason)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type verifyCollectionRequest struct {
+	Verified *bool  `json:"verified"`
+	Reason   string `json:"reason"`
+}
+
+func (h *Handler) AdminVerifyCollection(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req verifyCollectionRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	verified := req.Verified == nil || *req.Verified
+
+	col, err := h.service.VerifyCollection(actorID(c), id, verified, req.Reason)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, col)
+}
+
+func (h *Handler) AdminCancelListing(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req reasonRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	if err := h.service.ForceCancelListing(actorID(c), id, req.Reason); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
+}
+
+func (h *Handler) AdminFailOrder(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req reasonRequest
+	if err := bindOptionalJSON(c, &req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	if err := h.service.MarkOrderFailed(actorID(c), id, req.Reason); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "failed"})
+}
+
+type auditLogQuery struct {
+	pageQuery
+	ActorID    string `form:"actor_id" binding:"omitempty,id"`
+	TargetType string `form:"target_type"`
+	TargetID   string `form:"target_id" binding:"omitempty,id"`
+}
+
+func (h *Handler) AdminAuditLog(c *gin.Context) {
+	var q auditLogQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.AuditFilter{
+		ActorID:    optionalID(q.ActorID),
+		TargetType: q.TargetType,
+		TargetID:   optionalID(q.TargetID),
+	}
+
+	entries, err := h.service.ListAuditLog(filter, q.page())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *Handler) AdminListReconciliationRuns(c *gin.Context) {
+	var q pageQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	runs, err := h.service.ListReconciliationRuns(q.page())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func (h *Handler) AdminGetReconciliationRun(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	run, err := h.service.GetReconciliationRun(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) AdminStartReconciliation(c *gin.Context) {
+	run, err := h.service.StartReconciliation(actorID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
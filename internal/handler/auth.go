@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/middleware"
+)
+
+// Auth Handlers
+func (h *Handler) AuthNonce(c *gin.Context) {
+	nonce, err := h.auth.Nonce()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nonce": nonce.Nonce, "expires_at": nonce.ExpiresAt})
+}
+
+type verifyRequest struct {
+	Message   string `json:"message" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+func (h *Handler) AuthVerify(c *gin.Context) {
+	var req verifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	tokens, err := h.auth.Verify(req.Message, req.Signature, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func (h *Handler) AuthRefresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	tokens, err := h.auth.Refresh(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// AuthLogout revokes the current session, or every session of the user
+// with ?all=true.
+func (h *Handler) AuthLogout(c *gin.Context) {
+	p := middleware.PrincipalFrom(c)
+	var err error
+	if c.Query("all") == "true" {
+		err = h.auth.LogoutAll(p.UserID)
+	} else {
+		err = h.auth.Logout(p.SessionID)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "logged_out"})
+}
+
+func (h *Handler) AuthMe(c *gin.Context) {
+	user, err := h.service.GetUser(actorID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// API Key Handlers
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	scopes, err := core.ParseScopes(req.Scopes)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, plaintext, err := h.auth.CreateAPIKey(actorID(c), req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// The key itself is only ever shown in this response.
+	c.JSON(http.StatusCreated, gin.H{"key": plaintext, "api_key": key})
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.auth.ListAPIKeys(actorID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.auth.RevokeAPIKey(actorID(c), id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+type apiKeyUsageQuery struct {
+	Days string `form:"days" binding:"omitempty,number"`
+}
+
+func (h *Handler) APIKeyUsage(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var q apiKeyUsageQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	days := limitOr(q.Days, 30)
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+
+	usage, err := h.auth.APIKeyUsage(actorID(c), id, days)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
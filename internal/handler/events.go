@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/service"
+)
+
+// wsWriteWait bounds each write to a WebSocket client.
+const wsWriteWait = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// Origins are checked by middleware.RequireOrigin
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+type eventStreamQuery struct {
+	Types        []string `form:"type"`
+	CollectionID string   `form:"collection_id" binding:"omitempty,id"`
+	NFTID        string   `form:"nft_id" binding:"omitempty,id"`
+	UserID       string   `form:"user_id" binding:"omitempty,id"`
+	LastEventID  string   `form:"last_event_id" binding:"omitempty,number"`
+}
+
+// openEventStream subscribes to the events selected by the query: repeated
+// type parameters and collection, NFT and user topics. Streams resume after
+// the Last-Event-ID header or last_event_id parameter.
+func (h *Handler) openEventStream(c *gin.Context) (*service.EventStream, error) {
+	var q eventStreamQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		return nil, invalidRequest(err)
+	}
+	types, err := core.ParseEventTypes(q.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	// EventSource sends the header when it reconnects
+	last := c.GetHeader("Last-Event-ID")
+	if last == "" {
+		last = q.LastEventID
+	}
+	var lastID uint64
+	if last != "" {
+		if lastID, err = strconv.ParseUint(last, 10, strconv.IntSize); err != nil {
+			return nil, core.Validation("invalid_last_event_id", "last event id %q is not an event id", last)
+		}
+	}
+
+	filter := core.EventFilter{
+		Types:        types,
+		CollectionID: optionalID(q.CollectionID),
+		NFTID:        optionalID(q.NFTID),
+		UserID:       optionalID(q.UserID),
+	}
+	return h.events.Subscribe(filter, uint(lastID))
+}
+
+// StreamEvents streams events as Server-Sent Events, each with its id and
+// type, and a comment line as heartbeat.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	stream, err := h.openEventStream(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		e, ok, err := stream.Next(c.Request.Context())
+		if err != nil {
+			log.Printf("Event stream failed: %v", err)
+			return
+		}
+		if !ok {
+			return
+		}
+		if e == nil {
+			_, err = fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		} else {
+			var data []byte
+			if data, err = json.Marshal(e); err != nil {
+				log.Printf("Failed to encode event %d: %v", e.ID, err)
+				return
+			}
+			_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		}
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// EventsWebSocket streams events as JSON text messages over a WebSocket,
+// pinging idle connections. When the stream ends the connection is closed
+// with 1013 (try again later), and clients reconnect with last_event_id.
+func (h *Handler) EventsWebSocket(c *gin.Context) {
+	stream, err := h.openEventStream(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer stream.Close()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied
+		return
+	}
+	defer conn.Close()
+
+	// Clients only send control frames; reading them notices a disconnect
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		e, ok, err := stream.Next(ctx)
+		if err != nil {
+			log.Printf("Event stream failed: %v", err)
+			closeWebSocket(conn, websocket.CloseInternalServerErr, "event stream failed")
+			return
+		}
+		if !ok {
+			closeWebSocket(conn, websocket.CloseTryAgainLater, "resume from the last event id")
+			return
+		}
+		if e == nil {
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		} else {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteJSON(e)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+}
@@ -1,25 +1,95 @@
 package handler
 
 import (
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/middleware"
+	"github.com/user/nft-marketplace/internal/platform/validation"
 	"github.com/user/nft-marketplace/internal/service"
 )
 
 type Handler struct {
-	service *service.MarketplaceService
+	service  *service.MarketplaceService
+	auth     *service.AuthService
+	events   *service.EventService
+	webhooks *service.WebhookService
 }
 
-func NewHandler(service *service.MarketplaceService) *Handler {
-	return &Handler{service: service}
+func NewHandler(service *service.MarketplaceService, auth *service.AuthService, events *service.EventService, webhooks *service.WebhookService) *Handler {
+	return &Handler{service: service, auth: auth, events: events, webhooks: webhooks}
 }
 
-// Responses
-type errorResponse struct {
-	Error string `json:"error"`
+// invalidRequest rejects a request body or query that failed to bind.
+func invalidRequest(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return core.TooLarge("request body too large")
+	}
+	return validation.Error(err)
+}
+
+// pageQuery holds the limit, cursor and sort query parameters shared by list
+// endpoints; embed it in their query structs.
+type pageQuery struct {
+	Limit  string `form:"limit" binding:"omitempty,number"`
+	Cursor string `form:"cursor"`
+	Sort   string `form:"sort"`
+}
+
+func (q pageQuery) page() core.PageRequest {
+	limit, _ := strconv.Atoi(q.Limit)
+	return core.PageRequest{Limit: limit, Cursor: q.Cursor, Sort: q.Sort}
+}
+
+// idParam reads the :id path parameter.
+func idParam(c *gin.Context) (uint, error) {
+	var p struct {
+		ID string `uri:"id" binding:"id"`
+	}
+	if err := c.ShouldBindUri(&p); err != nil {
+		return 0, invalidRequest(err)
+	}
+	return validation.ParseID(p.ID)
+}
+
+// optionalID converts an id query parameter already checked by its id
+// binding; absent ids are 0.
+func optionalID(s string) uint {
+	id, _ := validation.ParseID(s)
+	return id
+}
+
+// optionalWei converts an amount query parameter already checked by its
+// uint256 binding.
+func optionalWei(s string) *core.Wei {
+	if s == "" {
+		return nil
+	}
+	w, _ := core.ParseWei(s)
+	return &w
+}
+
+// limitOr converts a limit already checked by its binding, defaulting when absent.
+func limitOr(s string, def int) int {
+	if limit, err := strconv.Atoi(s); err == nil {
+		return limit
+	}
+	return def
+}
+
+// actorID is the id of the authenticated user; routes using it sit behind
+// middleware.RequireAuth.
+func actorID(c *gin.Context) uint {
+	if p := middleware.PrincipalFrom(c); p != nil {
+		return p.UserID
+	}
+	return 0
 }
 
 func (h *Handler) Health(c *gin.Context) {
@@ -31,206 +101,440 @@ func (h *Handler) Health(c *gin.Context) {
 }
 
 // User Handlers
-func (h *Handler) CreateUser(c *gin.Context) {
-	var req struct {
-		Wallet string `json:"wallet_address" binding:"required"`
-		Name   string `json:"name"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
-		return
-	}
-
-	user, err := h.service.CreateUser(req.Wallet, req.Name)
+func (h *Handler) GetUser(c *gin.Context) {
+	id, err := idParam(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusCreated, user)
-}
-
-func (h *Handler) GetUser(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	user, err := h.service.GetUser(uint(id))
+	user, err := h.service.GetUser(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, errorResponse{Error: "user not found"})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, user)
 }
 
 // Collection Handlers
+type createCollectionRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Symbol string `json:"symbol" binding:"required"`
+}
+
 func (h *Handler) CreateCollection(c *gin.Context) {
-	var req struct {
-		CreatorID uint   `json:"creator_user_id" binding:"required"`
-		Name      string `json:"name" binding:"required"`
-		Symbol    string `json:"symbol" binding:"required"`
-	}
+	var req createCollectionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	col, err := h.service.CreateCollection(req.CreatorID, req.Name, req.Symbol)
+	col, err := h.service.CreateCollection(actorID(c), req.Name, req.Symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, col)
 }
 
+type listCollectionsQuery struct {
+	pageQuery
+	CreatorID string `form:"creator_id" binding:"omitempty,id"`
+}
+
 func (h *Handler) ListCollections(c *gin.Context) {
-	cols, err := h.service.ListCollections()
+	var q listCollectionsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.CollectionFilter{CreatorID: optionalID(q.CreatorID)}
+
+	cols, err := h.service.ListCollections(filter, q.page())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, cols)
 }
 
+func (h *Handler) ListCollectionTraits(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	traits, err := h.service.ListTraits(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, traits)
+}
+
 // NFT Handlers
+type registerNFTRequest struct {
+	TokenID      string              `json:"token_id" binding:"required,uint256"`
+	Contract     string              `json:"contract_address" binding:"required,eth_address"`
+	Chain        string              `json:"chain" binding:"required"`
+	CollectionID uint                `json:"collection_id" binding:"required,id"`
+	Name         string              `json:"name"`
+	Description  string              `json:"description"`
+	MetadataURL  string              `json:"metadata_url"`
+	Attributes   []core.NFTAttribute `json:"attributes"`
+}
+
 func (h *Handler) RegisterNFT(c *gin.Context) {
-	var req struct {
-		TokenID      string `json:"token_id" binding:"required"`
-		Contract     string `json:"contract_address" binding:"required"`
-		Chain        string `json:"chain" binding:"required"`
-		CollectionID uint   `json:"collection_id" binding:"required"`
-		OwnerID      uint   `json:"owner_user_id" binding:"required"`
-		MetadataURL  string `json:"metadata_url"`
-	}
+	var req registerNFTRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	nft, err := h.service.RegisterNFT(req.TokenID, req.Contract, req.Chain, req.CollectionID, req.OwnerID, req.MetadataURL)
+	nft, err := h.service.RegisterNFT(req.TokenID, req.Contract, req.Chain, req.CollectionID, actorID(c), req.Name, req.Description, req.MetadataURL, req.Attributes)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, nft)
 }
 
+type listNFTsQuery struct {
+	pageQuery
+	OwnerID      string `form:"owner_id" binding:"omitempty,id"`
+	CollectionID string `form:"collection_id" binding:"omitempty,id"`
+	Chain        string `form:"chain"`
+}
+
 func (h *Handler) ListNFTs(c *gin.Context) {
-	ownerID, _ := strconv.Atoi(c.Query("owner_id"))
-	collectionID, _ := strconv.Atoi(c.Query("collection_id"))
-	chain := c.Query("chain")
+	var q listNFTsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.NFTFilter{
+		OwnerID:      optionalID(q.OwnerID),
+		CollectionID: optionalID(q.CollectionID),
+		Chain:        q.Chain,
+	}
 
-	nfts, err := h.service.ListNFTs(uint(ownerID), uint(collectionID), chain)
+	nfts, err := h.service.ListNFTs(filter, q.page())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, nfts)
 }
 
+// Search Handlers
+type searchRequest struct {
+	Text         string   `form:"q"`
+	CollectionID string   `form:"collection_id" binding:"omitempty,id"`
+	Chain        string   `form:"chain"`
+	Status       string   `form:"status" binding:"omitempty,oneof=listed unlisted"`
+	MinPrice     string   `form:"min_price" binding:"omitempty,uint256"`
+	MaxPrice     string   `form:"max_price" binding:"omitempty,uint256"`
+	Limit        string   `form:"limit" binding:"omitempty,number"`
+	Traits       []string `form:"trait" binding:"dive,contains=:"`
+}
+
+func (h *Handler) Search(c *gin.Context) {
+	var req searchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	limit := limitOr(req.Limit, 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	q := core.SearchQuery{
+		Text:         req.Text,
+		CollectionID: optionalID(req.CollectionID),
+		Chain:        req.Chain,
+		Status:       req.Status,
+		MinPrice:     optionalWei(req.MinPrice),
+		MaxPrice:     optionalWei(req.MaxPrice),
+		Limit:        limit,
+	}
+	// Traits are passed as repeated trait=Type:Value parameters.
+	for _, t := range req.Traits {
+		traitType, value, _ := strings.Cut(t, ":")
+		q.Traits = append(q.Traits, core.NFTAttribute{TraitType: traitType, Value: value})
+	}
+
+	result, err := h.service.Search(q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type suggestRequest struct {
+	Text  string `form:"q"`
+	Limit string `form:"limit" binding:"omitempty,number"`
+}
+
+func (h *Handler) Suggest(c *gin.Context) {
+	var req suggestRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	limit := limitOr(req.Limit, 10)
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	suggestions, err := h.service.Suggest(req.Text, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// Currency Handlers
+type createCurrencyRequest struct {
+	Symbol       string `json:"symbol" binding:"required"`
+	Chain        string `json:"chain" binding:"required"`
+	TokenAddress string `json:"token_address" binding:"omitempty,eth_address"`
+	Decimals     *uint8 `json:"decimals"`
+}
+
+func (h *Handler) CreateCurrency(c *gin.Context) {
+	var req createCurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	currency, err := h.service.RegisterCurrency(req.Symbol, req.Chain, req.TokenAddress, req.Decimals)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, currency)
+}
+
+func (h *Handler) ListCurrencies(c *gin.Context) {
+	currencies, err := h.service.ListCurrencies(c.Query("chain"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, currencies)
+}
+
+type updateCurrencyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+func (h *Handler) UpdateCurrency(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req updateCurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	currency, err := h.service.SetCurrencyEnabled(id, *req.Enabled)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, currency)
+}
+
 // Listing Handlers
+type mintNFTRequest struct {
+	Name           string              `json:"name" binding:"required"`
+	Symbol         string              `json:"symbol" binding:"required"`
+	Desc           string              `json:"description"`
+	ImageURL       string              `json:"image_url" binding:"required"`
+	CollectionName string              `json:"collection_name"`
+	Attributes     []core.NFTAttribute `json:"attributes"`
+}
+
 func (h *Handler) MintNFT(c *gin.Context) {
-	var req struct {
-		OwnerID        uint   `json:"owner_id" binding:"required"`
-		Name           string `json:"name" binding:"required"`
-		Symbol         string `json:"symbol" binding:"required"`
-		Desc           string `json:"description"`
-		ImageURL       string `json:"image_url" binding:"required"`
-		CollectionName string `json:"collection_name"`
-	}
+	var req mintNFTRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	nft, err := h.service.MintNFT(req.OwnerID, req.Name, req.Symbol, req.Desc, req.ImageURL, req.CollectionName)
+	nft, intent, err := h.service.MintNFT(actorID(c), req.Name, req.Symbol, req.Desc, req.ImageURL, req.CollectionName, req.Attributes)
 	if err != nil {
-		log.Printf("MintNFT Error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+	if nft == nil {
+		// Still being mined; the intent is finished in the background
+		c.JSON(http.StatusAccepted, intent)
 		return
 	}
 
 	c.JSON(http.StatusCreated, nft)
 }
 
-func (h *Handler) CreateListing(c *gin.Context) {
-	var req struct {
-		NFTID    uint   `json:"nft_id" binding:"required"`
-		SellerID uint   `json:"seller_user_id" binding:"required"`
-		Price    string `json:"price_wei" binding:"required"`
-		Currency string `json:"currency"`
+type burnNFTRequest struct {
+	TxHash string `json:"tx_hash" binding:"required,tx_hash"`
+}
+
+func (h *Handler) BurnNFT(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
+	var req burnNFTRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	intent, err := h.service.BurnNFT(id, actorID(c), req.TxHash)
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	if intent.Status != core.IntentConfirmed {
+		c.JSON(http.StatusAccepted, intent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "burned"})
+}
 
-	if req.Currency == "" {
-		req.Currency = "ETH"
+func (h *Handler) GetIntent(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	intent, err := h.service.GetIntent(actorID(c), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, intent)
+}
+
+type createListingRequest struct {
+	NFTID    uint      `json:"nft_id" binding:"required,id"`
+	Price    *core.Wei `json:"price_wei" binding:"required"`
+	Currency string    `json:"currency"`
+	// ExpiresAt is optional; the listing never expires without it
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *Handler) CreateListing(c *gin.Context) {
+	var req createListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
 	}
 
-	listing, err := h.service.CreateListing(req.NFTID, req.SellerID, req.Price, req.Currency)
+	listing, err := h.service.CreateListing(req.NFTID, actorID(c), *req.Price, req.Currency, req.ExpiresAt)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, listing)
 }
 
+type listListingsQuery struct {
+	pageQuery
+	Currency     string `form:"currency"`
+	SellerID     string `form:"seller_id" binding:"omitempty,id"`
+	CollectionID string `form:"collection_id" binding:"omitempty,id"`
+	Chain        string `form:"chain"`
+	MinPrice     string `form:"min_price" binding:"omitempty,uint256"`
+	MaxPrice     string `form:"max_price" binding:"omitempty,uint256"`
+	// IncludeInvalid also returns listings the chain no longer backs
+	IncludeInvalid bool `form:"include_invalid"`
+}
+
 func (h *Handler) ListListings(c *gin.Context) {
-	listings, err := h.service.ListActiveListings()
+	var q listListingsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.ListingFilter{
+		Currency:       q.Currency,
+		SellerID:       optionalID(q.SellerID),
+		CollectionID:   optionalID(q.CollectionID),
+		Chain:          q.Chain,
+		MinPrice:       optionalWei(q.MinPrice),
+		MaxPrice:       optionalWei(q.MaxPrice),
+		IncludeInvalid: q.IncludeInvalid,
+	}
+
+	listings, err := h.service.ListActiveListings(filter, q.page())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, listings)
 }
 
 func (h *Handler) CancelListing(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	var req struct {
-		UserID uint `json:"user_id" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
-
-	if err := h.service.CancelListing(uint(id), req.UserID); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	if err := h.service.CancelListing(id, actorID(c)); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
 }
 
 // Order Handlers
+type createOrderRequest struct {
+	ListingID uint `json:"listing_id" binding:"required,id"`
+}
+
 func (h *Handler) CreateOrder(c *gin.Context) {
-	var req struct {
-		ListingID uint `json:"listing_id" binding:"required"`
-		BuyerID   uint `json:"buyer_user_id" binding:"required"`
-	}
+	var req createOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	order, err := h.service.CreateOrder(req.ListingID, req.BuyerID)
+	order, err := h.service.CreateOrder(req.ListingID, actorID(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, order)
 }
 
+type confirmOrderRequest struct {
+	TxHash string `json:"tx_hash" binding:"required,tx_hash"`
+}
+
 func (h *Handler) ConfirmOrder(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	var req struct {
-		TxHash string `json:"tx_hash" binding:"required"`
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
+	var req confirmOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	if err := h.service.ConfirmOrder(uint(id), req.TxHash); err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
+	if err := h.service.ConfirmOrder(id, actorID(c), req.TxHash); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "confirmed"})
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/openapi"
+)
+
+// bindings are the structs each operation binds its JSON body and query
+// parameters to.
+var bindings = map[string]struct{ body, query interface{} }{
+	"POST /v1/auth/verify":                   {body: verifyRequest{}},
+	"POST /v1/auth/refresh":                  {body: refreshRequest{}},
+	"POST /v1/auth/api-keys":                 {body: createAPIKeyRequest{}},
+	"GET /v1/auth/api-keys/{id}/usage":       {query: apiKeyUsageQuery{}},
+	"GET /v1/collections":                    {query: listCollectionsQuery{}},
+	"POST /v1/collections":                   {body: createCollectionRequest{}},
+	"GET /v1/nfts":                           {query: listNFTsQuery{}},
+	"POST /v1/nfts":                          {body: registerNFTRequest{}},
+	"POST /v1/nfts/mint":                     {body: mintNFTRequest{}},
+	"POST /v1/nfts/{id}/burn":                {body: burnNFTRequest{}},
+	"GET /v1/search":                         {query: searchRequest{}},
+	"GET /v1/search/suggest":                 {query: suggestRequest{}},
+	"POST /v1/currencies":                    {body: createCurrencyRequest{}},
+	"PATCH /v1/currencies/{id}":              {body: updateCurrencyRequest{}},
+	"GET /v1/listings":                       {query: listListingsQuery{}},
+	"POST /v1/listings":                      {body: createListingRequest{}},
+	"POST /v1/orders":                        {body: createOrderRequest{}},
+	"POST /v1/orders/{id}/confirm":           {body: confirmOrderRequest{}},
+	"POST /v1/webhooks":                      {body: createWebhookRequest{}},
+	"GET /v1/webhooks/{id}/deliveries":       {query: listDeliveriesQuery{}},
+	"GET /v1/admin/users":                    {query: listUsersQuery{}},
+	"PATCH /v1/admin/users/{id}":             {body: updateUserRequest{}},
+	"POST /v1/admin/collections/{id}/verify": {body: verifyCollectionRequest{}},
+	"POST /v1/admin/listings/{id}/cancel":    {body: reasonRequest{}},
+	"POST /v1/admin/orders/{id}/fail":        {body: reasonRequest{}},
+	"GET /v1/admin/audit-log":                {query: auditLogQuery{}},
+	"GET /v1/admin/reconciliation/runs":      {query: pageQuery{}},
+	"GET /v1/events/stream":                  {query: eventStreamQuery{}},
+	"GET /v1/events/ws":                      {query: eventStreamQuery{}},
+}
+
+// unboundQueries are read with c.Query rather than bound to a struct.
+var unboundQueries = map[string][]string{
+	"POST /v1/auth/logout": {"all"},
+	"GET /v1/currencies":   {"chain"},
+}
+
+type specSchema struct {
+	Ref        string                     `json:"$ref"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Required   []string                   `json:"required"`
+}
+
+type specParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+type specOperation struct {
+	Parameters  []specParameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema specSchema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type specDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]specSchema    `json:"schemas"`
+		Parameters map[string]specParameter `json:"parameters"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) specDocument {
+	t.Helper()
+	var doc specDocument
+	if err := json.Unmarshal(openapi.Document, &doc); err != nil {
+		t.Fatalf("parse OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+// operations returns the document's operations by "METHOD path".
+func (d specDocument) operations(t *testing.T) map[string]specOperation {
+	t.Helper()
+	ops := make(map[string]specOperation)
+	for path, item := range d.Paths {
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op specOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("parse %s %s: %v", method, path, err)
+			}
+			ops[strings.ToUpper(method)+" "+path] = op
+		}
+	}
+	return ops
+}
+
+func (d specDocument) schema(s specSchema) specSchema {
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		return d.Components.Schemas[name]
+	}
+	return s
+}
+
+// query returns the operation's query parameters and whether each is required.
+func (d specDocument) query(op specOperation) map[string]bool {
+	params := make(map[string]bool)
+	for _, p := range op.Parameters {
+		if name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/"); ok {
+			p = d.Components.Parameters[name]
+		}
+		if p.In == "query" {
+			params[p.Name] = p.Required
+		}
+	}
+	return params
+}
+
+// fields returns the names v's fields bind under tag and whether each is
+// required, following embedded structs as Gin does.
+func fields(v interface{}, tag string) map[string]bool {
+	out := make(map[string]bool)
+	var walk func(reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			rules := strings.Split(f.Tag.Get("binding"), ",")
+			out[name] = len(rules) > 0 && rules[0] == "required"
+		}
+	}
+	walk(reflect.TypeOf(v))
+	return out
+}
+
+func required(s specSchema) map[string]bool {
+	out := make(map[string]bool)
+	for name := range s.Properties {
+		out[name] = false
+	}
+	for _, name := range s.Required {
+		out[name] = true
+	}
+	return out
+}
+
+func diff(t *testing.T, what string, documented, bound map[string]bool) {
+	t.Helper()
+	var problems []string
+	for name, req := range documented {
+		if b, ok := bound[name]; !ok {
+			problems = append(problems, name+" is documented but not bound")
+		} else if b != req {
+			problems = append(problems, name+" is required in only one of them")
+		}
+	}
+	for name := range bound {
+		if _, ok := documented[name]; !ok {
+			problems = append(problems, name+" is bound but not documented")
+		}
+	}
+	sort.Strings(problems)
+	for _, p := range problems {
+		t.Errorf("%s: %s", what, p)
+	}
+}
+
+func TestBindingsMatchOpenAPI(t *testing.T) {
+	doc := loadSpec(t)
+	ops := doc.operations(t)
+
+	for key := range bindings {
+		if _, ok := ops[key]; !ok {
+			t.Errorf("%s is not in the OpenAPI document", key)
+		}
+	}
+	for key, op := range ops {
+		b := bindings[key]
+		if op.RequestBody != nil {
+			media, ok := op.RequestBody.Content["application/json"]
+			if !ok || b.body == nil {
+				t.Errorf("%s: request body has no JSON binding", key)
+			} else {
+				diff(t, key+" body", required(doc.schema(media.Schema)), fields(b.body, "json"))
+			}
+		} else if b.body != nil {
+			t.Errorf("%s: bound body is not documented", key)
+		}
+
+		bound := map[string]bool{}
+		if b.query != nil {
+			bound = fields(b.query, "form")
+		}
+		for _, name := range unboundQueries[key] {
+			bound[name] = false
+		}
+		diff(t, key+" query", doc.query(op), bound)
+	}
+}
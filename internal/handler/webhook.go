@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/middleware"
+)
+
+// webhookOwner is the owner of the webhooks the request may manage; routes
+// using it sit behind middleware.RequireAuth.
+func webhookOwner(c *gin.Context) core.WebhookOwner {
+	return middleware.PrincipalFrom(c).WebhookOwner()
+}
+
+// Webhook Handlers
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	types, err := core.ParseEventTypes(req.EventTypes)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	hook, secret, err := h.webhooks.CreateWebhook(webhookOwner(c), req.URL, types)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// The signing secret is only ever shown in this response.
+	c.JSON(http.StatusCreated, gin.H{"secret": secret, "webhook": hook})
+}
+
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhooks.ListWebhooks(webhookOwner(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.webhooks.DeleteWebhook(webhookOwner(c), id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+type listDeliveriesQuery struct {
+	pageQuery
+	Status string `form:"status" binding:"omitempty,oneof=PENDING SUCCEEDED DEAD"`
+}
+
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var q listDeliveriesQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+	filter := core.DeliveryFilter{Status: core.DeliveryStatus(q.Status)}
+
+	deliveries, err := h.webhooks.ListDeliveries(webhookOwner(c), id, filter, q.page())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	id, err := idParam(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	delivery, err := h.webhooks.Redeliver(webhookOwner(c), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, delivery)
+}
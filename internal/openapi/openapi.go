@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Document is the OpenAPI 3 description of the API. The Go client in
+// pkg/client is generated from it.
+//
+//go:embed openapi.json
+var Document []byte
+
+const swaggerUIVersion = "5.17.14"
+
+// docsScript starts Swagger UI; the docs page's CSP allows it by hash.
+const docsScript = `SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });`
+
+var docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>NFT Marketplace API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js"></script>
+  <script>` + docsScript + `</script>
+</body>
+</html>
+`
+
+var docsPolicy = func() string {
+	sum := sha256.Sum256([]byte(docsScript))
+	return "default-src 'none'; connect-src 'self'; img-src 'self' data: https://unpkg.com; " +
+		"style-src https://unpkg.com 'unsafe-inline'; " +
+		"script-src https://unpkg.com 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'; " +
+		"frame-ancestors 'none'"
+}()
+
+// ServeDocument serves the OpenAPI document.
+func ServeDocument(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", Document)
+}
+
+// ServeDocs serves Swagger UI for the document, loosening the API's
+// Content-Security-Policy just enough for it to run.
+func ServeDocs(c *gin.Context) {
+	c.Header("Content-Security-Policy", docsPolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// CheckRoutes reports routes the document does not describe and operations
+// it describes that have no route, so the two cannot drift apart. Paths in
+// ignore, like the docs routes themselves, are skipped.
+func CheckRoutes(routes gin.RoutesInfo, ignore ...string) error {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Document, &doc); err != nil {
+		return fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	skip := make(map[string]bool)
+	for _, path := range ignore {
+		skip[path] = true
+	}
+
+	var problems []string
+	for _, r := range routes {
+		if skip[r.Path] {
+			continue
+		}
+		op := r.Method + " " + templatePath(r.Path)
+		if !documented[op] {
+			problems = append(problems, "undocumented route "+op)
+		}
+		delete(documented, op)
+	}
+	for op := range documented {
+		if method, _, _ := strings.Cut(op, " "); isMethod(method) {
+			problems = append(problems, "documented operation without a route "+op)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI document is out of date: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// templatePath turns Gin's :param and *param segments into {param}.
+func templatePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isMethod filters out path item keys that are not operations, such as
+// shared parameters.
+func isMethod(s string) bool {
+	switch s {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/core"
 )
 
 type Client struct {
@@ -26,6 +27,7 @@ type Client struct {
 
 	nftABI    abi.ABI
 	marketABI abi.ABI
+	erc20ABI  abi.ABI
 
 	nftAddr    common.Address
 	marketAddr common.Address
@@ -56,6 +58,15 @@ func NewClient(cfg config.EthConfig) (*Client, error) {
 		return nil, fmt.Errorf("parse market abi: %w", err)
 	}
 
+	erc20ABIBytes, err := os.ReadFile("internal/abi/ERC20.json")
+	if err != nil {
+		return nil, fmt.Errorf("read erc20 abi: %w", err)
+	}
+	erc20ABI, err := abi.JSON(strings.NewReader(string(erc20ABIBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("parse erc20 abi: %w", err)
+	}
+
 	// Detect ChainID from RPC
 	detectedChainID, err := rpc.NetworkID(context.Background())
 	if err != nil {
@@ -69,6 +80,7 @@ func NewClient(cfg config.EthConfig) (*Client, error) {
 		rpc:        rpc,
 		nftABI:     nftABI,
 		marketABI:  marketABI,
+		erc20ABI:   erc20ABI,
 		nftAddr:    common.HexToAddress(cfg.NFTAddress),
 		marketAddr: common.HexToAddress(cfg.MarketAddress),
 	}, nil
@@ -151,7 +163,7 @@ func (c *Client) Approve(tokenId string) (string, error) {
 	return tx.Hash().Hex(), nil
 }
 
-func (c *Client) List(tokenId, priceWei string) (string, error) {
+func (c *Client) List(tokenId string, price core.Wei) (string, error) {
 	auth, err := c.txOpts(c.cfg.SellerPrivateKey)
 	if err != nil {
 		return "", err
@@ -161,13 +173,9 @@ func (c *Client) List(tokenId, priceWei string) (string, error) {
 	if !ok {
 		return "", errors.New("invalid token id")
 	}
-	price, ok := new(big.Int).SetString(priceWei, 10)
-	if !ok {
-		return "", errors.New("invalid price")
-	}
 
 	market := bind.NewBoundContract(c.marketAddr, c.marketABI, c.rpc, c.rpc, c.rpc)
-	tx, err := market.Transact(auth, "list", c.nftAddr, tid, price)
+	tx, err := market.Transact(auth, "list", c.nftAddr, tid, price.BigInt())
 	if err != nil {
 		return "", fmt.Errorf("list tx: %w", err)
 	}
@@ -177,7 +185,7 @@ func (c *Client) List(tokenId, priceWei string) (string, error) {
 	return tx.Hash().Hex(), nil
 }
 
-func (c *Client) Buy(tokenId, priceWei string) (string, error) {
+func (c *Client) Buy(tokenId string, price core.Wei) (string, error) {
 	auth, err := c.txOpts(c.cfg.BuyerPrivateKey)
 	if err != nil {
 		return "", err
@@ -187,13 +195,9 @@ func (c *Client) Buy(tokenId, priceWei string) (string, error) {
 	if !ok {
 		return "", errors.New("invalid token id")
 	}
-	price, ok := new(big.Int).SetString(priceWei, 10)
-	if !ok {
-		return "", errors.New("invalid price")
-	}
 	
 	// Value must match price
-	auth.Value = price
+	auth.Value = price.BigInt()
 
 	market := bind.NewBoundContract(c.marketAddr, c.marketABI, c.rpc, c.rpc, c.rpc)
 	tx, err := market.Transact(auth, "buy", c.nftAddr, tid)
@@ -350,23 +354,90 @@ func (c *Client) Delist(tokenId string) (string, error) {
 	return tx.Hash().Hex(), nil
 }
 
-func (c *Client) GetListing(tokenId string) (price string, seller string, active bool, err error) {
+func (c *Client) GetListing(tokenId string) (*core.ListingInfo, error) {
 	tid, ok := new(big.Int).SetString(tokenId, 10)
 	if !ok {
-		return "", "", false, errors.New("invalid token id")
+		return nil, errors.New("invalid token id")
 	}
 
 	market := bind.NewBoundContract(c.marketAddr, c.marketABI, c.rpc, c.rpc, c.rpc)
-	
-	// The struct returns (price, seller, active)
-	var priceOut *big.Int
-	var sellerOut common.Address
-	var activeOut bool
-	
-	results := []interface{}{&priceOut, &sellerOut, &activeOut}
+
+	// getListing returns a single Listing tuple
+	var out struct {
+		Price    *big.Int
+		Seller   common.Address
+		Active   bool
+		Currency common.Address
+	}
+	results := []interface{}{&out}
 	if err := market.Call(&bind.CallOpts{}, &results, "getListing", c.nftAddr, tid); err != nil {
-		return "", "", false, err
+		return nil, err
 	}
-	
-	return priceOut.String(), sellerOut.Hex(), activeOut, nil
+
+	info := &core.ListingInfo{
+		TokenID: tokenId,
+		Price:   core.NewWei(out.Price),
+		Seller:  out.Seller.Hex(),
+		Active:  out.Active,
+	}
+	if out.Currency != (common.Address{}) {
+		info.Currency = out.Currency.Hex()
+	}
+	return info, nil
+}
+
+func (c *Client) callToken(token, method string, params ...interface{}) (*big.Int, error) {
+	erc20 := bind.NewBoundContract(common.HexToAddress(token), c.erc20ABI, c.rpc, c.rpc, c.rpc)
+	var out *big.Int
+	results := []interface{}{&out}
+	if err := erc20.Call(&bind.CallOpts{}, &results, method, params...); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	return out, nil
+}
+
+// TokenAllowance returns how much of token owner has approved the marketplace to spend.
+func (c *Client) TokenAllowance(token, owner string) (core.Wei, error) {
+	out, err := c.callToken(token, "allowance", common.HexToAddress(owner), c.marketAddr)
+	if err != nil {
+		return core.Wei{}, err
+	}
+	return core.NewWei(out), nil
+}
+
+func (c *Client) TokenBalance(token, owner string) (core.Wei, error) {
+	out, err := c.callToken(token, "balanceOf", common.HexToAddress(owner))
+	if err != nil {
+		return core.Wei{}, err
+	}
+	return core.NewWei(out), nil
+}
+
+func (c *Client) TokenDecimals(token string) (uint8, error) {
+	erc20 := bind.NewBoundContract(common.HexToAddress(token), c.erc20ABI, c.rpc, c.rpc, c.rpc)
+	var out uint8
+	results := []interface{}{&out}
+	if err := erc20.Call(&bind.CallOpts{}, &results, "decimals"); err != nil {
+		return 0, fmt.Errorf("decimals: %w", err)
+	}
+	return out, nil
+}
+
+// CheckTokenPayment verifies buyer can pay price in token through the marketplace.
+func (c *Client) CheckTokenPayment(token, buyer string, price core.Wei) error {
+	allowance, err := c.TokenAllowance(token, buyer)
+	if err != nil {
+		return err
+	}
+	if allowance.Cmp(price) < 0 {
+		return fmt.Errorf("%w: allowance %s is below price %s", core.ErrInsufficientAllowance, allowance, price)
+	}
+	balance, err := c.TokenBalance(token, buyer)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(price) < 0 {
+		return fmt.Errorf("%w: balance %s is below price %s", core.ErrInsufficientBalance, balance, price)
+	}
+	return nil
 }
@@ -0,0 +1,259 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// transferEvent is the topic of Transfer(address,address,uint256).
+var transferEvent = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// defaultMemoryNFTAddress is the contract a MemoryChain mints on when no
+// NFT address is configured.
+const defaultMemoryNFTAddress = "0x00000000000000000000000000000000000000aa"
+
+// memoryTx is a mint signed by a MemoryChain.
+type memoryTx struct {
+	hash common.Hash
+	to   common.Address
+}
+
+// MemoryChain simulates a node for the demo mode: transactions are mined as
+// soon as they are sent, the NFT contract keeps its tokens in memory and the
+// marketplace is approved for, and lists, every token for its owner unless
+// the owner revokes the approval. ERC20 payments always go through.
+type MemoryChain struct {
+	mu       sync.Mutex
+	nftAddr  common.Address
+	nonce    uint64
+	nextID   int64
+	signed   map[string]memoryTx
+	receipts map[common.Hash]*types.Receipt
+	owners   map[string]common.Address
+	revoked  map[common.Address]bool
+}
+
+func NewMemoryChain(nftAddress string) *MemoryChain {
+	if nftAddress == "" {
+		nftAddress = defaultMemoryNFTAddress
+	}
+	return &MemoryChain{
+		nftAddr:  common.HexToAddress(nftAddress),
+		nextID:   1,
+		signed:   make(map[string]memoryTx),
+		receipts: make(map[common.Hash]*types.Receipt),
+		owners:   make(map[string]common.Address),
+		revoked:  make(map[common.Address]bool),
+	}
+}
+
+func (m *MemoryChain) GetNFTAddress() string {
+	return m.nftAddr.Hex()
+}
+
+// newHash returns a transaction hash not used before.
+func (m *MemoryChain) newHash() common.Hash {
+	m.nonce++
+	return crypto.Keccak256Hash(m.nftAddr.Bytes(), new(big.Int).SetUint64(m.nonce).Bytes())
+}
+
+// sign records tx under a fresh hash, its raw form being the hash itself.
+func (m *MemoryChain) sign(tx memoryTx) *core.SignedTx {
+	tx.hash = m.newHash()
+	raw := hexutil.Encode(tx.hash.Bytes())
+	m.signed[raw] = tx
+	return &core.SignedTx{Hash: tx.hash.Hex(), Raw: raw}
+}
+
+// SignMint signs, without sending, a mint of a token to to.
+func (m *MemoryChain) SignMint(to string, tokenURI string) (*core.SignedTx, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sign(memoryTx{to: common.HexToAddress(to)}), nil
+}
+
+// Burn mines at once a burn of a token sent by the wallet from, as its
+// owner's wallet would send it, and returns the transaction hash. As on the
+// contract, the burn reverts unless from owns the token.
+func (m *MemoryChain) Burn(from, tokenId string) (string, error) {
+	tid, err := parseTokenID(tokenId)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      m.newHash(),
+		BlockNumber: big.NewInt(int64(len(m.receipts) + 1)),
+	}
+	owner, exists := m.owners[tid.String()]
+	if !exists || owner != common.HexToAddress(from) {
+		receipt.Status = types.ReceiptStatusFailed
+	} else {
+		delete(m.owners, tid.String())
+		receipt.Logs = []*types.Log{m.transferLog(receipt, owner, common.Address{}, tid)}
+	}
+	m.receipts[receipt.TxHash] = receipt
+	return receipt.TxHash.Hex(), nil
+}
+
+// Transfer mines at once a transfer of a token from its owner's wallet to
+// to, made outside the marketplace, and returns the transaction hash. As on
+// the contract, it reverts unless from owns the token.
+func (m *MemoryChain) Transfer(from, to, tokenId string) (string, error) {
+	tid, err := parseTokenID(tokenId)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      m.newHash(),
+		BlockNumber: big.NewInt(int64(len(m.receipts) + 1)),
+	}
+	owner, exists := m.owners[tid.String()]
+	if !exists || owner != common.HexToAddress(from) {
+		receipt.Status = types.ReceiptStatusFailed
+	} else {
+		m.owners[tid.String()] = common.HexToAddress(to)
+		receipt.Logs = []*types.Log{m.transferLog(receipt, owner, common.HexToAddress(to), tid)}
+	}
+	m.receipts[receipt.TxHash] = receipt
+	return receipt.TxHash.Hex(), nil
+}
+
+func (m *MemoryChain) transferLog(receipt *types.Receipt, from, to common.Address, tokenID *big.Int) *types.Log {
+	return &types.Log{
+		Address: m.nftAddr,
+		Topics: []common.Hash{
+			transferEvent,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+			common.BigToHash(tokenID),
+		},
+		TxHash:      receipt.TxHash,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+	}
+}
+
+// SendRaw mines a signed mint at once. Sending one already mined is not an
+// error.
+func (m *MemoryChain) SendRaw(raw string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx, ok := m.signed[raw]
+	if !ok {
+		return fmt.Errorf("decode tx: unknown transaction %s", raw)
+	}
+	if _, mined := m.receipts[tx.hash]; mined {
+		return nil
+	}
+
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      tx.hash,
+		BlockNumber: big.NewInt(int64(len(m.receipts) + 1)),
+	}
+	tokenID := big.NewInt(m.nextID)
+	m.nextID++
+	m.owners[tokenID.String()] = tx.to
+	receipt.Logs = []*types.Log{m.transferLog(receipt, common.Address{}, tx.to, tokenID)}
+	m.receipts[tx.hash] = receipt
+	return nil
+}
+
+// Receipt returns the receipt of a mined transaction, or nil if it has not
+// been sent.
+func (m *MemoryChain) Receipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.receipts[common.HexToHash(txHash)], nil
+}
+
+// WaitReceipt returns the receipt of a transaction, which must have been
+// sent since transactions are mined as they are.
+func (m *MemoryChain) WaitReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	receipt, err := m.Receipt(ctx, txHash)
+	if err == nil && receipt == nil {
+		err = fmt.Errorf("transaction %s was never sent", txHash)
+	}
+	return receipt, err
+}
+
+func (m *MemoryChain) MintedTokenID(receipt *types.Receipt) (string, error) {
+	return zeroTransferTokenID(receipt, m.nftAddr, transferEvent, transferFrom)
+}
+
+func (m *MemoryChain) BurnedTokenID(receipt *types.Receipt) (string, error) {
+	return zeroTransferTokenID(receipt, m.nftAddr, transferEvent, transferTo)
+}
+
+// OwnersOf returns the owner of each token, or "" for tokens that do not
+// exist.
+func (m *MemoryChain) OwnersOf(ctx context.Context, tokenIds []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	owners := make([]string, len(tokenIds))
+	for i, id := range tokenIds {
+		tid, err := parseTokenID(id)
+		if err != nil {
+			return nil, err
+		}
+		if owner, ok := m.owners[tid.String()]; ok {
+			owners[i] = owner.Hex()
+		}
+	}
+	return owners, nil
+}
+
+// SetApprovalForAll approves the marketplace for every token of owner or,
+// with approved false, revokes the approval, as the owner's wallet would.
+func (m *MemoryChain) SetApprovalForAll(owner string, approved bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked[common.HexToAddress(owner)] = !approved
+}
+
+// MarketplaceApproved reports every token as approved unless its owner
+// revoked the approval.
+func (m *MemoryChain) MarketplaceApproved(ctx context.Context, tokens []core.TokenOwner) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	approved := make([]bool, len(tokens))
+	for i, t := range tokens {
+		approved[i] = !m.revoked[common.HexToAddress(t.Owner)]
+	}
+	return approved, nil
+}
+
+// MarketListings reports every existing token as listed by its owner, with
+// no price of its own.
+func (m *MemoryChain) MarketListings(ctx context.Context, tokenIds []string) ([]core.ListingInfo, error) {
+	owners, err := m.OwnersOf(ctx, tokenIds)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]core.ListingInfo, len(tokenIds))
+	for i, owner := range owners {
+		listings[i] = core.ListingInfo{TokenID: tokenIds[i], Seller: owner, Active: owner != ""}
+	}
+	return listings, nil
+}
+
+func (m *MemoryChain) TokenDecimals(token string) (uint8, error) {
+	return 18, nil
+}
+
+func (m *MemoryChain) CheckTokenPayment(token, buyer string, price core.Wei) error {
+	return nil
+}
@@ -0,0 +1,296 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// multicallABI is the aggregate3 function of Multicall3.
+const multicallABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// multicallBatch bounds how many calls go into one aggregate3 call.
+const multicallBatch = 200
+
+var parsedMulticallABI = mustParseABI(multicallABI)
+
+// nonexistentTokenSelector starts the revert data of ownerOf for a token that
+// was never minted or was burned: OpenZeppelin's ERC721NonexistentToken.
+var nonexistentTokenSelector = crypto.Keccak256([]byte("ERC721NonexistentToken(uint256)"))[:4]
+
+func mustParseABI(s string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// call is one contract read to batch.
+type call struct {
+	target common.Address
+	abi    *abi.ABI
+	method string
+	params []interface{}
+}
+
+// callResult is the outcome of a call; OK is false when the call failed,
+// with the revert data, if any, in Revert.
+type callResult struct {
+	OK     bool
+	Out    []interface{}
+	Revert []byte
+}
+
+// multicall makes calls in batches through Multicall3, or one by one when
+// it is not configured or deployed. Reverted calls are reported in their
+// result rather than as an error; results that do not decode fail the
+// whole batch, so a wrong contract address never reads as reverts.
+func (c *Client) multicall(ctx context.Context, calls []call) ([]callResult, error) {
+	useMulticall, err := c.hasMulticall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !useMulticall {
+		return c.callEach(ctx, calls)
+	}
+
+	results := make([]callResult, 0, len(calls))
+	for start := 0; start < len(calls); start += multicallBatch {
+		end := min(start+multicallBatch, len(calls))
+		batch, err := c.aggregate(ctx, calls[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func (c *Client) hasMulticall(ctx context.Context) (bool, error) {
+	if c.cfg.MulticallAddress == "" {
+		return false, nil
+	}
+	code, err := c.rpc.CodeAt(ctx, common.HexToAddress(c.cfg.MulticallAddress), nil)
+	if err != nil {
+		return false, fmt.Errorf("multicall code: %w", err)
+	}
+	return len(code) > 0, nil
+}
+
+func (c *Client) aggregate(ctx context.Context, calls []call) ([]callResult, error) {
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	in := make([]call3, len(calls))
+	for i, cl := range calls {
+		data, err := cl.abi.Pack(cl.method, cl.params...)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", cl.method, err)
+		}
+		in[i] = call3{Target: cl.target, AllowFailure: true, CallData: data}
+	}
+
+	multicall := bind.NewBoundContract(common.HexToAddress(c.cfg.MulticallAddress), parsedMulticallABI, c.rpc, c.rpc, c.rpc)
+	var raw []interface{}
+	if err := multicall.Call(&bind.CallOpts{Context: ctx}, &raw, "aggregate3", in); err != nil {
+		return nil, fmt.Errorf("aggregate3: %w", err)
+	}
+	out := *abi.ConvertType(raw[0], new([]struct {
+		Success    bool
+		ReturnData []byte
+	})).(*[]struct {
+		Success    bool
+		ReturnData []byte
+	})
+	if len(out) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(out), len(calls))
+	}
+
+	results := make([]callResult, len(calls))
+	for i, r := range out {
+		if !r.Success {
+			results[i].Revert = r.ReturnData
+			continue
+		}
+		values, err := calls[i].abi.Unpack(calls[i].method, r.ReturnData)
+		if err != nil {
+			// The target has no code or is not the contract we think
+			return nil, fmt.Errorf("unpack %s: %w", calls[i].method, err)
+		}
+		results[i] = callResult{OK: true, Out: values}
+	}
+	return results, nil
+}
+
+func (c *Client) callEach(ctx context.Context, calls []call) ([]callResult, error) {
+	results := make([]callResult, len(calls))
+	for i, cl := range calls {
+		data, err := cl.abi.Pack(cl.method, cl.params...)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: %w", cl.method, err)
+		}
+		target := cl.target
+		ret, err := c.rpc.CallContract(ctx, ethereum.CallMsg{To: &target, Data: data}, nil)
+		if err != nil {
+			if data, ok := revertData(err); ok {
+				results[i].Revert = data
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", cl.method, err)
+		}
+		values, err := cl.abi.Unpack(cl.method, ret)
+		if err != nil {
+			return nil, fmt.Errorf("unpack %s: %w", cl.method, err)
+		}
+		results[i] = callResult{OK: true, Out: values}
+	}
+	return results, nil
+}
+
+// revertData returns the revert data of a call the contract rejected. Other
+// failures, including reverts that carry no data, are not reverts: nothing
+// tells them from a node that failed to make the call.
+func revertData(err error) ([]byte, bool) {
+	var dataErr interface{ ErrorData() interface{} }
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	hex, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, err := hexutil.Decode(hex)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// isNonexistentToken reports whether revert is ERC721NonexistentToken for
+// tokenId.
+func isNonexistentToken(revert []byte, tokenId *big.Int) bool {
+	return len(revert) == 36 && bytes.Equal(revert[:4], nonexistentTokenSelector) &&
+		new(big.Int).SetBytes(revert[4:]).Cmp(tokenId) == 0
+}
+
+// OwnersOf returns the owner of each token of our NFT contract, or "" for
+// tokens that do not exist, such as burned ones. A token only counts as
+// nonexistent when ownerOf reverts with ERC721NonexistentToken; any other
+// failure fails the whole call, so it is never taken for a burn.
+func (c *Client) OwnersOf(ctx context.Context, tokenIds []string) ([]string, error) {
+	calls := make([]call, len(tokenIds))
+	tids := make([]*big.Int, len(tokenIds))
+	for i, id := range tokenIds {
+		tid, err := parseTokenID(id)
+		if err != nil {
+			return nil, err
+		}
+		tids[i] = tid
+		calls[i] = call{target: c.nftAddr, abi: &c.nftABI, method: "ownerOf", params: []interface{}{tid}}
+	}
+	results, err := c.multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	owners := make([]string, len(tokenIds))
+	for i, r := range results {
+		switch {
+		case r.OK:
+			owners[i] = r.Out[0].(common.Address).Hex()
+		case !isNonexistentToken(r.Revert, tids[i]):
+			return nil, fmt.Errorf("ownerOf token %s failed (revert data %s)", tokenIds[i], hexutil.Encode(r.Revert))
+		}
+	}
+	return owners, nil
+}
+
+// MarketplaceApproved reports for each token whether its owner lets the
+// marketplace transfer it, through getApproved or isApprovedForAll.
+func (c *Client) MarketplaceApproved(ctx context.Context, tokens []core.TokenOwner) ([]bool, error) {
+	calls := make([]call, 0, 2*len(tokens))
+	for _, t := range tokens {
+		tid, err := parseTokenID(t.TokenID)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls,
+			call{target: c.nftAddr, abi: &c.nftABI, method: "getApproved", params: []interface{}{tid}},
+			call{target: c.nftAddr, abi: &c.nftABI, method: "isApprovedForAll", params: []interface{}{common.HexToAddress(t.Owner), c.marketAddr}},
+		)
+	}
+	results, err := c.multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	approved := make([]bool, len(tokens))
+	for i := range tokens {
+		single, all := results[2*i], results[2*i+1]
+		approved[i] = single.OK && single.Out[0].(common.Address) == c.marketAddr ||
+			all.OK && all.Out[0].(bool)
+	}
+	return approved, nil
+}
+
+// MarketListings returns the marketplace's listing of each token of our NFT
+// contract; tokens it has no listing for come back inactive.
+func (c *Client) MarketListings(ctx context.Context, tokenIds []string) ([]core.ListingInfo, error) {
+	calls := make([]call, len(tokenIds))
+	for i, id := range tokenIds {
+		tid, err := parseTokenID(id)
+		if err != nil {
+			return nil, err
+		}
+		calls[i] = call{target: c.marketAddr, abi: &c.marketABI, method: "getListing", params: []interface{}{c.nftAddr, tid}}
+	}
+	results, err := c.multicall(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	listings := make([]core.ListingInfo, len(tokenIds))
+	for i, r := range results {
+		listings[i].TokenID = tokenIds[i]
+		if !r.OK {
+			continue
+		}
+		out := *abi.ConvertType(r.Out[0], new(struct {
+			Price    *big.Int
+			Seller   common.Address
+			Active   bool
+			Currency common.Address
+		})).(*struct {
+			Price    *big.Int
+			Seller   common.Address
+			Active   bool
+			Currency common.Address
+		})
+		listings[i].Price = core.NewWei(out.Price)
+		listings[i].Seller = out.Seller.Hex()
+		listings[i].Active = out.Active
+		if out.Currency != (common.Address{}) {
+			listings[i].Currency = out.Currency.Hex()
+		}
+	}
+	return listings, nil
+}
+
+func parseTokenID(tokenId string) (*big.Int, error) {
+	tid, ok := new(big.Int).SetString(tokenId, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid token id %q", tokenId)
+	}
+	return tid, nil
+}
@@ -0,0 +1,58 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// rpcError mimics the errors of the RPC client, which carry revert data.
+type rpcError struct {
+	msg  string
+	data interface{}
+}
+
+func (e rpcError) Error() string          { return e.msg }
+func (e rpcError) ErrorData() interface{} { return e.data }
+
+func nonexistentRevert(tokenId int64) []byte {
+	return append(append([]byte{}, nonexistentTokenSelector...), common.LeftPadBytes(big.NewInt(tokenId).Bytes(), 32)...)
+}
+
+func TestIsNonexistentToken(t *testing.T) {
+	notOwner := append([]byte{0x08, 0xc3, 0x79, 0xa0}, make([]byte, 32)...) // Error(string)
+	for name, tc := range map[string]struct {
+		revert []byte
+		want   bool
+	}{
+		"nonexistent": {nonexistentRevert(7), true},
+		"other token": {nonexistentRevert(8), false},
+		"no data":     {nil, false},
+		"other error": {notOwner, false},
+		"truncated":   {nonexistentRevert(7)[:20], false},
+	} {
+		if got := isNonexistentToken(tc.revert, big.NewInt(7)); got != tc.want {
+			t.Errorf("%s: isNonexistentToken = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestRevertData(t *testing.T) {
+	revert := nonexistentRevert(7)
+	if data, ok := revertData(fmt.Errorf("ownerOf: %w", rpcError{"execution reverted", hexutil.Encode(revert)})); !ok || string(data) != string(revert) {
+		t.Fatalf("revertData of a revert = %x, %v", data, ok)
+	}
+	for name, err := range map[string]error{
+		"rpc error without data": rpcError{"header not found", nil},
+		"revert without data":    rpcError{"execution reverted", "0x"},
+		"transport error":        errors.New("connection refused: execution reverted"),
+	} {
+		if _, ok := revertData(err); ok {
+			t.Errorf("%s: taken for a revert", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func (c *Client) sign(privHex string, contract common.Address, contractABI abi.ABI, method string, params ...interface{}) (*core.SignedTx, error) {
+	auth, err := c.txOpts(privHex)
+	if err != nil {
+		return nil, err
+	}
+	auth.NoSend = true
+
+	bound := bind.NewBoundContract(contract, contractABI, c.rpc, c.rpc, c.rpc)
+	tx, err := bound.Transact(auth, method, params...)
+	if err != nil {
+		return nil, fmt.Errorf("%s tx: %w", method, err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &core.SignedTx{Hash: tx.Hash().Hex(), Raw: hexutil.Encode(raw)}, nil
+}
+
+// SignMint signs, without sending, a mint of a token to to.
+func (c *Client) SignMint(to string, tokenURI string) (*core.SignedTx, error) {
+	return c.sign(c.cfg.OwnerPrivateKey, c.nftAddr, c.nftABI, "mint", common.HexToAddress(to), tokenURI)
+}
+
+// SendRaw broadcasts a signed transaction. Broadcasting one the node already
+// has is not an error, so SendRaw may be retried freely.
+func (c *Client) SendRaw(raw string) error {
+	b, err := hexutil.Decode(raw)
+	if err != nil {
+		return fmt.Errorf("decode tx: %w", err)
+	}
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(b); err != nil {
+		return fmt.Errorf("decode tx: %w", err)
+	}
+
+	err = c.rpc.SendTransaction(context.Background(), &tx)
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "already known"), strings.Contains(msg, "known transaction"):
+		return nil
+	case strings.Contains(msg, "nonce too low"):
+		return fmt.Errorf("%w: %v", core.ErrNonceUsed, err)
+	}
+	return err
+}
+
+// Receipt returns the receipt of a mined transaction, or nil if it has not
+// been mined.
+func (c *Client) Receipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	receipt, err := c.rpc.TransactionReceipt(ctx, common.HexToHash(txHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	return receipt, err
+}
+
+// WaitReceipt polls for the receipt of a transaction until it is mined or
+// ctx is done.
+func (c *Client) WaitReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	for {
+		receipt, err := c.Receipt(ctx, txHash)
+		if err == nil && receipt != nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return nil, err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// MintedTokenID reads the id of the token minted by a transaction from its
+// Transfer log from the zero address.
+func (c *Client) MintedTokenID(receipt *types.Receipt) (string, error) {
+	return zeroTransferTokenID(receipt, c.nftAddr, c.nftABI.Events["Transfer"].ID, transferFrom)
+}
+
+// BurnedTokenID reads the id of the token burned by a transaction from its
+// Transfer log to the zero address.
+func (c *Client) BurnedTokenID(receipt *types.Receipt) (string, error) {
+	return zeroTransferTokenID(receipt, c.nftAddr, c.nftABI.Events["Transfer"].ID, transferTo)
+}
+
+// The topics of Transfer(address indexed from, address indexed to,
+// uint256 indexed tokenId) holding its addresses.
+const (
+	transferFrom = 1
+	transferTo   = 2
+)
+
+// zeroTransferTokenID reads the token id of the first Transfer log of
+// nftAddr whose address in topic is zero: a mint or a burn.
+func zeroTransferTokenID(receipt *types.Receipt, nftAddr common.Address, transfer common.Hash, topic int) (string, error) {
+	for _, l := range receipt.Logs {
+		if l.Address != nftAddr || len(l.Topics) != 4 || l.Topics[0] != transfer {
+			continue
+		}
+		if common.BytesToAddress(l.Topics[topic].Bytes()) != (common.Address{}) {
+			continue
+		}
+		return l.Topics[3].Big().String(), nil
+	}
+	if topic == transferTo {
+		return "", errors.New("no burn transfer in receipt")
+	}
+	return "", errors.New("no mint transfer in receipt")
+}
@@ -0,0 +1,45 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestZeroTransferTokenID(t *testing.T) {
+	nft := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	wallet := common.HexToAddress("0x00000000000000000000000000000000000000c3")
+	transfer := common.HexToHash("0x01")
+	logOf := func(contract, from, to common.Address, tokenID int64) *types.Log {
+		return &types.Log{Address: contract, Topics: []common.Hash{
+			transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(tokenID)),
+		}}
+	}
+	receipt := &types.Receipt{Logs: []*types.Log{
+		logOf(other, common.Address{}, wallet, 1),       // mint on another contract
+		logOf(nft, wallet, other, 2),                    // plain transfer
+		logOf(nft, common.Address{}, wallet, 3),         // mint
+		logOf(nft, wallet, common.Address{}, 4),         // burn
+		{Address: nft, Topics: []common.Hash{transfer}}, // not a Transfer(address,address,uint256)
+	}}
+
+	for name, tc := range map[string]struct {
+		topic int
+		want  string
+	}{
+		"mint": {transferFrom, "3"},
+		"burn": {transferTo, "4"},
+	} {
+		got, err := zeroTransferTokenID(receipt, nft, transfer, tc.topic)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: token id = %q, %v; want %q", name, got, err, tc.want)
+		}
+	}
+
+	if _, err := zeroTransferTokenID(&types.Receipt{Logs: receipt.Logs[:2]}, nft, transfer, transferTo); err == nil {
+		t.Error("expected an error for a receipt without a burn")
+	}
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// ErrTooManySubscribers is returned when the hub is at its subscriber limit.
+var ErrTooManySubscribers = errors.New("too many event subscribers")
+
+// Hub fans published events out to the subscribers in this process whose
+// filter matches. Events are persisted before they are published, so
+// subscribers that miss some catch up from the event log.
+type Hub struct {
+	mu             sync.Mutex
+	subs           map[*Subscription]struct{}
+	buffer         int
+	maxSubscribers int
+	closed         bool
+}
+
+// NewHub returns a hub whose subscribers may fall buffer events behind
+// before they are dropped.
+func NewHub(buffer, maxSubscribers int) *Hub {
+	return &Hub{
+		subs:           make(map[*Subscription]struct{}),
+		buffer:         buffer,
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// Subscription receives the events matching its filter until it is closed.
+type Subscription struct {
+	hub    *Hub
+	filter core.EventFilter
+	ch     chan core.Event
+}
+
+// Events yields the subscription's events. It is closed when the
+// subscription is, including when the subscriber fell too far behind.
+func (s *Subscription) Events() <-chan core.Event {
+	return s.ch
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (h *Hub) Subscribe(filter core.EventFilter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || len(h.subs) >= h.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	sub := &Subscription{hub: h, filter: filter, ch: make(chan core.Event, h.buffer)}
+	h.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish delivers events to matching subscribers without blocking. A
+// subscriber whose buffer is full is dropped rather than holding up the
+// others; it resumes from the log after its last event.
+func (h *Hub) Publish(events ...core.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		for i := range events {
+			if !sub.filter.Matches(&events[i]) {
+				continue
+			}
+			select {
+			case sub.ch <- events[i]:
+			default:
+				h.remove(sub)
+			}
+			if _, ok := h.subs[sub]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones, so that open streams
+// finish when the server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
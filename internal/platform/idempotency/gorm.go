@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps idempotency keys in the database, shared by all replicas.
+type GormStore struct {
+	db          *gorm.DB
+	lockTimeout time.Duration
+}
+
+// NewGormStore returns a store in which keys left processing for longer than
+// lockTimeout, e.g. by a crashed replica, may be claimed again. Its table is
+// created by the database migrations.
+func NewGormStore(db *gorm.DB, lockTimeout time.Duration) *GormStore {
+	return &GormStore{db: db, lockTimeout: lockTimeout}
+}
+
+func (s *GormStore) Begin(ctx context.Context, scope, key, hash string, ttl time.Duration) (*Record, bool, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	// Expired and abandoned keys may be reused
+	if err := db.Where("scope = ? AND key = ?", scope, key).
+		Where("(expires_at <= ? OR (status = ? AND locked_until <= ?))", now, StatusProcessing, now).
+		Delete(&Record{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	rec := &Record{Scope: scope, Key: key, RequestHash: hash, Status: StatusProcessing,
+		ExpiresAt: now.Add(ttl), LockedUntil: now.Add(s.lockTimeout)}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return rec, true, nil
+	}
+
+	var existing Record
+	if err := db.Where("scope = ? AND key = ?", scope, key).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between our insert and read
+			return nil, false, ErrInProgress
+		}
+		return nil, false, err
+	}
+	return checkExisting(&existing, hash)
+}
+
+func checkExisting(rec *Record, hash string) (*Record, bool, error) {
+	if rec.RequestHash != hash {
+		return nil, false, ErrMismatch
+	}
+	if rec.Status != StatusCompleted {
+		return nil, false, ErrInProgress
+	}
+	return rec, false, nil
+}
+
+func (s *GormStore) Extend(ctx context.Context, rec *Record) error {
+	lockedUntil := time.Now().Add(s.lockTimeout)
+	err := s.db.WithContext(ctx).Model(&Record{}).Where("id = ? AND status = ?", rec.ID, StatusProcessing).
+		Update("locked_until", lockedUntil).Error
+	if err != nil {
+		return err
+	}
+	rec.LockedUntil = lockedUntil
+	return nil
+}
+
+func (s *GormStore) Complete(ctx context.Context, rec *Record, status int, contentType string, body []byte) error {
+	return s.db.WithContext(ctx).Model(rec).Updates(map[string]interface{}{
+		"status":          StatusCompleted,
+		"response_status": status,
+		"content_type":    contentType,
+		"response_body":   body,
+	}).Error
+}
+
+func (s *GormStore) Release(ctx context.Context, rec *Record) error {
+	return s.db.WithContext(ctx).Delete(rec).Error
+}
+
+func (s *GormStore) Prune(ctx context.Context) error {
+	return s.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&Record{}).Error
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+)
+
+var (
+	// ErrInProgress means another request with the key is still running.
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrMismatch means the key was first used with a different request.
+	ErrMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+// Record is a claimed idempotency key and, once completed, the response of
+// the request that claimed it.
+type Record struct {
+	ID             uint   `gorm:"primaryKey"`
+	Scope          string `gorm:"not null;uniqueIndex:idx_idempotency_key_scope_key"`
+	Key            string `gorm:"not null;uniqueIndex:idx_idempotency_key_scope_key"`
+	RequestHash    string `gorm:"not null"`
+	Status         string `gorm:"not null"`
+	ResponseStatus int
+	ContentType    string
+	ResponseBody   []byte
+	ExpiresAt      time.Time `gorm:"not null;index"`
+	// LockedUntil is until when a processing key stays claimed; after it
+	// the key is taken for abandoned and may be claimed again.
+	LockedUntil time.Time `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+func (Record) TableName() string {
+	return "idempotency_key"
+}
+
+// Store persists idempotency keys. Keys are scoped so that different callers
+// cannot see each other's responses.
+type Store interface {
+	// Begin claims key for a request hashing to hash. It returns the stored
+	// record with claimed=false when the key was already used: completed
+	// records are to be replayed. It fails with ErrInProgress or ErrMismatch.
+	Begin(ctx context.Context, scope, key, hash string, ttl time.Duration) (rec *Record, claimed bool, err error)
+	// Extend keeps a claimed key locked for another lock timeout. The
+	// request holding the key extends it while it runs, so that it is not
+	// taken for abandoned and run a second time.
+	Extend(ctx context.Context, rec *Record) error
+	// Complete stores the response of a claimed key.
+	Complete(ctx context.Context, rec *Record, status int, contentType string, body []byte) error
+	// Release frees a claimed key so the request can be retried with it.
+	Release(ctx context.Context, rec *Record) error
+	// Prune deletes expired keys.
+	Prune(ctx context.Context) error
+}
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps idempotency keys in process memory.
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[string]*Record
+	nextID      uint
+	lockTimeout time.Duration
+}
+
+func NewMemoryStore(lockTimeout time.Duration) *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record), lockTimeout: lockTimeout}
+}
+
+func memoryKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+func (m *MemoryStore) Begin(_ context.Context, scope, key, hash string, ttl time.Duration) (*Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	existing, ok := m.records[memoryKey(scope, key)]
+	abandoned := ok && existing.Status == StatusProcessing && !existing.LockedUntil.After(now)
+	if ok && existing.ExpiresAt.After(now) && !abandoned {
+		copied := *existing
+		return checkExisting(&copied, hash)
+	}
+
+	m.nextID++
+	rec := &Record{ID: m.nextID, Scope: scope, Key: key, RequestHash: hash, Status: StatusProcessing,
+		ExpiresAt: now.Add(ttl), LockedUntil: now.Add(m.lockTimeout), CreatedAt: now}
+	m.records[memoryKey(scope, key)] = rec
+	copied := *rec
+	return &copied, true, nil
+}
+
+func (m *MemoryStore) Extend(_ context.Context, rec *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec.LockedUntil = time.Now().Add(m.lockTimeout)
+	if stored, ok := m.records[memoryKey(rec.Scope, rec.Key)]; ok && stored.ID == rec.ID && stored.Status == StatusProcessing {
+		stored.LockedUntil = rec.LockedUntil
+	}
+	return nil
+}
+
+func (m *MemoryStore) Complete(_ context.Context, rec *Record, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.records[memoryKey(rec.Scope, rec.Key)]; ok && stored.ID == rec.ID {
+		stored.Status = StatusCompleted
+		stored.ResponseStatus = status
+		stored.ContentType = contentType
+		stored.ResponseBody = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Release(_ context.Context, rec *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.records[memoryKey(rec.Scope, rec.Key)]; ok && stored.ID == rec.ID {
+		delete(m.records, memoryKey(rec.Scope, rec.Key))
+	}
+	return nil
+}
+
+func (m *MemoryStore) Prune(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for k, rec := range m.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(m.records, k)
+		}
+	}
+	return nil
+}
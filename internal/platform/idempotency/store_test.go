@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/db/dbtest"
+)
+
+const lockTimeout = 200 * time.Millisecond
+
+// forEachStore runs fn against the memory store and the database store on
+// SQLite and, when TEST_POSTGRES_DB is set, on Postgres.
+func forEachStore(t *testing.T, fn func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) { fn(t, NewMemoryStore(lockTimeout)) })
+	t.Run("sqlite", func(t *testing.T) { fn(t, NewGormStore(dbtest.SQLite(t), lockTimeout)) })
+	t.Run("postgres", func(t *testing.T) { fn(t, NewGormStore(dbtest.Postgres(t), lockTimeout)) })
+}
+
+func TestBeginReplaysCompletedKey(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		rec, claimed, err := s.Begin(ctx, "ip:1", "key", "hash", time.Hour)
+		if err != nil || !claimed {
+			t.Fatalf("Begin: claimed %v, err %v", claimed, err)
+		}
+		if _, _, err := s.Begin(ctx, "ip:1", "key", "hash", time.Hour); !errors.Is(err, ErrInProgress) {
+			t.Fatalf("Begin while running: %v, want ErrInProgress", err)
+		}
+		if _, _, err := s.Begin(ctx, "ip:1", "key", "other", time.Hour); !errors.Is(err, ErrMismatch) {
+			t.Fatalf("Begin with another request: %v, want ErrMismatch", err)
+		}
+		// Keys are scoped to their caller
+		if _, claimed, err := s.Begin(ctx, "ip:2", "key", "other", time.Hour); err != nil || !claimed {
+			t.Fatalf("Begin by another caller: claimed %v, err %v", claimed, err)
+		}
+
+		if err := s.Complete(ctx, rec, 201, "application/json", []byte(`{"id":1}`)); err != nil {
+			t.Fatal(err)
+		}
+		got, claimed, err := s.Begin(ctx, "ip:1", "key", "hash", time.Hour)
+		if err != nil || claimed || got.ResponseStatus != 201 || got.ContentType != "application/json" || string(got.ResponseBody) != `{"id":1}` {
+			t.Fatalf("Begin after completing: %+v, claimed %v, err %v", got, claimed, err)
+		}
+	})
+}
+
+func TestReleasedAndExpiredKeysAreClaimedAgain(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		rec, _, err := s.Begin(ctx, "ip:1", "released", "hash", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Release(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		if _, claimed, err := s.Begin(ctx, "ip:1", "released", "other", time.Hour); err != nil || !claimed {
+			t.Fatalf("Begin after releasing: claimed %v, err %v", claimed, err)
+		}
+
+		rec, _, err = s.Begin(ctx, "ip:1", "expiring", "hash", -time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Complete(ctx, rec, 200, "application/json", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, claimed, err := s.Begin(ctx, "ip:1", "expiring", "other", time.Hour); err != nil || !claimed {
+			t.Fatalf("Begin after expiry: claimed %v, err %v", claimed, err)
+		}
+	})
+}
+
+// A key is claimed again once its lock runs out, but not while the request
+// holding it keeps extending it, and the stale holder cannot complete it.
+func TestLockTimeout(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		held, _, err := s.Begin(ctx, "ip:1", "held", "hash", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		abandoned, _, err := s.Begin(ctx, "ip:1", "abandoned", "hash", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			time.Sleep(lockTimeout / 2)
+			if err := s.Extend(ctx, held); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, _, err := s.Begin(ctx, "ip:1", "held", "hash", time.Hour); !errors.Is(err, ErrInProgress) {
+			t.Fatalf("Begin of an extended key: %v, want ErrInProgress", err)
+		}
+		again, claimed, err := s.Begin(ctx, "ip:1", "abandoned", "hash", time.Hour)
+		if err != nil || !claimed {
+			t.Fatalf("Begin of an abandoned key: claimed %v, err %v", claimed, err)
+		}
+
+		if err := s.Complete(ctx, abandoned, 200, "application/json", []byte("stale")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Complete(ctx, again, 201, "application/json", []byte("fresh")); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := s.Begin(ctx, "ip:1", "abandoned", "hash", time.Hour)
+		if err != nil || string(got.ResponseBody) != "fresh" {
+			t.Fatalf("replayed %+v, err %v; want the new holder's response", got, err)
+		}
+	})
+}
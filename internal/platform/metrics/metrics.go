@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics exported on /metrics.
+var (
+	// OwnershipDrift is how many NFTs the last reconciliation found with an
+	// owner differing from the chain.
+	OwnershipDrift = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "marketplace_ownership_drift_nfts",
+		Help: "NFTs whose recorded owner differed from the chain in the last reconciliation.",
+	})
+	OwnershipDriftTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketplace_ownership_drift_total",
+		Help: "Ownership drift found by reconciliation, by kind (transferred, burned).",
+	}, []string{"kind"})
+	ReconciledNFTs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "marketplace_reconciliation_checked_nfts",
+		Help: "NFTs checked against the chain by the last reconciliation.",
+	})
+	ReconciliationCancelledListings = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "marketplace_reconciliation_cancelled_listings_total",
+		Help: "Active listings cancelled by reconciliation.",
+	})
+	ReconciliationFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "marketplace_reconciliation_failures_total",
+		Help: "Reconciliation runs that did not finish.",
+	})
+	ReconciliationLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "marketplace_reconciliation_last_success_timestamp_seconds",
+		Help: "When the last reconciliation finished without error.",
+	})
+	ListingsInvalidated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "marketplace_listings_invalidated_total",
+		Help: "Listings found no longer backed by the chain, by reason.",
+	}, []string{"reason"})
+	ListingCheckFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "marketplace_listing_check_failures_total",
+		Help: "Listing checks that could not read the chain.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		OwnershipDrift, OwnershipDriftTotal, ReconciledNFTs,
+		ReconciliationCancelledListings, ReconciliationFailures, ReconciliationLastSuccess,
+		ListingsInvalidated, ListingCheckFailures,
+	)
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
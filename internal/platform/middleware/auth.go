@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+const principalKey = "principal"
+
+// Authenticator resolves a bearer token to the principal it was issued to.
+type Authenticator interface {
+	Authenticate(token string) (*core.Principal, error)
+}
+
+// Authenticate resolves the request's bearer token or X-API-Key header and
+// stores the principal in the context. Requests without credentials pass
+// through anonymously; invalid credentials are rejected.
+func Authenticate(auth Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := credential(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		principal, err := auth.Authenticate(token)
+		if err != nil {
+			abort(c, err)
+			return
+		}
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// WithCredentials runs h only for requests presenting a bearer token or API
+// key, so a limit placed before Authenticate leaves anonymous requests alone.
+func WithCredentials(h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if credential(c) == "" {
+			c.Next()
+			return
+		}
+		h(c)
+	}
+}
+
+// RequireAuth rejects anonymous requests. It must run after Authenticate.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if PrincipalFrom(c) == nil {
+			abort(c, core.Unauthorized("missing_credentials", "missing bearer token or API key"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func credential(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return token
+}
+
+// PrincipalFrom returns the principal set by RequireAuth, or nil.
+func PrincipalFrom(c *gin.Context) *core.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(*core.Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// RequirePermission rejects principals whose role does not grant p. It must
+// run after RequireAuth.
+func RequirePermission(p core.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.Role.Can(p) {
+			abort(c, core.Forbidden("missing_permission", "insufficient permissions: requires "+string(p)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects API keys lacking scope; sessions pass. It must run
+// after RequireAuth.
+func RequireScope(scope core.APIScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.HasScope(scope) {
+			abort(c, core.Forbidden("missing_scope", "API key lacks scope "+string(scope)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects API keys, for routes that manage the account itself.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := PrincipalFrom(c)
+		if principal == nil || principal.APIKey != nil {
+			abort(c, core.Forbidden("session_required", "this route requires a signed-in session"))
+			return
+		}
+		c.Next()
+	}
+}
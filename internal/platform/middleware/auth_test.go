@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// tokens authenticates a fixed set of tokens.
+type tokens map[string]*core.Principal
+
+func (a tokens) Authenticate(token string) (*core.Principal, error) {
+	if p, ok := a[token]; ok {
+		return p, nil
+	}
+	return nil, core.Unauthorized("invalid_credentials", "invalid or expired credentials")
+}
+
+var testTokens = tokens{
+	"user":      {UserID: 1, SessionID: 1, Role: core.RoleUser},
+	"creator":   {UserID: 2, SessionID: 2, Role: core.RoleCreator},
+	"moderator": {UserID: 3, SessionID: 3, Role: core.RoleModerator},
+	"admin":     {UserID: 4, SessionID: 4, Role: core.RoleAdmin},
+}
+
+// authRequest is a request with token as bearer token, or none if empty.
+func authRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestAuthenticate(t *testing.T) {
+	var got *core.Principal
+	handlers := []gin.HandlerFunc{Authenticate(testTokens), func(c *gin.Context) {
+		got = PrincipalFrom(c)
+		ok(c)
+	}}
+
+	if w := serve(authRequest(""), handlers...); w.Code != http.StatusOK || got != nil {
+		t.Fatalf("anonymous request: status %d, principal %+v", w.Code, got)
+	}
+	if w := serve(authRequest("creator"), handlers...); w.Code != http.StatusOK || got == nil || got.UserID != 2 {
+		t.Fatalf("bearer token: status %d, principal %+v", w.Code, got)
+	}
+	expectProblem(t, serve(authRequest("forged"), handlers...), http.StatusUnauthorized, "invalid_credentials")
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		token  string
+		perm   core.Permission
+		status int
+		code   string
+	}{
+		{"", core.PermViewAuditLog, http.StatusUnauthorized, "missing_credentials"},
+		{"user", core.PermCreateCollection, http.StatusForbidden, "missing_permission"},
+		{"creator", core.PermMint, http.StatusOK, ""},
+		{"creator", core.PermViewAuditLog, http.StatusForbidden, "missing_permission"},
+		{"moderator", core.PermModerateListings, http.StatusOK, ""},
+		{"moderator", core.PermManageUsers, http.StatusForbidden, "missing_permission"},
+		{"admin", core.PermManageUsers, http.StatusOK, ""},
+		{"admin", core.PermRunReconcile, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		name := tt.token + " " + string(tt.perm)
+		if tt.token == "" {
+			name = "anonymous " + string(tt.perm)
+		}
+		t.Run(name, func(t *testing.T) {
+			w := serve(authRequest(tt.token), Authenticate(testTokens), RequireAuth(), RequirePermission(tt.perm), ok)
+			if tt.code != "" {
+				expectProblem(t, w, tt.status, tt.code)
+			} else if w.Code != tt.status {
+				t.Fatalf("status %d, want %d; body %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	auth := tokens{
+		"session": {UserID: 1, SessionID: 1, Role: core.RoleUser},
+		"nftm_read": {UserID: 1, Role: core.RoleUser,
+			APIKey: &core.APIKey{ID: 1, Scopes: []core.APIScope{core.ScopeRead}}},
+	}
+	apiKey := func() *http.Request {
+		req := authRequest("")
+		req.Header.Set("X-API-Key", "nftm_read")
+		return req
+	}
+
+	if w := serve(apiKey(), Authenticate(auth), RequireAuth(), RequireScope(core.ScopeRead), ok); w.Code != http.StatusOK {
+		t.Fatalf("key with the route's scope: status %d", w.Code)
+	}
+	expectProblem(t, serve(apiKey(), Authenticate(auth), RequireAuth(), RequireScope(core.ScopeTrade), ok),
+		http.StatusForbidden, "missing_scope")
+	if w := serve(authRequest("session"), Authenticate(auth), RequireAuth(), RequireScope(core.ScopeTrade), ok); w.Code != http.StatusOK {
+		t.Fatalf("session on a scoped route: status %d", w.Code)
+	}
+	expectProblem(t, serve(apiKey(), Authenticate(auth), RequireAuth(), RequireSession(), ok),
+		http.StatusForbidden, "session_required")
+}
@@ -1,21 +1,97 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/core"
 )
 
-func CORS() gin.HandlerFunc {
+// CORS answers preflight requests and echoes the request's Origin when it is
+// allow-listed. Origins that are not allowed get no CORS headers, so the
+// browser blocks the response.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	anyOrigin := allowsAnyOrigin(cfg.AllowedOrigins)
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		origin := c.GetHeader("Origin")
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		allowed := origin != "" && (anyOrigin || originAllowed(origin, cfg.AllowedOrigins))
+		if allowed {
+			// A wildcard must never be combined with credentials
+			if anyOrigin && !cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if exposed != "" {
+				h.Set("Access-Control-Expose-Headers", exposed)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			if allowed {
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// RequireOrigin rejects cross-origin requests from origins not allowed by
+// CORS. Browsers don't apply CORS to WebSocket handshakes, so routes that
+// upgrade must check the origin themselves.
+func RequireOrigin(cfg config.CORSConfig) gin.HandlerFunc {
+	anyOrigin := allowsAnyOrigin(cfg.AllowedOrigins)
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin != "" && !anyOrigin && !originAllowed(origin, cfg.AllowedOrigins) {
+			abort(c, core.Forbidden("origin_not_allowed", "origin "+origin+" is not allowed"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func allowsAnyOrigin(allowed []string) bool {
+	for _, o := range allowed {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// originAllowed matches origin against exact entries and wildcard subdomain
+// entries such as https://*.example.com.
+func originAllowed(origin string, allowed []string) bool {
+	origin = strings.ToLower(origin)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(a, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
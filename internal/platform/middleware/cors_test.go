@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/config"
+)
+
+func corsConfig(origins ...string) config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"Retry-After"},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+func preflight(origin string) *http.Request {
+	req := httptest.NewRequest(http.MethodOptions, "/v1/listings", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	return req
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cfg := corsConfig("https://app.example.com", "https://*.example.org")
+
+	for _, origin := range []string{"https://app.example.com", "https://shop.example.org"} {
+		w := serve(preflight(origin), CORS(cfg), ok)
+		h := w.Header()
+		if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Origin") != origin ||
+			h.Get("Access-Control-Allow-Methods") != "GET, POST" ||
+			h.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" ||
+			h.Get("Access-Control-Max-Age") != "600" {
+			t.Fatalf("preflight from %s: status %d, headers %v", origin, w.Code, h)
+		}
+	}
+
+	// The preflight is answered, but without allowing the origin
+	for _, origin := range []string{"https://evil.example", "https://example.org.evil.example", "http://app.example.com"} {
+		w := serve(preflight(origin), CORS(cfg), ok)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("preflight from %s: status %d", origin, w.Code)
+		}
+		for _, name := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Credentials"} {
+			if v := w.Header().Get(name); v != "" {
+				t.Fatalf("preflight from %s: %s %q", origin, name, v)
+			}
+		}
+	}
+}
+
+func TestCORSRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/listings", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := serve(req, CORS(corsConfig("https://app.example.com")), ok)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "Retry-After" || w.Header().Get("Vary") != "Origin" {
+		t.Fatalf("status %d, headers %v", w.Code, w.Header())
+	}
+}
+
+// A wildcard allows any origin, but credentials are never sent to "*".
+func TestCORSWildcard(t *testing.T) {
+	cfg := corsConfig("*")
+	w := serve(preflight("https://anywhere.example"), CORS(cfg), ok)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("wildcard without credentials: headers %v", w.Header())
+	}
+
+	cfg.AllowCredentials = true
+	w = serve(preflight("https://anywhere.example"), CORS(cfg), ok)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://anywhere.example" ||
+		w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("wildcard with credentials: headers %v", w.Header())
+	}
+}
+
+func TestRequireOrigin(t *testing.T) {
+	cfg := corsConfig("https://app.example.com")
+	req := httptest.NewRequest(http.MethodGet, "/v1/events/ws", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	expectProblem(t, serve(req, RequireOrigin(cfg), ok), http.StatusForbidden, "origin_not_allowed")
+
+	req.Header.Set("Origin", "https://app.example.com")
+	if w := serve(req, RequireOrigin(cfg), ok); w.Code != http.StatusOK {
+		t.Fatalf("allowed origin: status %d", w.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// Problem is an RFC 7807 problem details response. Code is the stable
+// identifier of the core error and Errors lists invalid request fields.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Code     string            `json:"code"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   []core.FieldError `json:"errors,omitempty"`
+	// RetryAt is when the request may succeed, also sent as Retry-After.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// Errors renders the last error a handler attached with c.Error as
+// application/problem+json. Errors other than *core.Error are reported as
+// internal errors so their text never reaches the client.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		renderError(c)
+	}
+}
+
+// abort stops the chain with err, to be rendered by Errors.
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// renderError writes the response for c's last error unless one was already
+// written. Middleware that inspects the response, like Idempotency, calls it
+// before doing so.
+func renderError(c *gin.Context) {
+	last := c.Errors.Last()
+	if last == nil || c.Writer.Written() {
+		return
+	}
+
+	var e *core.Error
+	if !errors.As(last.Err, &e) {
+		e = core.Internal(last.Err)
+	}
+	status := e.Kind.Status()
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, last.Err)
+	}
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Message,
+		Code:     e.Code,
+		Instance: c.Request.URL.Path,
+		Errors:   e.Fields,
+	}
+	if !e.RetryAt.IsZero() {
+		problem.RetryAt = &e.RetryAt
+		c.Header("Retry-After", ceilSeconds(max(time.Until(e.RetryAt), 0)))
+	}
+
+	c.Header("Content-Type", "application/problem+json")
+	c.JSON(status, problem)
+}
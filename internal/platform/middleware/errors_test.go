@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func failWith(err error) gin.HandlerFunc {
+	return func(c *gin.Context) { _ = c.Error(err) }
+}
+
+func TestErrorsRendersProblem(t *testing.T) {
+	invalid := core.Validation("invalid_request", "invalid request")
+	invalid.Fields = []core.FieldError{{Field: "price", Message: "must be positive"}}
+	req := httptest.NewRequest(http.MethodPost, "/v1/listings", nil)
+
+	p := problem(t, serve(req, failWith(invalid)))
+	if p.Type != "about:blank" || p.Title != "Bad Request" || p.Status != http.StatusBadRequest ||
+		p.Code != "invalid_request" || p.Detail != "invalid request" || p.Instance != "/v1/listings" ||
+		len(p.Errors) != 1 || p.Errors[0].Field != "price" {
+		t.Fatalf("problem %+v", p)
+	}
+	if p.RetryAt != nil {
+		t.Fatalf("retry_at %v on an error without one", p.RetryAt)
+	}
+}
+
+func TestErrorsRetryAfter(t *testing.T) {
+	until := time.Now().Add(90 * time.Second)
+	w := serve(httptest.NewRequest(http.MethodPost, "/v1/orders", nil),
+		failWith(core.Conflict("listing_reserved", "listing is reserved").RetryingAt(until)))
+	expectProblem(t, w, http.StatusConflict, "listing_reserved")
+	if got := w.Header().Get("Retry-After"); got != "90" && got != "89" {
+		t.Fatalf("Retry-After %q, want 90", got)
+	}
+	if p := problem(t, w); p.RetryAt == nil || !p.RetryAt.Equal(until) {
+		t.Fatalf("retry_at %v, want %v", p.RetryAt, until)
+	}
+}
+
+// Errors that are not core errors may hold internal details and are
+// reported without their text.
+func TestErrorsHidesInternalErrors(t *testing.T) {
+	w := serve(httptest.NewRequest(http.MethodGet, "/", nil), failWith(errors.New("dial tcp 10.0.0.5:5432: refused")))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.5") {
+		t.Fatalf("internal error text in the response: %s", w.Body)
+	}
+	problem(t, w)
+}
+
+// A response the handler already wrote is left alone.
+func TestErrorsAfterResponse(t *testing.T) {
+	w := serve(httptest.NewRequest(http.MethodGet, "/", nil), func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{})
+		_ = c.Error(core.Conflict("late", "too late"))
+	})
+	if w.Code != http.StatusAccepted || w.Body.String() != "{}" {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+}
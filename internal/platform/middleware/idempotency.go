@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/idempotency"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// bodyRecorder tees the response body so it can be stored.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry. The first request with a key runs and its response is stored with a
+// hash of the request; later requests with the key get that response
+// replayed, or 422 if their request differs. A duplicate arriving while the
+// first is still running waits up to wait for it, then gets 409. Keys are
+// scoped to the caller, so it must run after Authenticate.
+func Idempotency(store idempotency.Store, ttl, wait time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abort(c, core.Validation("invalid_idempotency_key", "Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abort(c, core.TooLarge("request body too large"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.RequestURI()+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+
+		rec, claimed, err := begin(c.Request.Context(), store, callerKey(c), key, hash, ttl, wait)
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			abort(c, core.Unprocessable("idempotency_key_reused", err.Error()))
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.Header("Retry-After", "1")
+			abort(c, core.Conflict("idempotency_key_in_progress", err.Error()))
+			return
+		case err != nil:
+			abort(c, core.Unavailable("idempotency_unavailable", "idempotency store unavailable").Wrap(err))
+			return
+		}
+
+		if !claimed {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(rec.ResponseStatus, rec.ContentType, rec.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// The client may have gone away; the outcome must still be recorded.
+		ctx := context.Background()
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		unlock := keepLocked(store, rec)
+		defer func() {
+			if p := recover(); p != nil {
+				unlock()
+				_ = store.Release(ctx, rec)
+				panic(p)
+			}
+		}()
+
+		c.Next()
+		// Render a pending error now so that it is what gets recorded.
+		renderError(c)
+		unlock()
+
+		status := c.Writer.Status()
+		if storable(status) {
+			err = store.Complete(ctx, rec, status, c.Writer.Header().Get("Content-Type"), recorder.body.Bytes())
+		} else {
+			err = store.Release(ctx, rec)
+		}
+		if err != nil {
+			log.Printf("Failed to record idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+func begin(ctx context.Context, store idempotency.Store, scope, key, hash string, ttl, wait time.Duration) (*idempotency.Record, bool, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		rec, claimed, err := store.Begin(ctx, scope, key, hash, ttl)
+		if !errors.Is(err, idempotency.ErrInProgress) || time.Now().After(deadline) {
+			return rec, claimed, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// keepLocked extends the lock of a just claimed key every half lock timeout
+// until the returned function is called, so that a request running longer
+// than the lock timeout is not taken for abandoned and run again by a retry.
+func keepLocked(store idempotency.Store, rec *idempotency.Record) (unlock func()) {
+	interval := time.Until(rec.LockedUntil) / 2
+	if interval <= 0 {
+		return func() {}
+	}
+	ticker := time.NewTicker(interval)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if err := store.Extend(context.Background(), rec); err != nil {
+				log.Printf("Failed to extend idempotency key %q: %v", rec.Key, err)
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(stop)
+		<-stopped
+	}
+}
+
+// storable reports whether a response is final. Server errors and requests
+// turned away before running (authentication, permissions, rate limits)
+// release the key so the request can be retried with it.
+func storable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/platform/idempotency"
+)
+
+func idempotentRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// counting answers 201 with how many times it ran, after delay.
+func counting(runs *int32, delay time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n := atomic.AddInt32(runs, 1)
+		time.Sleep(delay)
+		c.JSON(http.StatusCreated, gin.H{"run": n})
+	}
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	var runs int32
+	mw := Idempotency(idempotency.NewMemoryStore(time.Minute), time.Hour, 0)
+
+	first := serve(idempotentRequest("k1", `{"listing_id":1}`), mw, counting(&runs, 0))
+	again := serve(idempotentRequest("k1", `{"listing_id":1}`), mw, counting(&runs, 0))
+	if first.Code != http.StatusCreated || again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+		t.Fatalf("responses %d %s and %d %s, want the first replayed", first.Code, first.Body, again.Code, again.Body)
+	}
+	if again.Header().Get("Idempotent-Replayed") != "true" || runs != 1 {
+		t.Fatalf("replayed header %q after %d runs", again.Header().Get("Idempotent-Replayed"), runs)
+	}
+
+	expectProblem(t, serve(idempotentRequest("k1", `{"listing_id":2}`), mw, counting(&runs, 0)),
+		http.StatusUnprocessableEntity, "idempotency_key_reused")
+	if runs != 1 {
+		t.Fatalf("handler ran %d times", runs)
+	}
+}
+
+// Server errors release the key so the request can be retried with it.
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	var runs int32
+	mw := Idempotency(idempotency.NewMemoryStore(time.Minute), time.Hour, 0)
+	failing := func(c *gin.Context) {
+		atomic.AddInt32(&runs, 1)
+		c.Status(http.StatusServiceUnavailable)
+	}
+	serve(idempotentRequest("k1", `{}`), mw, failing)
+	if w := serve(idempotentRequest("k1", `{}`), mw, counting(&runs, 0)); w.Code != http.StatusCreated || runs != 2 {
+		t.Fatalf("retry after a server error: status %d after %d runs", w.Code, runs)
+	}
+}
+
+// A retry arriving while the request runs is refused, even once the request
+// has run for longer than the lock timeout.
+func TestIdempotencyInProgress(t *testing.T) {
+	const lockTimeout = 100 * time.Millisecond
+	var runs int32
+	mw := Idempotency(idempotency.NewMemoryStore(lockTimeout), time.Hour, 0)
+
+	start := time.Now()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- serve(idempotentRequest("k1", `{}`), mw, counting(&runs, 5*lockTimeout)) }()
+
+	for _, at := range []time.Duration{lockTimeout / 2, 3 * lockTimeout} {
+		time.Sleep(time.Until(start.Add(at)))
+		w := serve(idempotentRequest("k1", `{}`), mw, counting(&runs, 0))
+		expectProblem(t, w, http.StatusConflict, "idempotency_key_in_progress")
+		if w.Header().Get("Retry-After") == "" {
+			t.Fatal("no Retry-After on a request in progress")
+		}
+	}
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d", w.Code)
+	}
+	if runs != 1 {
+		t.Fatalf("handler ran %d times", runs)
+	}
+}
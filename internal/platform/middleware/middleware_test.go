@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve runs req through handlers on a router that renders errors as the
+// API does.
+func serve(req *http.Request, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(Errors())
+	router.Any("/*path", handlers...)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// ok answers 200 with an empty JSON object.
+func ok(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// problem decodes a problem+json response.
+func problem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type %q, want application/problem+json; body %s", ct, w.Body)
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode problem %s: %v", w.Body, err)
+	}
+	return p
+}
+
+// expectProblem fails unless w is a problem with the given status and code.
+func expectProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d; body %s", w.Code, status, w.Body)
+	}
+	if p := problem(t, w); p.Status != status || p.Code != code {
+		t.Fatalf("problem %+v, want status %d and code %s", p, status, code)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/ratelimit"
+)
+
+// RateLimit returns a middleware factory limiting each route class with its
+// configured limit. Buckets are keyed by API key, else by signed-in user,
+// else by client IP, so it must run after Authenticate, except for
+// ratelimit.ClassAuth, which is always keyed by client IP. Classes without a
+// limit, or a nil limiter, are not limited.
+func RateLimit(limiter ratelimit.Limiter, limits map[ratelimit.Class]ratelimit.Limit) func(ratelimit.Class) gin.HandlerFunc {
+	return func(class ratelimit.Class) gin.HandlerFunc {
+		limit, ok := limits[class]
+		if limiter == nil || !ok {
+			return func(c *gin.Context) { c.Next() }
+		}
+		policy := fmt.Sprintf("%d;w=%d", limit.Burst, int(limit.Window.Seconds()))
+
+		return func(c *gin.Context) {
+			key := callerKey(c)
+			if class == ratelimit.ClassAuth {
+				key = "ip:" + c.ClientIP()
+			}
+			res, err := limiter.Allow(c.Request.Context(), string(class)+":"+key, limit)
+			if err != nil {
+				// Fail open: an unavailable limiter store must not take the API down
+				log.Printf("Rate limiter error: %v", err)
+				c.Next()
+				return
+			}
+
+			h := c.Writer.Header()
+			h.Set("RateLimit-Policy", policy)
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.ResetAfter))
+			if !res.Allowed {
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				abort(c, core.RateLimited("rate limit exceeded"))
+				return
+			}
+			c.Next()
+		}
+	}
+}
+
+// callerKey identifies the caller by API key, else signed-in user, else
+// client IP.
+func callerKey(c *gin.Context) string {
+	if p := PrincipalFrom(c); p != nil {
+		if p.APIKey != nil {
+			return "key:" + strconv.FormatUint(uint64(p.APIKey.ID), 10)
+		}
+		return "user:" + strconv.FormatUint(uint64(p.UserID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
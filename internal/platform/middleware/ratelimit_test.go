@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/platform/ratelimit"
+)
+
+// Requests from one peer share a bucket whatever X-Forwarded-For they send,
+// unless the peer is a trusted proxy.
+func TestRateLimitKeyIgnoresSpoofedForwardedFor(t *testing.T) {
+	once := map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRead: {Rate: 1.0 / 60, Burst: 1, Window: time.Minute},
+	}
+	statuses := func(trusted []string) []int {
+		router := gin.New()
+		if err := router.SetTrustedProxies(trusted); err != nil {
+			t.Fatal(err)
+		}
+		router.Use(Errors())
+		router.GET("/", RateLimit(ratelimit.NewMemoryLimiter(), once)(ratelimit.ClassRead), ok)
+
+		var codes []int
+		for _, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:4000"
+			req.Header.Set("X-Forwarded-For", forwarded)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes = append(codes, w.Code)
+		}
+		return codes
+	}
+
+	// By default no proxy is trusted
+	t.Setenv("TRUSTED_PROXIES", "")
+	if got := statuses(config.LoadRateLimitConfig().TrustedProxies); got[0] != http.StatusOK || got[1] != http.StatusTooManyRequests {
+		t.Fatalf("statuses %v from one peer claiming two clients, want 200 then 429", got)
+	}
+	if got := statuses([]string{"192.0.2.0/24"}); got[0] != http.StatusOK || got[1] != http.StatusOK {
+		t.Fatalf("statuses %v for two clients behind a trusted proxy, want 200 twice", got)
+	}
+}
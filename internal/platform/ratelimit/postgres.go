@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rateLimitBucket is the shared state of one bucket.
+type rateLimitBucket struct {
+	Key       string    `gorm:"primaryKey"`
+	Tokens    float64   `gorm:"not null"`
+	Allowed   bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;index"`
+}
+
+func (rateLimitBucket) TableName() string {
+	return "rate_limit_bucket"
+}
+
+// PostgresLimiter keeps buckets in a table, created by the database
+// migrations, so limits hold across replicas. Each request is a single
+// atomic upsert.
+type PostgresLimiter struct {
+	db *gorm.DB
+}
+
+func NewPostgresLimiter(db *gorm.DB) (*PostgresLimiter, error) {
+	if name := db.Dialector.Name(); name != "postgres" {
+		return nil, fmt.Errorf("the postgres rate limiter cannot run on %s", name)
+	}
+	return &PostgresLimiter{db: db}, nil
+}
+
+// refilledSQL is the bucket's token count after refilling since its last use.
+const refilledSQL = `LEAST(CAST(@burst AS DOUBLE PRECISION), b.tokens + CAST(EXTRACT(EPOCH FROM now() - b.updated_at) AS DOUBLE PRECISION) * CAST(@rate AS DOUBLE PRECISION))`
+
+const takeTokenSQL = `
+INSERT INTO rate_limit_bucket AS b (key, tokens, allowed, updated_at)
+VALUES (@key, CAST(@burst AS DOUBLE PRECISION) - 1, true, now())
+ON CONFLICT (key) DO UPDATE SET
+	allowed = ` + refilledSQL + ` >= 1,
+	tokens = ` + refilledSQL + ` - CASE WHEN ` + refilledSQL + ` >= 1 THEN 1 ELSE 0 END,
+	updated_at = now()
+RETURNING tokens, allowed`
+
+func (p *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := p.db.WithContext(ctx).Raw(takeTokenSQL, map[string]interface{}{
+		"key":   key,
+		"burst": float64(limit.Burst),
+		"rate":  limit.Rate,
+	}).Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+	return result(row.Allowed, row.Tokens, limit), nil
+}
+
+// Prune deletes buckets idle for longer than maxIdle.
+func (p *PostgresLimiter) Prune(maxIdle time.Duration) error {
+	return p.db.Where("updated_at < ?", time.Now().Add(-maxIdle)).Delete(&rateLimitBucket{}).Error
+}
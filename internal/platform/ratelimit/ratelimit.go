@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Class groups routes that share a limit.
+type Class string
+
+const (
+	ClassRead  Class = "read"
+	ClassWrite Class = "write"
+	ClassMint  Class = "mint"
+	// ClassChain covers routes that send blockchain transactions.
+	ClassChain Class = "chain"
+	// ClassAuth covers requests presenting credentials, keyed by client IP
+	// before the credentials are checked.
+	ClassAuth Class = "auth"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst.
+type Limit struct {
+	Rate   float64
+	Burst  int
+	Window time.Duration
+}
+
+// ParseLimit reads limits such as "60/m", "10/s" or "1000/h"; the count is
+// both the bucket size and the number of tokens refilled per window.
+func ParseLimit(s string) (Limit, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected e.g. 60/m", s)
+	}
+	var window time.Duration
+	switch unit {
+	case "s":
+		window = time.Second
+	case "m":
+		window = time.Minute
+	case "h":
+		window = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit unit in %q, expected s, m or h", s)
+	}
+	return Limit{Rate: float64(n) / window.Seconds(), Burst: n, Window: window}, nil
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is when the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is when the next token is available, if denied.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket of key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill returns the tokens of a bucket last left at tokens, elapsed ago.
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+}
+
+func result(allowed bool, tokens float64, limit Limit) Result {
+	r := Result{
+		Allowed:    allowed,
+		Limit:      limit.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,152 @@
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const headerSuffix = " wants you to sign in with your Ethereum account:"
+
+var (
+	ErrMalformed        = errors.New("malformed SIWE message")
+	ErrInvalidSignature = errors.New("invalid SIWE signature")
+)
+
+// Message is a parsed EIP-4361 Sign-In With Ethereum message.
+type Message struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// Parse reads a message in the EIP-4361 text format.
+func Parse(raw string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], headerSuffix) {
+		return nil, fmt.Errorf("%w: missing header", ErrMalformed)
+	}
+
+	m := &Message{Domain: strings.TrimSuffix(lines[0], headerSuffix)}
+	if i := strings.Index(m.Domain, "://"); i >= 0 {
+		m.Domain = m.Domain[i+3:]
+	}
+	if !common.IsHexAddress(lines[1]) {
+		return nil, fmt.Errorf("%w: invalid address", ErrMalformed)
+	}
+	m.Address = common.HexToAddress(lines[1])
+
+	// An optional statement sits between the address and the URI field.
+	i := 2
+	var statement []string
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "URI: "); i++ {
+		if lines[i] != "" {
+			statement = append(statement, lines[i])
+		}
+	}
+	m.Statement = strings.Join(statement, "\n")
+
+	fields := make(map[string]string)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if line == "Resources:" {
+			for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+				m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+			break
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected line %q", ErrMalformed, line)
+		}
+		fields[key] = value
+	}
+
+	m.URI = fields["URI"]
+	m.Version = fields["Version"]
+	m.Nonce = fields["Nonce"]
+	m.RequestID = fields["Request ID"]
+	if m.URI == "" || m.Version != "1" || len(m.Nonce) < 8 {
+		return nil, fmt.Errorf("%w: URI, Version 1 and a Nonce of at least 8 characters are required", ErrMalformed)
+	}
+
+	chainID, err := strconv.ParseInt(fields["Chain ID"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid Chain ID", ErrMalformed)
+	}
+	m.ChainID = chainID
+
+	if m.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid Issued At", ErrMalformed)
+	}
+	if v, ok := fields["Expiration Time"]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid Expiration Time", ErrMalformed)
+		}
+		m.ExpirationTime = &t
+	}
+	if v, ok := fields["Not Before"]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid Not Before", ErrMalformed)
+		}
+		m.NotBefore = &t
+	}
+	return m, nil
+}
+
+// ValidAt checks the message's validity window at now.
+func (m *Message) ValidAt(now time.Time) error {
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return errors.New("SIWE message has expired")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return errors.New("SIWE message is not yet valid")
+	}
+	if m.IssuedAt.After(now.Add(5 * time.Minute)) {
+		return errors.New("SIWE message is issued in the future")
+	}
+	return nil
+}
+
+// VerifySignature checks that signature is an EIP-191 personal_sign of raw by
+// the address stated in the message.
+func VerifySignature(raw string, address common.Address, signature string) error {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("%w: expected a 65-byte hex signature", ErrInvalidSignature)
+	}
+	// Wallets produce V as 27/28; recovery expects 0/1.
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(raw)), sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if crypto.PubkeyToAddress(*pub) != address {
+		return fmt.Errorf("%w: signer does not match message address", ErrInvalidSignature)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package siwe
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const sample = `example.com wants you to sign in with your Ethereum account:
+0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266
+
+Sign in to the marketplace.
+
+URI: https://example.com/login
+Version: 1
+Chain ID: 1
+Nonce: k3j9x0aPq2
+Issued At: 2024-01-01T00:00:00Z
+Expiration Time: 2024-01-01T01:00:00Z
+Not Before: 2024-01-01T00:00:00Z
+Request ID: r-1
+Resources:
+- https://example.com/terms`
+
+func TestParse(t *testing.T) {
+	m, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Domain != "example.com" || m.Address.Hex() != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" ||
+		m.Statement != "Sign in to the marketplace." || m.URI != "https://example.com/login" || m.ChainID != 1 ||
+		m.Nonce != "k3j9x0aPq2" || m.RequestID != "r-1" || len(m.Resources) != 1 || m.Resources[0] != "https://example.com/terms" {
+		t.Fatalf("parsed %+v", m)
+	}
+	if !m.IssuedAt.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || m.ExpirationTime == nil || m.NotBefore == nil {
+		t.Fatalf("times %v, %v, %v", m.IssuedAt, m.ExpirationTime, m.NotBefore)
+	}
+
+	if m, err := Parse(strings.ReplaceAll(sample, "\n", "\r\n")); err != nil || m.Nonce != "k3j9x0aPq2" {
+		t.Fatalf("CRLF message: %+v, %v", m, err)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := map[string]string{
+		"no header":          strings.Replace(sample, " wants you to sign in", "", 1),
+		"invalid address":    strings.Replace(sample, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", "0xf39F", 1),
+		"no URI":             strings.Replace(sample, "URI: https://example.com/login\n", "", 1),
+		"version 2":          strings.Replace(sample, "Version: 1", "Version: 2", 1),
+		"short nonce":        strings.Replace(sample, "k3j9x0aPq2", "k3j9", 1),
+		"invalid chain id":   strings.Replace(sample, "Chain ID: 1", "Chain ID: one", 1),
+		"invalid issued at":  strings.Replace(sample, "Issued At: 2024-01-01T00:00:00Z", "Issued At: yesterday", 1),
+		"invalid expiry":     strings.Replace(sample, "Expiration Time: 2024-01-01T01:00:00Z", "Expiration Time: soon", 1),
+		"invalid not before": strings.Replace(sample, "Not Before: 2024-01-01T00:00:00Z", "Not Before: 0", 1),
+		"line without colon": strings.Replace(sample, "Version: 1", "Version 1", 1),
+	}
+	for name, raw := range tests {
+		if _, err := Parse(raw); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: err = %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestValidAt(t *testing.T) {
+	m, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := m.IssuedAt
+	for _, tt := range []struct {
+		at    time.Time
+		valid bool
+	}{
+		{issued, true},
+		{issued.Add(59 * time.Minute), true},
+		{issued.Add(time.Hour), false},
+		{issued.Add(-time.Second), false},
+	} {
+		if err := m.ValidAt(tt.at); (err == nil) != tt.valid {
+			t.Errorf("ValidAt(%v) = %v, want valid %v", tt.at, err, tt.valid)
+		}
+	}
+
+	m.ExpirationTime, m.NotBefore = nil, nil
+	if err := m.ValidAt(issued.Add(-10 * time.Minute)); err == nil {
+		t.Fatal("message issued 10 minutes in the future is valid")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	sig, err := crypto.Sign(accounts.TextHash([]byte(sample)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recovery ids are accepted as 0/1 and as wallets send them, 27/28
+	if err := VerifySignature(sample, address, hexutil.Encode(sig)); err != nil {
+		t.Fatal(err)
+	}
+	wallet := append([]byte(nil), sig...)
+	wallet[crypto.RecoveryIDOffset] += 27
+	if err := VerifySignature(sample, address, hexutil.Encode(wallet)); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := crypto.GenerateKey()
+	for name, err := range map[string]error{
+		"another signer":  VerifySignature(sample, crypto.PubkeyToAddress(other.PublicKey), hexutil.Encode(sig)),
+		"another message": VerifySignature(sample+"\n", address, hexutil.Encode(sig)),
+		"short signature": VerifySignature(sample, address, hexutil.Encode(sig[:64])),
+		"not hex":         VerifySignature(sample, address, "signature"),
+	} {
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+}
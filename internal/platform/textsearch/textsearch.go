@@ -0,0 +1,76 @@
+// Package textsearch matches free text the way the Postgres search does, for
+// the stores without it: query words match as prefixes of a document's
+// words, and WordSimilarity stands in for pg_trgm's word_similarity, so
+// typos are tolerated alike.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits free text into lower-case words of letters and digits.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MatchesPrefixes tells whether every word is a prefix of a word of doc.
+func MatchesPrefixes(words []string, doc string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	docWords := Words(doc)
+	for _, w := range words {
+		found := false
+		for _, d := range docWords {
+			if strings.HasPrefix(d, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// trigrams are the trigrams of text's words, padded as pg_trgm pads them.
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range Words(text) {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// WordSimilarity is the share of text's trigrams found in the run of
+// target's words that has the most of them.
+func WordSimilarity(text, target string) float64 {
+	want := trigrams(text)
+	if len(want) == 0 {
+		return 0
+	}
+	words := Words(target)
+	var best float64
+	for i := range words {
+		for j := i + 1; j <= len(words); j++ {
+			have := trigrams(strings.Join(words[i:j], " "))
+			var common int
+			for t := range want {
+				if have[t] {
+					common++
+				}
+			}
+			if s := float64(common) / float64(len(want)); s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
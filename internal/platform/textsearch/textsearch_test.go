@@ -0,0 +1,45 @@
+package textsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	if got := Words("Bored Ape #42, Café-Club!"); !reflect.DeepEqual(got, []string{"bored", "ape", "42", "café", "club"}) {
+		t.Fatalf("Words = %q", got)
+	}
+}
+
+func TestMatchesPrefixes(t *testing.T) {
+	doc := "Golden Ape Yacht Club"
+	for words, want := range map[string]bool{
+		"gold":      true,
+		"ape gold":  true,
+		"apes":      false,
+		"old":       false,
+		"gold cats": false,
+	} {
+		if got := MatchesPrefixes(Words(words), doc); got != want {
+			t.Errorf("MatchesPrefixes(%q) = %v, want %v", words, got, want)
+		}
+	}
+	if MatchesPrefixes(nil, doc) {
+		t.Error("no words match")
+	}
+}
+
+func TestWordSimilarity(t *testing.T) {
+	if s := WordSimilarity("golden", "The Golden Ape"); s != 1 {
+		t.Errorf("similarity of a contained word = %v, want 1", s)
+	}
+	if s := WordSimilarity("goldne", "The Golden Ape"); s <= 0.3 {
+		t.Errorf("similarity of a typo = %v, want above the fuzzy threshold 0.3", s)
+	}
+	if s := WordSimilarity("zebra", "The Golden Ape"); s > 0.3 {
+		t.Errorf("similarity of an unrelated word = %v", s)
+	}
+	if s := WordSimilarity("", "The Golden Ape"); s != 0 {
+		t.Errorf("similarity of no text = %v", s)
+	}
+}
@@ -0,0 +1,200 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+var (
+	addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	txHashPattern  = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	digitsPattern  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// messages explains each failed validation tag to clients.
+var messages = map[string]string{
+	"required":    "is required",
+	"eth_address": "must be a 0x-prefixed 20-byte hex address with a valid EIP-55 checksum",
+	"tx_hash":     "must be a 0x-prefixed 32-byte hex transaction hash",
+	"uint256":     "must be a non-negative integer that fits in 256 bits",
+	"id":          "must be a positive integer id",
+	"number":      "must be a number",
+}
+
+// Register adds the eth_address, tx_hash, uint256 and id tags to Gin's
+// validator and reports fields by their json, form or uri name. It must run
+// before the first request is bound.
+func Register() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin validator is not go-playground/validator")
+	}
+	v.RegisterTagNameFunc(fieldName)
+	for tag, fn := range map[string]validator.Func{
+		"eth_address": func(fl validator.FieldLevel) bool { return IsAddress(fl.Field().String()) },
+		"tx_hash":     func(fl validator.FieldLevel) bool { return IsTxHash(fl.Field().String()) },
+		"uint256":     func(fl validator.FieldLevel) bool { return IsUint256(fl.Field().String()) },
+		"id":          validID,
+	} {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embedded names embedded structs, whose fields are reported as the
+// embedding struct's own.
+const embedded = "_"
+
+func fieldName(f reflect.StructField) string {
+	if f.Anonymous {
+		return embedded
+	}
+	for _, key := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// IsAddress accepts a hex address in all lower or upper case, or in mixed
+// case matching its EIP-55 checksum.
+func IsAddress(s string) bool {
+	if !addressPattern.MatchString(s) {
+		return false
+	}
+	hex := s[2:]
+	if hex == strings.ToLower(hex) || hex == strings.ToUpper(hex) {
+		return true
+	}
+	return common.HexToAddress(s).Hex() == s
+}
+
+// NormalizeAddress returns the EIP-55 checksummed form of a valid address,
+// the form addresses are stored in.
+func NormalizeAddress(s string) (string, error) {
+	if !IsAddress(s) {
+		return "", core.Validation("invalid_address", "%q %s", s, messages["eth_address"])
+	}
+	return common.HexToAddress(s).Hex(), nil
+}
+
+func IsTxHash(s string) bool {
+	return txHashPattern.MatchString(s)
+}
+
+func IsUint256(s string) bool {
+	if !digitsPattern.MatchString(s) {
+		return false
+	}
+	_, err := core.ParseWei(s)
+	return err == nil
+}
+
+// ParseID parses a positive integer id.
+func ParseID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, strconv.IntSize)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return uint(id), nil
+}
+
+// validID accepts positive unsigned integers and strings holding one.
+func validID(fl validator.FieldLevel) bool {
+	f := fl.Field()
+	switch f.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return f.Uint() > 0
+	case reflect.String:
+		_, err := ParseID(f.String())
+		return err == nil
+	}
+	return false
+}
+
+// Error turns a binding error into a validation error listing each invalid
+// field. Errors that don't name a field, like malformed JSON, are reported
+// as a whole.
+func Error(err error) *core.Error {
+	var fields []core.FieldError
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var coreErr *core.Error
+	switch {
+	case errors.As(err, &verrs):
+		for _, fe := range verrs {
+			fields = append(fields, core.FieldError{Field: fieldPath(fe), Message: message(fe)})
+		}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		fields = append(fields, core.FieldError{Field: typeErr.Field, Message: "must be " + typeErr.Type.String()})
+	case errors.As(err, &coreErr) && coreErr.Kind == core.KindValidation:
+		// Raised by a type's UnmarshalJSON, such as core.Wei
+		return coreErr
+	default:
+		return core.Validation("invalid_request", "%v", err)
+	}
+	return Fields(fields...)
+}
+
+// Fields reports the given invalid fields.
+func Fields(fields ...core.FieldError) *core.Error {
+	e := core.Validation("invalid_request", "request has %d invalid field(s)", len(fields))
+	e.Fields = fields
+	return e
+}
+
+// fieldPath drops the top-level struct and embedded structs from the
+// validator's namespace. Only named structs appear in it, and their name is
+// the one segment that is the same in the Go namespace, as fields are
+// renamed by their tags.
+func fieldPath(fe validator.FieldError) string {
+	segments := strings.Split(fe.Namespace(), ".")
+	goSegments := strings.Split(fe.StructNamespace(), ".")
+	if len(segments) > 1 && segments[0] == goSegments[0] {
+		segments = segments[1:]
+	}
+	path := segments[:0]
+	for _, s := range segments {
+		if s != embedded {
+			path = append(path, s)
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+func message(fe validator.FieldError) string {
+	if m, ok := messages[fe.Tag()]; ok {
+		return m
+	}
+	switch fe.Tag() {
+	case "contains":
+		return "must contain " + strconv.Quote(fe.Param())
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed %s=%s", fe.Tag(), fe.Param())
+	}
+	return "failed " + fe.Tag()
+}
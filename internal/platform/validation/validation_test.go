@@ -0,0 +1,49 @@
+package validation
+
+import "testing"
+
+func TestIsAddress(t *testing.T) {
+	for s, want := range map[string]bool{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266": true,
+		"0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266": true,
+		"0xF39FD6E51AAD88F6F4CE6AB8827279CFFFB92266": true,
+		// Mixed case with a letter's case flipped fails the checksum
+		"0xf39fd6e51aad88F6F4ce6aB8827279cffFb92266":  false,
+		"0xF39Fd6e51aad88F6F4ce6aB8827279cffFb92266":  false,
+		"f39Fd6e51aad88F6F4ce6aB8827279cffFb92266":    false,
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb9226":   false,
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb922666": false,
+		"0xg39Fd6e51aad88F6F4ce6aB8827279cffFb92266":  false,
+	} {
+		if got := IsAddress(s); got != want {
+			t.Errorf("IsAddress(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	got, err := NormalizeAddress("0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266")
+	if err != nil || got != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Fatalf("NormalizeAddress = %s, %v; want the checksummed address", got, err)
+	}
+	if _, err := NormalizeAddress("0xF39Fd6e51aad88F6F4ce6aB8827279cffFb92266"); err == nil {
+		t.Fatal("NormalizeAddress accepted a bad checksum")
+	}
+}
+
+func TestIsUint256(t *testing.T) {
+	for s, want := range map[string]bool{
+		"0":  true,
+		"12": true,
+		"115792089237316195423570985008687907853269984665640564039457584007913129639935": true,
+		"115792089237316195423570985008687907853269984665640564039457584007913129639936": false,
+		"-1":  false,
+		"+1":  false,
+		"1.0": false,
+		"":    false,
+	} {
+		if got := IsUint256(s); got != want {
+			t.Errorf("IsUint256(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenTarget is returned for webhook hosts on loopback, private,
+// link-local or otherwise internal addresses.
+var ErrForbiddenTarget = errors.New("webhook target address is not allowed")
+
+// reserved are the internal ranges net.IP has no predicate for.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 of any IPv4 address
+}
+
+// PublicIP reports whether ip may receive webhooks: it is not loopback,
+// private, link-local, multicast, unspecified or otherwise reserved.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range reserved {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host and returns ErrForbiddenTarget if any of its
+// addresses is not public. Senders check again when they connect, since
+// the name may resolve differently by then.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !PublicIP(ip) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if !PublicIP(a.IP) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to addresses that are not public. It runs
+// after name resolution, on the address actually dialed, so a name that
+// resolves to an internal address later on is refused as well.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return ErrForbiddenTarget
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex signature>".
+	SignatureHeader = "X-Webhook-Signature"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+
+	// maxResponseBody is how much of a response is read to measure it for
+	// the delivery log; the body itself is not kept.
+	maxResponseBody = 1 << 20
+)
+
+// Sign returns the hex HMAC-SHA256, keyed by secret, of the timestamp and
+// body joined by a dot. Signing the timestamp lets receivers reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender posts signed payloads. It does not follow redirects, so a payload
+// only ever goes to the URL it was signed for, and unless allowPrivate it
+// refuses to connect to addresses that are not public (see PublicIP).
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection, so addresses could not be checked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Result is the outcome of one post. Err is set for transport failures and
+// non-2xx responses alike.
+type Result struct {
+	StatusCode int
+	// BodyBytes is the length of the response body, up to 1 MiB.
+	BodyBytes int64
+	Duration  time.Duration
+	Err       error
+}
+
+// Send posts body to url as delivery deliveryID of an event of eventType.
+func (s *Sender) Send(ctx context.Context, url, secret string, deliveryID uint, eventType string, body []byte) Result {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nft-marketplace-webhooks/1.0")
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(deliveryID), 10))
+	req.Header.Set(EventHeader, eventType)
+	ts := start.Unix()
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", ts, Sign(secret, ts, body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Duration: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+	n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	res := Result{StatusCode: resp.StatusCode, BodyBytes: n, Duration: time.Since(start)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		res.Err = fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return res
+}
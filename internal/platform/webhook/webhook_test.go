@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+	} {
+		if got := PublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("PublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	for _, host := range []string{"127.0.0.1", "::1", "169.254.169.254", "localhost"} {
+		if err := CheckHost(ctx, host); !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("CheckHost(%s) = %v, want ErrForbiddenTarget", host, err)
+		}
+	}
+	if err := CheckHost(ctx, "93.184.216.34"); err != nil {
+		t.Errorf("CheckHost(public) = %v", err)
+	}
+}
+
+func TestSenderRefusesPrivateAddresses(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("internal secret"))
+	}))
+	defer srv.Close()
+
+	res := NewSender(time.Second, false).Send(context.Background(), srv.URL, "whsec_test", 1, "test", []byte("{}"))
+	if !errors.Is(res.Err, ErrForbiddenTarget) {
+		t.Fatalf("Send to loopback: err = %v, want ErrForbiddenTarget", res.Err)
+	}
+	if hits != 0 {
+		t.Fatalf("loopback server was reached %d times", hits)
+	}
+
+	res = NewSender(time.Second, true).Send(context.Background(), srv.URL, "whsec_test", 1, "test", []byte("{}"))
+	if res.Err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("Send with private addresses allowed: status %d, err %v", res.StatusCode, res.Err)
+	}
+	if res.BodyBytes != int64(len("internal secret")) {
+		t.Fatalf("BodyBytes = %d, want %d", res.BodyBytes, len("internal secret"))
+	}
+}
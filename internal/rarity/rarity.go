@@ -0,0 +1,180 @@
+package rarity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+type Method string
+
+const (
+	// MethodStatistical scores a token by the inverse of the product of its trait probabilities.
+	MethodStatistical Method = "statistical"
+	// MethodTraitNormalized sums 1/p per trait, weighted so trait types with many values don't dominate.
+	MethodTraitNormalized Method = "trait_normalized"
+	// MethodInformationContent sums -log2(p) per trait, normalized by the collection entropy.
+	MethodInformationContent Method = "information_content"
+)
+
+// NoneValue stands in for a trait type a token does not have.
+const NoneValue = "<none>"
+
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(s); m {
+	case MethodStatistical, MethodTraitNormalized, MethodInformationContent:
+		return m, nil
+	case "":
+		return MethodStatistical, nil
+	}
+	return "", fmt.Errorf("unknown rarity method %q", s)
+}
+
+// Frequencies holds the trait value counts of a collection.
+type Frequencies struct {
+	total  int
+	counts map[string]map[string]int
+}
+
+func NewFrequencies(total int, counts []core.TraitCount) *Frequencies {
+	f := &Frequencies{total: total, counts: make(map[string]map[string]int)}
+	for _, tc := range counts {
+		if tc.TokenCount <= 0 {
+			continue
+		}
+		if f.counts[tc.TraitType] == nil {
+			f.counts[tc.TraitType] = make(map[string]int)
+		}
+		f.counts[tc.TraitType][tc.Value] += tc.TokenCount
+	}
+	// Tokens missing a trait type are counted under NoneValue.
+	for traitType, values := range f.counts {
+		if traitType == core.TraitCountType {
+			continue
+		}
+		seen := 0
+		for _, n := range values {
+			seen += n
+		}
+		if missing := total - seen; missing > 0 {
+			values[NoneValue] = missing
+		}
+	}
+	return f
+}
+
+func (f *Frequencies) probability(traitType, value string) float64 {
+	n := f.counts[traitType][value]
+	if n == 0 || f.total == 0 {
+		return 1
+	}
+	return float64(n) / float64(f.total)
+}
+
+// values returns the value a token holds for every trait type in the collection.
+func (f *Frequencies) values(attrs []core.NFTAttribute, withTraitCount bool) map[string]string {
+	own := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		own[a.TraitType] = a.Value
+	}
+	out := make(map[string]string, len(f.counts))
+	for traitType := range f.counts {
+		if traitType == core.TraitCountType {
+			if withTraitCount {
+				out[traitType] = fmt.Sprint(len(attrs))
+			}
+			continue
+		}
+		if v, ok := own[traitType]; ok {
+			out[traitType] = v
+		} else {
+			out[traitType] = NoneValue
+		}
+	}
+	return out
+}
+
+func (f *Frequencies) entropy() float64 {
+	var h float64
+	for traitType, values := range f.counts {
+		if traitType == core.TraitCountType {
+			continue
+		}
+		for _, n := range values {
+			p := float64(n) / float64(f.total)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// Score returns the rarity score of a token; higher is rarer.
+func (f *Frequencies) Score(method Method, attrs []core.NFTAttribute) float64 {
+	if f.total == 0 {
+		return 0
+	}
+	switch method {
+	case MethodTraitNormalized:
+		values := f.values(attrs, true)
+		if len(values) == 0 {
+			return 0
+		}
+		var avgDistinct float64
+		for traitType := range values {
+			avgDistinct += float64(len(f.counts[traitType]))
+		}
+		avgDistinct /= float64(len(values))
+
+		var score float64
+		for traitType, v := range values {
+			distinct := float64(len(f.counts[traitType]))
+			if distinct == 0 {
+				continue
+			}
+			score += (1 / f.probability(traitType, v)) * (avgDistinct / distinct)
+		}
+		return score
+	case MethodInformationContent:
+		h := f.entropy()
+		if h == 0 {
+			return 0
+		}
+		var ic float64
+		for traitType, v := range f.values(attrs, false) {
+			ic -= math.Log2(f.probability(traitType, v))
+		}
+		return ic / h
+	default:
+		product := 1.0
+		for traitType, v := range f.values(attrs, false) {
+			product *= f.probability(traitType, v)
+		}
+		return 1 / product
+	}
+}
+
+// Rank assigns competition ranks (1 = rarest) to scores, keyed by NFT id.
+func Rank(scores map[uint]float64) map[uint]int {
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	ranks := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		if i > 0 && scores[id] == scores[ids[i-1]] {
+			ranks[id] = ranks[ids[i-1]]
+			continue
+		}
+		ranks[id] = i + 1
+	}
+	return ranks
+}
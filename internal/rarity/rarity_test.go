@@ -0,0 +1,72 @@
+package rarity
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// attrs are the attributes, by token id, of a collection of four tokens:
+// three red, one blue, and one of the red ones wearing a cap.
+var attrs = map[uint][]core.NFTAttribute{
+	1: {{TraitType: "Color", Value: "Red"}, {TraitType: "Hat", Value: "Cap"}},
+	2: {{TraitType: "Color", Value: "Red"}},
+	3: {{TraitType: "Color", Value: "Red"}},
+	4: {{TraitType: "Color", Value: "Blue"}},
+}
+
+func collection() *Frequencies {
+	return NewFrequencies(4, []core.TraitCount{
+		{TraitType: "Color", Value: "Red", TokenCount: 3},
+		{TraitType: "Color", Value: "Blue", TokenCount: 1},
+		{TraitType: "Hat", Value: "Cap", TokenCount: 1},
+		{TraitType: core.TraitCountType, Value: "1", TokenCount: 3},
+		{TraitType: core.TraitCountType, Value: "2", TokenCount: 1},
+	})
+}
+
+func TestStatisticalScore(t *testing.T) {
+	f := collection()
+	// Tokens without a hat count as having none, 3 in 4 of them
+	want := map[uint]float64{1: 16.0 / 3, 2: 16.0 / 9, 3: 16.0 / 9, 4: 16.0 / 3}
+	for id, a := range attrs {
+		if got := f.Score(MethodStatistical, a); math.Abs(got-want[id]) > 1e-9 {
+			t.Errorf("token %d: score %v, want %v", id, got, want[id])
+		}
+	}
+}
+
+func TestScoresOrderTokens(t *testing.T) {
+	f := collection()
+	for _, method := range []Method{MethodStatistical, MethodTraitNormalized, MethodInformationContent} {
+		common, rare := f.Score(method, attrs[2]), f.Score(method, attrs[1])
+		if !(rare > common) || common <= 0 {
+			t.Errorf("%s: capped token scores %v, plain one %v; want the capped one rarer", method, rare, common)
+		}
+	}
+	if s := NewFrequencies(0, nil).Score(MethodStatistical, nil); s != 0 {
+		t.Errorf("score in an empty collection is %v, want 0", s)
+	}
+}
+
+func TestRank(t *testing.T) {
+	got := Rank(map[uint]float64{1: 2, 2: 5, 3: 2, 4: 1})
+	want := map[uint]int{2: 1, 1: 2, 3: 2, 4: 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranks %v, want %v", got, want)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	if m, err := ParseMethod(""); err != nil || m != MethodStatistical {
+		t.Fatalf("empty method: %q, %v; want statistical", m, err)
+	}
+	if m, err := ParseMethod("information_content"); err != nil || m != MethodInformationContent {
+		t.Fatalf("information_content: %q, %v", m, err)
+	}
+	if _, err := ParseMethod("random"); err == nil {
+		t.Fatal("unknown method accepted")
+	}
+}
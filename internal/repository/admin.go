@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Admin methods. Every change made here is written to the audit log in the
+// same transaction as the change itself.
+
+func (r *Repository) ListUsers(filter core.UserFilter, page core.PageRequest) (*core.Page[core.User], error) {
+	query := r.db.Model(&core.User{})
+	if filter.Role != "" {
+		query = query.Where(`"user".role = ?`, filter.Role)
+	}
+	return userQuery.find(query, page)
+}
+
+func (r *Repository) SetUserRole(actorID *uint, userID uint, role core.Role, reason string) (*core.User, error) {
+	var user core.User
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return notFound(err, "user")
+		}
+		previous := user.Role
+		if err := tx.Model(&user).Update("role", role).Error; err != nil {
+			return err
+		}
+		return tx.Create(&core.AuditLog{
+			ActorUserID: actorID,
+			Action:      core.AuditUserRoleChanged,
+			TargetType:  "user",
+			TargetID:    userID,
+			Reason:      reason,
+			Details:     map[string]interface{}{"from": previous, "to": role},
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) SetCollectionVerified(actorID, collectionID uint, verified bool, reason string) (*core.Collection, error) {
+	var collection core.Collection
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&collection, collectionID).Error; err != nil {
+			return notFound(err, "collection")
+		}
+		if err := tx.Model(&collection).Update("verified", verified).Error; err != nil {
+			return err
+		}
+		return tx.Create(&core.AuditLog{
+			ActorUserID: &actorID,
+			Action:      core.AuditCollectionVerified,
+			TargetType:  "collection",
+			TargetID:    collectionID,
+			Reason:      reason,
+			Details:     map[string]interface{}{"verified": verified},
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// ForceCancelListing cancels an open listing regardless of its seller.
+func (r *Repository) ForceCancelListing(actorID, listingID uint, reason string) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		res := tx.Model(&core.Listing{}).Where("id = ? AND status IN ?", listingID, openListingStatuses).
+			Updates(bumpVersion(map[string]interface{}{"status": core.ListingCancelled}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return core.InvalidState("listing_not_active", "listing is not active")
+		}
+		if err := tx.Create(&core.AuditLog{
+			ActorUserID: &actorID,
+			Action:      core.AuditListingForceCancel,
+			TargetType:  "listing",
+			TargetID:    listingID,
+			Reason:      reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		var listing core.Listing
+		if err := tx.First(&listing, listingID).Error; err != nil {
+			return err
+		}
+		e, err := listingEvent(tx, core.EventListingCancelled, &listing, 0, map[string]interface{}{"reason": reason})
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
+}
+
+// MarkOrderFailed fails a pending order, releasing its reservation.
+func (r *Repository) MarkOrderFailed(actorID, orderID uint, reason string) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		res := tx.Model(&core.Order{}).Where("id = ? AND status = ?", orderID, core.OrderPending).
+			Updates(bumpVersion(map[string]interface{}{"status": core.OrderFailed}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return core.InvalidState("order_not_pending", "order is not pending")
+		}
+		if err := releaseReservation(tx, orderID); err != nil {
+			return err
+		}
+		if err := tx.Create(&core.AuditLog{
+			ActorUserID: &actorID,
+			Action:      core.AuditOrderMarkedFailed,
+			TargetType:  "order",
+			TargetID:    orderID,
+			Reason:      reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		var order core.Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return err
+		}
+		e, err := orderEvent(tx, core.EventOrderFailed, &order, map[string]interface{}{"reason": reason})
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
+}
+
+func (r *Repository) ListAuditLog(filter core.AuditFilter, page core.PageRequest) (*core.Page[core.AuditLog], error) {
+	query := r.db.Model(&core.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("audit_log.actor_user_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("audit_log.target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("audit_log.target_id = ?", filter.TargetID)
+	}
+	return auditLogQuery.find(query, page)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// API key methods
+func (r *Repository) CreateAPIKey(key *core.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *Repository) GetAPIKeyByHash(hash string) (*core.APIKey, error) {
+	var key core.APIKey
+	if err := r.db.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *Repository) GetAPIKey(userID, id uint) (*core.APIKey, error) {
+	var key core.APIKey
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&key).Error; err != nil {
+		return nil, notFound(err, "api_key")
+	}
+	return &key, nil
+}
+
+func (r *Repository) ListAPIKeys(userID uint) ([]core.APIKey, error) {
+	var keys []core.APIKey
+	if err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of userID.
+func (r *Repository) RevokeAPIKey(userID, id uint) error {
+	res := r.db.Model(&core.APIKey{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return core.NotFound("api_key")
+	}
+	return nil
+}
+
+// RecordAPIKeyUse bumps the key's usage counters for a request made at now.
+func (r *Repository) RecordAPIKeyUse(id uint, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&core.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"usage_count":  gorm.Expr("usage_count + 1"),
+			"last_used_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		usage := core.APIKeyUsage{APIKeyID: id, Day: now.UTC().Truncate(24 * time.Hour), Requests: 1}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "api_key_id"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"requests": gorm.Expr("api_key_usage.requests + 1")}),
+		}).Create(&usage).Error
+	})
+}
+
+func (r *Repository) ListAPIKeyUsage(id uint, since time.Time) ([]core.APIKeyUsage, error) {
+	var usage []core.APIKeyUsage
+	if err := r.db.Where("api_key_id = ? AND day >= ?", id, since.UTC().Truncate(24*time.Hour)).
+		Order("day").Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
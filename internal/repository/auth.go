@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Auth methods
+func (r *Repository) CreateAuthNonce(nonce *core.AuthNonce) error {
+	return r.db.Create(nonce).Error
+}
+
+// ConsumeAuthNonce marks an unexpired nonce used. It fails with a not-found
+// error if the nonce is unknown, expired or already used.
+func (r *Repository) ConsumeAuthNonce(nonce string) error {
+	now := time.Now()
+	res := r.db.Model(&core.AuthNonce{}).
+		Where("nonce = ? AND used_at IS NULL AND expires_at > ?", nonce, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return core.NotFound("nonce")
+	}
+	return nil
+}
+
+// FindOrCreateUserByWallet matches wallets case-insensitively and creates
+// the user with the given address when none exists.
+func (r *Repository) FindOrCreateUserByWallet(wallet string) (*core.User, error) {
+	var user core.User
+	err := r.db.Where("LOWER(wallet_address) = LOWER(?)", wallet).Order("id").First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	user = core.User{WalletAddress: wallet}
+	if err := r.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) CreateSession(session *core.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *Repository) GetSession(id uint) (*core.Session, error) {
+	var session core.Session
+	if err := r.db.First(&session, id).Error; err != nil {
+		return nil, notFound(err, "session")
+	}
+	return &session, nil
+}
+
+func (r *Repository) GetSessionByRefreshHash(hash string) (*core.Session, error) {
+	var session core.Session
+	if err := r.db.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, notFound(err, "session")
+	}
+	return &session, nil
+}
+
+// RotateSession revokes old and stores next as its replacement. It fails with
+// a conflict if old was revoked concurrently.
+func (r *Repository) RotateSession(old, next *core.Session) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&core.Session{}).Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by_id": next.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return core.Conflict("session_revoked", "session was already rotated")
+		}
+		return nil
+	})
+}
+
+func (r *Repository) RevokeSession(id uint) error {
+	return r.db.Model(&core.Session{}).Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *Repository) RevokeSessionFamily(familyID string) error {
+	return r.db.Model(&core.Session{}).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *Repository) RevokeUserSessions(userID uint) error {
+	return r.db.Model(&core.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// contenders is how many changes each test races.
+const contenders = 8
+
+// race runs fn for 0 to n-1 in parallel, releasing them together, and
+// returns their errors by index.
+func race(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// winner returns the index of the only nil error, failing unless there is
+// exactly one.
+func winner(t *testing.T, errs []error) int {
+	t.Helper()
+	won := -1
+	for i, err := range errs {
+		if err != nil {
+			continue
+		}
+		if won >= 0 {
+			t.Fatalf("changes %d and %d both succeeded", won, i)
+		}
+		won = i
+	}
+	if won < 0 {
+		t.Fatalf("no change succeeded: %v", errs)
+	}
+	return won
+}
+
+func TestConcurrentListingStatusUpdates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+
+		// Every contender decides from the same read
+		errs := race(contenders, func(i int) error {
+			read := *listing
+			return s.UpdateListingStatus(&read, core.ListingCancelled)
+		})
+		won := winner(t, errs)
+		for i, err := range errs {
+			if i != won && !errors.Is(err, core.Conflict("concurrent_update", "")) {
+				t.Errorf("change %d: %v, want concurrent_update", i, err)
+			}
+		}
+		got, _ := s.GetListingByID(listing.ID)
+		if got.Status != core.ListingCancelled || got.Version != listing.Version+1 {
+			t.Fatalf("listing %s version %d, want CANCELLED at version %d", got.Status, got.Version, listing.Version+1)
+		}
+	})
+}
+
+// Buyers confirming an order race its seller cancelling the listing, as
+// read before any of them: one of them wins and the state is either sold to
+// the buyer or cancelled, never a mix.
+func TestConcurrentConfirmAndCancel(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		nft := seedNFT(t, s, collection, owner, 1)
+		listing := seedListing(t, s, nft, 100)
+		order := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+		if err := s.CreateOrder(order, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		confirms := func(i int) bool { return i%2 == 0 }
+		errs := race(contenders, func(i int) error {
+			if confirms(i) {
+				return s.ConfirmOrder(order.ID, "0x01")
+			}
+			read := *listing
+			return s.UpdateListingStatus(&read, core.ListingCancelled)
+		})
+		won := winner(t, errs)
+		for i, err := range errs {
+			switch {
+			case i == won:
+			case !confirms(i):
+				// Cancelling a listing that changed since it was read
+				if !errors.Is(err, core.Conflict("concurrent_update", "")) {
+					t.Errorf("cancel %d: %v, want concurrent_update", i, err)
+				}
+			case !errors.Is(err, core.ErrInvalidState):
+				// Confirmations read the listing afresh and find it sold
+				// or cancelled
+				t.Errorf("confirmation %d: %v, want an invalid state", i, err)
+			}
+		}
+
+		gotOrder, _ := s.GetOrderByID(order.ID)
+		gotListing, _ := s.GetListingByID(listing.ID)
+		gotNFT, _ := s.GetNFTByID(nft.ID)
+		if confirms(won) {
+			if gotOrder.Status != core.OrderConfirmed || gotListing.Status != core.ListingSold || gotNFT.OwnerUserID != buyer.ID {
+				t.Fatalf("confirmation won, but order %s, listing %s, nft owned by %d",
+					gotOrder.Status, gotListing.Status, gotNFT.OwnerUserID)
+			}
+		} else if gotOrder.Status != core.OrderPending || gotListing.Status != core.ListingCancelled || gotNFT.OwnerUserID != owner.ID {
+			t.Fatalf("cancel won, but order %s, listing %s, nft owned by %d",
+				gotOrder.Status, gotListing.Status, gotNFT.OwnerUserID)
+		}
+	})
+}
+
+// Buyers ordering the same listing at once: one order reserves it and the
+// others are told until when it is held.
+func TestConcurrentOrdersReserveOnce(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+
+		base := time.Now().Add(time.Minute).Truncate(time.Second)
+		orders := make([]*core.Order, contenders)
+		errs := race(contenders, func(i int) error {
+			orders[i] = &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+			until := base.Add(time.Duration(i) * time.Second)
+			return s.CreateOrder(orders[i], &until)
+		})
+		won := winner(t, errs)
+		held := base.Add(time.Duration(won) * time.Second)
+		for i, err := range errs {
+			if i == won {
+				continue
+			}
+			var coreErr *core.Error
+			if !errors.As(err, &coreErr) || coreErr.Code != "listing_reserved" || !coreErr.RetryAt.Equal(held) {
+				t.Errorf("order %d: %v, want listing_reserved until %v", i, err, held)
+			}
+		}
+
+		got, _ := s.GetListingByID(listing.ID)
+		if id := got.ReservedByOrderID; id == nil || *id != orders[won].ID || !got.ReservedUntil.Equal(held) {
+			t.Fatalf("listing reserved by %v until %v, want order %d until %v", id, got.ReservedUntil, orders[won].ID, held)
+		}
+	})
+}
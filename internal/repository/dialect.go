@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// The repository runs on Postgres and SQLite. SQLite has no row locks, so
+// db serializes its transactions instead, and no numeric type exact to 78
+// digits, so wei amounts are kept there as decimal text.
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// weiDigits is the most digits a wei amount has.
+const weiDigits = 78
+
+// weiSQL is expr, a wei amount, as compared and ordered by value in db: on
+// SQLite, its text zero-padded to weiDigits.
+func weiSQL(db *gorm.DB, expr string) string {
+	if isSQLite(db) {
+		return fmt.Sprintf("substr('%s' || %s, -%d)", strings.Repeat("0", weiDigits), expr, weiDigits)
+	}
+	return expr
+}
+
+// weiCondition is the condition that expr, a wei amount, compares by op
+// with the wei amount bound to it.
+func weiCondition(db *gorm.DB, expr, op string) string {
+	return weiSQL(db, expr) + " " + op + " " + weiSQL(db, "?")
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// EventPublisher is told about events once the transaction recording them
+// has committed.
+type EventPublisher interface {
+	Publish(events ...core.Event)
+}
+
+// eventLog records events in a transaction for publishing after it commits.
+type eventLog struct {
+	tx       *gorm.DB
+	recorded []core.Event
+}
+
+func (l *eventLog) record(events ...*core.Event) error {
+	for _, e := range events {
+		if err := l.tx.Create(e).Error; err != nil {
+			return err
+		}
+		l.recorded = append(l.recorded, *e)
+	}
+	return nil
+}
+
+// transaction runs fn in a transaction and publishes the events it recorded
+// if the transaction commits.
+func (r *Repository) transaction(fn func(tx *gorm.DB, events *eventLog) error) error {
+	var events *eventLog
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		events = &eventLog{tx: tx}
+		return fn(tx, events)
+	})
+	if err == nil && r.events != nil && len(events.recorded) > 0 {
+		r.events.Publish(events.recorded...)
+	}
+	return err
+}
+
+// RecordEvents logs and publishes events that accompany no other change,
+// such as a transaction being mined.
+func (r *Repository) RecordEvents(events ...*core.Event) error {
+	return r.transaction(func(tx *gorm.DB, l *eventLog) error {
+		return l.record(events...)
+	})
+}
+
+// ListEvents returns up to limit logged events matching filter with ids
+// after afterID, oldest first.
+func (r *Repository) ListEvents(filter core.EventFilter, afterID uint, limit int) ([]core.Event, error) {
+	query := r.db.Where("id > ?", afterID)
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.CollectionID != 0 {
+		query = query.Where("collection_id = ?", filter.CollectionID)
+	}
+	if filter.NFTID != 0 {
+		query = query.Where("nft_id = ?", filter.NFTID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("(user_id = ? OR counterparty_user_id = ?)", filter.UserID, filter.UserID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	var events []core.Event
+	if err := query.Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// PruneEvents deletes events logged before cutoff, keeping those not yet
+// dispatched to webhooks.
+func (r *Repository) PruneEvents(cutoff time.Time) error {
+	return r.db.Where("created_at < ? AND dispatched_at IS NOT NULL", cutoff).Delete(&core.Event{}).Error
+}
+
+// orderEvent describes a change to order, published like its listing's
+// events with the buyer as counterparty.
+func orderEvent(tx *gorm.DB, t core.EventType, order *core.Order, data map[string]interface{}) (*core.Event, error) {
+	var listing core.Listing
+	if err := tx.First(&listing, order.ListingID).Error; err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["order_id"] = order.ID
+	return listingEvent(tx, t, &listing, order.BuyerUserID, data)
+}
+
+// listingEvent describes a change to listing, published on its NFT's
+// collection and its seller; buyerID is the counterparty, if any.
+func listingEvent(tx *gorm.DB, t core.EventType, listing *core.Listing, buyerID uint, data map[string]interface{}) (*core.Event, error) {
+	var collectionID uint
+	if err := tx.Model(&core.NFT{}).Select("collection_id").Where("id = ?", listing.NFTID).
+		Scan(&collectionID).Error; err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["listing_id"] = listing.ID
+	data["price_wei"] = listing.PriceWei
+	data["currency"] = listing.Currency
+	return &core.Event{
+		Type:               t,
+		CollectionID:       collectionID,
+		NFTID:              listing.NFTID,
+		UserID:             listing.SellerUserID,
+		CounterpartyUserID: buyerID,
+		Data:               data,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestListEventsSince(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		if err := s.RecordEvents(&core.Event{Type: core.EventListingCreated}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		since := time.Now()
+		later := &core.Event{Type: core.EventListingSold}
+		if err := s.RecordEvents(later); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := s.ListEvents(core.EventFilter{}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events, err := s.ListEvents(core.EventFilter{Since: since}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 || len(events) != 1 || events[0].ID != later.ID {
+			t.Fatalf("got %d events, %d since %v; want 2 and only event %d", len(all), len(events), since, later.ID)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Expiry methods. Each listing or order is expired in its own transaction
+// with a conditional update, so replicas expiring at the same time record
+// a single event.
+
+// expiryBatch bounds how many listings or orders one call expires.
+const expiryBatch = 100
+
+// ExpireListings moves up to a batch of open listings past their expiry to
+// EXPIRED and returns how many it found.
+func (r *Repository) ExpireListings(now time.Time) (int, error) {
+	var ids []uint
+	if err := r.db.Model(&core.Listing{}).
+		Where("status IN ? AND expires_at <= ?", openListingStatuses, now).
+		Order("expires_at").Limit(expiryBatch).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+			res := tx.Model(&core.Listing{}).Where("id = ? AND status IN ?", id, openListingStatuses).
+				Updates(bumpVersion(map[string]interface{}{"status": core.ListingExpired}))
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			var listing core.Listing
+			if err := tx.First(&listing, id).Error; err != nil {
+				return err
+			}
+			e, err := listingEvent(tx, core.EventListingExpired, &listing, 0, map[string]interface{}{"expires_at": listing.ExpiresAt})
+			if err != nil {
+				return err
+			}
+			return events.record(e)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// ExpireOrders moves up to a batch of pending orders past their expiry to
+// EXPIRED, releasing their reservations, and returns how many it found.
+func (r *Repository) ExpireOrders(now time.Time) (int, error) {
+	var ids []uint
+	if err := r.db.Model(&core.Order{}).
+		Where("status = ? AND expires_at <= ?", core.OrderPending, now).
+		Order("expires_at").Limit(expiryBatch).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+			res := tx.Model(&core.Order{}).Where("id = ? AND status = ?", id, core.OrderPending).
+				Updates(bumpVersion(map[string]interface{}{"status": core.OrderExpired}))
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			if err := releaseReservation(tx, id); err != nil {
+				return err
+			}
+			var order core.Order
+			if err := tx.First(&order, id).Error; err != nil {
+				return err
+			}
+			e, err := orderEvent(tx, core.EventOrderExpired, &order, map[string]interface{}{"expires_at": order.ExpiresAt})
+			if err != nil {
+				return err
+			}
+			return events.record(e)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// ReleaseReservations clears reservations that ran out, so the listing
+// shows no holder; they stopped holding the listing when they did.
+func (r *Repository) ReleaseReservations(now time.Time) (int, error) {
+	res := r.db.Model(&core.Listing{}).Where("reserved_until <= ?", now).
+		Updates(bumpVersion(map[string]interface{}{"reserved_by_order_id": nil, "reserved_until": nil}))
+	return int(res.RowsAffected), res.Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestExpireListings(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		expires := time.Now().Add(time.Minute)
+		later := expires.Add(time.Hour)
+		expiring := seedExpiringListing(t, s, seedNFT(t, s, collection, owner, 1), expires)
+		lasting := seedExpiringListing(t, s, seedNFT(t, s, collection, owner, 2), later)
+
+		if n, err := s.ExpireListings(expires.Add(-time.Second)); err != nil || n != 0 {
+			t.Fatalf("ExpireListings before expiry: %d, %v", n, err)
+		}
+		if n, err := s.ExpireListings(expires.Add(time.Second)); err != nil || n != 1 {
+			t.Fatalf("ExpireListings: %d, %v", n, err)
+		}
+		// Expired listings are not found again
+		if n, err := s.ExpireListings(expires.Add(time.Second)); err != nil || n != 0 {
+			t.Fatalf("ExpireListings again: %d, %v", n, err)
+		}
+
+		got, _ := s.GetListingByID(expiring.ID)
+		other, _ := s.GetListingByID(lasting.ID)
+		if got.Status != core.ListingExpired || other.Status != core.ListingActive {
+			t.Fatalf("listings %s and %s, want EXPIRED and ACTIVE", got.Status, other.Status)
+		}
+		events, err := s.ListEvents(core.EventFilter{Types: []core.EventType{core.EventListingExpired}}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].NFTID != expiring.NFTID {
+			t.Fatalf("got %d listing.expired events, want one for listing %d", len(events), expiring.ID)
+		}
+	})
+}
+
+func seedExpiringListing(t *testing.T, s core.Store, nft *core.NFT, expiresAt time.Time) *core.Listing {
+	t.Helper()
+	listing := &core.Listing{NFTID: nft.ID, SellerUserID: nft.OwnerUserID, PriceWei: core.NewWei(big.NewInt(100)), ExpiresAt: &expiresAt}
+	if err := s.CreateListing(listing); err != nil {
+		t.Fatal(err)
+	}
+	return listing
+}
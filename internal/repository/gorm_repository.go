@@ -1,16 +1,48 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 	"github.com/user/nft-marketplace/internal/core"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events EventPublisher
+}
+
+// NewRepository returns a repository that publishes the events it records to
+// events, which may be nil.
+func NewRepository(db *gorm.DB, events EventPublisher) *Repository {
+	return &Repository{db: db, events: events}
+}
+
+// notFound turns gorm's not-found error into a core not-found error for what.
+func notFound(err error, what string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return core.NotFound(what)
+	}
+	return err
 }
 
-func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+// conflict turns a unique violation into a core conflict error.
+func conflict(err error, code, message string) error {
+	if isUniqueViolation(err) {
+		return core.Conflict(code, message)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" || errors.Is(err, gorm.ErrDuplicatedKey)
 }
 
 func (r *Repository) Ping() error {
@@ -22,22 +54,29 @@ func (r *Repository) Ping() error {
 }
 
 // User methods
+// CreateUser creates user unless a user with its wallet, in any letter case,
+// already exists, in which case user is filled with that one.
 func (r *Repository) CreateUser(user *core.User) error {
-	return r.db.Where(core.User{WalletAddress: user.WalletAddress}).FirstOrCreate(user).Error
+	err := r.db.Where("LOWER(wallet_address) = LOWER(?)", user.WalletAddress).Order("id").FirstOrCreate(user).Error
+	if isUniqueViolation(err) {
+		// Created concurrently; wallets are unique
+		return r.db.Where("LOWER(wallet_address) = LOWER(?)", user.WalletAddress).First(user).Error
+	}
+	return err
 }
 
 func (r *Repository) GetUserByID(id uint) (*core.User, error) {
 	var user core.User
 	if err := r.db.First(&user, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err, "user")
 	}
 	return &user, nil
 }
 
 func (r *Repository) GetUserByWallet(wallet string) (*core.User, error) {
 	var user core.User
-	if err := r.db.Where("wallet_address = ?", wallet).First(&user).Error; err != nil {
-		return nil, err
+	if err := r.db.Where("LOWER(wallet_address) = LOWER(?)", wallet).Order("id").First(&user).Error; err != nil {
+		return nil, notFound(err, "user")
 	}
 	return &user, nil
 }
@@ -47,18 +86,18 @@ func (r *Repository) CreateCollection(collection *core.Collection) error {
 	return r.db.Create(collection).Error
 }
 
-func (r *Repository) ListCollections() ([]core.Collection, error) {
-	var collections []core.Collection
-	if err := r.db.Find(&collections).Error; err != nil {
-		return nil, err
+func (r *Repository) ListCollections(filter core.CollectionFilter, page core.PageRequest) (*core.Page[core.Collection], error) {
+	query := r.db.Model(&core.Collection{})
+	if filter.CreatorID != 0 {
+		query = query.Where("collection.creator_user_id = ?", filter.CreatorID)
 	}
-	return collections, nil
+	return collectionQuery.find(query, page)
 }
 
 func (r *Repository) FindCollectionByOwner(ownerID uint) (*core.Collection, error) {
 	var collection core.Collection
 	if err := r.db.Where("creator_user_id = ?", ownerID).First(&collection).Error; err != nil {
-		return nil, err
+		return nil, notFound(err, "collection")
 	}
 	return &collection, nil
 }
@@ -66,118 +105,477 @@ func (r *Repository) FindCollectionByOwner(ownerID uint) (*core.Collection, erro
 func (r *Repository) FindCollectionByName(ownerID uint, name string) (*core.Collection, error) {
 	var collection core.Collection
 	if err := r.db.Where("creator_user_id = ? AND name = ?", ownerID, name).First(&collection).Error; err != nil {
-		return nil, err
+		return nil, notFound(err, "collection")
 	}
 	return &collection, nil
 }
 
 // NFT methods
 func (r *Repository) CreateNFT(nft *core.NFT) error {
-	return r.db.Create(nft).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(nft).Error; err != nil {
+			return conflict(err, "nft_exists", "nft is already registered")
+		}
+		return adjustTraitCounts(tx, nft.CollectionID, nft.Attributes, 1)
+	})
 }
 
 func (r *Repository) GetNFTByID(id uint) (*core.NFT, error) {
 	var nft core.NFT
-	if err := r.db.First(&nft, id).Error; err != nil {
-		return nil, err
+	if err := r.db.Preload("Attributes").First(&nft, id).Error; err != nil {
+		return nil, notFound(err, "nft")
 	}
 	return &nft, nil
 }
 
-func (r *Repository) ListNFTs(ownerID uint, collectionID uint, chain string) ([]core.NFT, error) {
-	query := r.db.Model(&core.NFT{})
-	if ownerID != 0 {
-		query = query.Where("owner_user_id = ?", ownerID)
+// burnNFT marks the token burned, removes its traits from the collection
+// frequencies and cancels any listing still open for it.
+func burnNFT(tx *gorm.DB, events *eventLog, id uint) error {
+	res := tx.Model(&core.NFT{}).Where("id = ? AND burned_at IS NULL", id).
+		Updates(bumpVersion(map[string]interface{}{"burned_at": time.Now(), "rarity_rank": 0}))
+	if res.Error != nil {
+		return res.Error
 	}
-	if collectionID != 0 {
-		query = query.Where("collection_id = ?", collectionID)
+	if res.RowsAffected == 0 {
+		return core.InvalidState("nft_burned", "nft is already burned")
 	}
-	if chain != "" {
-		query = query.Where("chain = ?", chain)
+
+	var nft core.NFT
+	if err := tx.Preload("Attributes").First(&nft, id).Error; err != nil {
+		return err
+	}
+	if err := adjustTraitCounts(tx, nft.CollectionID, nft.Attributes, -1); err != nil {
+		return err
+	}
+	if err := events.record(&core.Event{
+		Type:         core.EventNFTBurned,
+		CollectionID: nft.CollectionID,
+		NFTID:        nft.ID,
+		UserID:       nft.OwnerUserID,
+		Data:         map[string]interface{}{"token_id": nft.TokenID, "contract_address": nft.ContractAddress},
+	}); err != nil {
+		return err
+	}
+
+	var listings []core.Listing
+	if err := tx.Where("nft_id = ? AND status IN ?", id, openListingStatuses).Find(&listings).Error; err != nil {
+		return err
+	}
+	for i := range listings {
+		if err := cancelListing(tx, events, &listings[i], "nft burned"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ListNFTs(filter core.NFTFilter, page core.PageRequest) (*core.Page[core.NFT], error) {
+	query := r.db.Model(&core.NFT{}).Preload("Attributes").Where("nft.burned_at IS NULL")
+	if filter.OwnerID != 0 {
+		query = query.Where("nft.owner_user_id = ?", filter.OwnerID)
 	}
+	if filter.CollectionID != 0 {
+		query = query.Where("nft.collection_id = ?", filter.CollectionID)
+	}
+	if filter.Chain != "" {
+		query = query.Where("nft.chain = ?", filter.Chain)
+	}
+	return nftQuery.find(query, page)
+}
+
+// Rarity methods
+
+// rarityBatch bounds how many tokens one UPDATE rescores, within the bind
+// parameter limits of Postgres and SQLite.
+const rarityBatch = 1000
+
+// adjustTraitCounts counts attrs delta more times in the collection and
+// marks its rarity stale.
+func adjustTraitCounts(tx *gorm.DB, collectionID uint, attrs []core.NFTAttribute, delta int) error {
+	keys := make([]core.TraitCount, 0, len(attrs)+1)
+	for _, a := range attrs {
+		keys = append(keys, core.TraitCount{CollectionID: collectionID, TraitType: a.TraitType, Value: a.Value})
+	}
+	keys = append(keys, core.TraitCount{CollectionID: collectionID, TraitType: core.TraitCountType, Value: fmt.Sprint(len(attrs))})
+
+	for _, key := range keys {
+		key.TokenCount = delta
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "collection_id"}, {Name: "trait_type"}, {Name: "value"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"token_count": gorm.Expr("trait_count.token_count + ?", delta)}),
+		}).Create(&key).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&core.Collection{}).Where("id = ?", collectionID).Update("rarity_stale", true).Error
+}
+
+func (r *Repository) ListTraitCounts(collectionID uint) ([]core.TraitCount, error) {
+	var counts []core.TraitCount
+	if err := r.db.Where("collection_id = ? AND token_count > 0", collectionID).
+		Order("trait_type").Order("value").Find(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// ListCollectionNFTs returns the live tokens of a collection with their attributes.
+func (r *Repository) ListCollectionNFTs(collectionID uint) ([]core.NFT, error) {
 	var nfts []core.NFT
-	if err := query.Find(&nfts).Error; err != nil {
+	if err := r.db.Preload("Attributes").
+		Where("collection_id = ? AND burned_at IS NULL", collectionID).Find(&nfts).Error; err != nil {
 		return nil, err
 	}
 	return nfts, nil
 }
 
+// UpdateNFTRarity sets the scores and ranks of tokens, a batch of them per
+// UPDATE joined to their values.
+func (r *Repository) UpdateNFTRarity(scores map[uint]float64, ranks map[uint]int) error {
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(ids); start += rarityBatch {
+			batch := ids[start:min(start+rarityBatch, len(ids))]
+			rows := make([]string, len(batch))
+			args := make([]interface{}, 0, 3*len(batch))
+			for i, id := range batch {
+				rows[i] = "(CAST(? AS BIGINT), CAST(? AS DOUBLE PRECISION), CAST(? AS BIGINT))"
+				args = append(args, id, scores[id], ranks[id])
+			}
+			// Typed by CAST rather than ::, which SQLite does not parse
+			if err := tx.Exec(`WITH v (nft_id, score, nft_rank) AS (VALUES `+strings.Join(rows, ", ")+`)
+				UPDATE nft SET rarity_score = v.score, rarity_rank = v.nft_rank
+				FROM v WHERE nft.id = v.nft_id`, args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimStaleRarity clears the stale rarity flag of up to limit collections
+// and returns their ids; MarkRarityStale sets it again.
+func (r *Repository) ClaimStaleRarity(limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(skipLocked).Model(&core.Collection{}).Where("rarity_stale").
+			Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Model(&core.Collection{}).Where("id IN ?", ids).Update("rarity_stale", false).Error
+	})
+	return ids, err
+}
+
+func (r *Repository) MarkRarityStale(collectionID uint) error {
+	return r.db.Model(&core.Collection{}).Where("id = ?", collectionID).Update("rarity_stale", true).Error
+}
+
+// Currency methods
+func (r *Repository) CreateCurrency(currency *core.Currency) error {
+	return conflict(r.db.Create(currency).Error, "currency_exists", "currency is already registered on this chain")
+}
+
+// EnsureCurrency creates the currency unless one with the same chain and symbol exists.
+func (r *Repository) EnsureCurrency(currency *core.Currency) error {
+	return r.db.Where(core.Currency{Chain: currency.Chain, Symbol: currency.Symbol}).FirstOrCreate(currency).Error
+}
+
+func (r *Repository) GetCurrency(chain, symbol string) (*core.Currency, error) {
+	var currency core.Currency
+	if err := r.db.Where("chain = ? AND symbol = ?", chain, symbol).First(&currency).Error; err != nil {
+		return nil, notFound(err, "currency")
+	}
+	return &currency, nil
+}
+
+func (r *Repository) ListCurrencies(chain string) ([]core.Currency, error) {
+	query := r.db.Model(&core.Currency{})
+	if chain != "" {
+		query = query.Where("chain = ?", chain)
+	}
+	var currencies []core.Currency
+	if err := query.Order("chain").Order("symbol").Find(&currencies).Error; err != nil {
+		return nil, err
+	}
+	return currencies, nil
+}
+
+func (r *Repository) SetCurrencyEnabled(id uint, enabled bool) (*core.Currency, error) {
+	var currency core.Currency
+	if err := r.db.First(&currency, id).Error; err != nil {
+		return nil, notFound(err, "currency")
+	}
+	if err := r.db.Model(&currency).Update("enabled", enabled).Error; err != nil {
+		return nil, err
+	}
+	return &currency, nil
+}
+
 // Listing methods
 func (r *Repository) CreateListing(listing *core.Listing) error {
-	return r.db.Create(listing).Error
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		if err := tx.Create(listing).Error; err != nil {
+			return err
+		}
+		e, err := listingEvent(tx, core.EventListingCreated, listing, 0, nil)
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
 }
 
 func (r *Repository) GetListingByID(id uint) (*core.Listing, error) {
 	var listing core.Listing
 	if err := r.db.First(&listing, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err, "listing")
 	}
 	return &listing, nil
 }
 
-func (r *Repository) ListActiveListings() ([]core.Listing, error) {
-	var listings []core.Listing
-	if err := r.db.Preload("NFT").Preload("Seller").Where("status = ?", core.ListingActive).Find(&listings).Error; err != nil {
-		return nil, err
+func (r *Repository) ListActiveListings(filter core.ListingFilter, page core.PageRequest) (*core.Page[core.Listing], error) {
+	statuses := []core.ListingStatus{core.ListingActive}
+	if filter.IncludeInvalid {
+		statuses = openListingStatuses
+	}
+	query := r.db.Model(&core.Listing{}).Preload("NFT").Preload("Seller").
+		Where("listing.status IN ?", statuses).
+		Where("listing.expires_at IS NULL OR listing.expires_at > ?", time.Now())
+	if filter.MinPrice != nil {
+		query = query.Where(weiCondition(r.db, "listing.price_wei", ">="), *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where(weiCondition(r.db, "listing.price_wei", "<="), *filter.MaxPrice)
+	}
+	if filter.Currency != "" {
+		query = query.Where("listing.currency = ?", filter.Currency)
 	}
-	return listings, nil
+	if filter.SellerID != 0 {
+		query = query.Where("listing.seller_user_id = ?", filter.SellerID)
+	}
+	if filter.CollectionID != 0 || filter.Chain != "" {
+		nfts := r.db.Model(&core.NFT{}).Select("id")
+		if filter.CollectionID != 0 {
+			nfts = nfts.Where("collection_id = ?", filter.CollectionID)
+		}
+		if filter.Chain != "" {
+			nfts = nfts.Where("chain = ?", filter.Chain)
+		}
+		query = query.Where("listing.nft_id IN (?)", nfts)
+	}
+	return listingQuery.find(query, page)
 }
 
-func (r *Repository) UpdateListingStatus(id uint, status core.ListingStatus) error {
-	return r.db.Model(&core.Listing{}).Where("id = ?", id).Update("status", status).Error
+// listingStatusEvents are the events recorded when a listing enters a status.
+var listingStatusEvents = map[core.ListingStatus]core.EventType{
+	core.ListingCancelled: core.EventListingCancelled,
+	core.ListingSold:      core.EventListingSold,
+}
+
+// UpdateListingStatus moves listing, as it was read, to status. It fails
+// with concurrent_update when the listing changed since, so it only ever
+// leaves the status the change was decided from.
+func (r *Repository) UpdateListingStatus(listing *core.Listing, status core.ListingStatus) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		res := tx.Model(&core.Listing{}).
+			Where("id = ? AND status = ? AND version = ?", listing.ID, listing.Status, listing.Version).
+			Updates(bumpVersion(map[string]interface{}{"status": status}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return concurrentUpdate("listing")
+		}
+		listing.Status = status
+		listing.Version++
+
+		t, ok := listingStatusEvents[status]
+		if !ok {
+			return nil
+		}
+		e, err := listingEvent(tx, t, listing, 0, nil)
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
 }
 
 // Order methods
-func (r *Repository) CreateOrder(order *core.Order) error {
-	return r.db.Create(order).Error
+// CreateOrder creates a pending order. With reservedUntil set it also
+// reserves the active listing for the order until then, in one conditional
+// update, and loads it into the order; while another order holds the
+// listing it fails with listing_reserved. Pending orders whose reservation
+// of the listing ran out are expired, as the new order takes it over.
+func (r *Repository) CreateOrder(order *core.Order, reservedUntil *time.Time) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		var data map[string]interface{}
+		if reservedUntil != nil {
+			if err := reserveListing(tx, order, *reservedUntil); err != nil {
+				return err
+			}
+			if err := expireSuperseded(tx, events, order); err != nil {
+				return err
+			}
+			data = map[string]interface{}{"reserved_until": *reservedUntil}
+		}
+		e, err := orderEvent(tx, core.EventOrderCreated, order, data)
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
+}
+
+func reserveListing(tx *gorm.DB, order *core.Order, until time.Time) error {
+	res := tx.Model(&core.Listing{}).
+		Where("id = ? AND status = ? AND (reserved_until IS NULL OR reserved_until <= ?)", order.ListingID, core.ListingActive, time.Now()).
+		Updates(bumpVersion(map[string]interface{}{"reserved_by_order_id": order.ID, "reserved_until": until}))
+	if res.Error != nil {
+		return res.Error
+	}
+	if err := tx.First(&order.Listing, order.ListingID).Error; err != nil {
+		return notFound(err, "listing")
+	}
+	if res.RowsAffected == 1 {
+		return nil
+	}
+	if order.Listing.Status != core.ListingActive {
+		return core.InvalidState("listing_not_active", "listing is not active")
+	}
+	return core.ListingReserved(*order.Listing.ReservedUntil)
+}
+
+// expireSuperseded expires the other pending orders of the listing order has
+// just reserved, so that they cannot be paid for once it is sold.
+func expireSuperseded(tx *gorm.DB, events *eventLog, order *core.Order) error {
+	var superseded []core.Order
+	if err := tx.Where("listing_id = ? AND status = ? AND id <> ?", order.ListingID, core.OrderPending, order.ID).
+		Order("id").Find(&superseded).Error; err != nil {
+		return err
+	}
+	for i := range superseded {
+		o := &superseded[i]
+		res := tx.Model(&core.Order{}).Where("id = ? AND version = ?", o.ID, o.Version).
+			Updates(bumpVersion(map[string]interface{}{"status": core.OrderExpired}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return concurrentUpdate("order")
+		}
+		o.Status = core.OrderExpired
+		e, err := orderEvent(tx, core.EventOrderExpired, o, map[string]interface{}{"superseded_by_order_id": order.ID})
+		if err != nil {
+			return err
+		}
+		if err := events.record(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseReservation frees the listing reserved by an order, if any.
+func releaseReservation(tx *gorm.DB, orderID uint) error {
+	return tx.Model(&core.Listing{}).Where("reserved_by_order_id = ?", orderID).
+		Updates(bumpVersion(map[string]interface{}{"reserved_by_order_id": nil, "reserved_until": nil})).Error
 }
 
 func (r *Repository) GetOrderByID(id uint) (*core.Order, error) {
 	var order core.Order
 	if err := r.db.First(&order, id).Error; err != nil {
-		return nil, err
+		return nil, notFound(err, "order")
 	}
 	return &order, nil
 }
 
+// ConfirmOrder marks the order confirmed and its listing sold and transfers
+// the NFT to the buyer, recording an event for each. Orders and listings past
+// their expiry cannot be confirmed, even before they are moved to EXPIRED.
 func (r *Repository) ConfirmOrder(orderID uint, txHash string) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
 		var order core.Order
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&order, orderID).Error; err != nil {
-			return err
+		if err := tx.Clauses(forUpdate).First(&order, orderID).Error; err != nil {
+			return notFound(err, "order")
 		}
 
+		now := time.Now()
+		if order.Status == core.OrderExpired || order.Status == core.OrderPending && order.IsExpired(now) {
+			return core.InvalidState("order_expired", "order has expired")
+		}
 		if order.Status != core.OrderPending {
-			return gorm.ErrInvalidData
+			return core.InvalidState("order_not_pending", "order is not pending")
 		}
 
 		var listing core.Listing
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&listing, order.ListingID).Error; err != nil {
-			return err
+		if err := tx.Clauses(forUpdate).First(&listing, order.ListingID).Error; err != nil {
+			return notFound(err, "listing")
 		}
 
-		if listing.Status != core.ListingActive {
-			return gorm.ErrInvalidData
+		if listing.Status == core.ListingExpired || listing.IsExpired(now) {
+			return core.InvalidState("listing_expired", "listing has expired")
+		}
+		if !listing.IsOpen() {
+			return core.InvalidState("listing_not_active", "listing is not active")
+		}
+		if listing.ReservedByOther(order.ID, now) {
+			return core.ListingReserved(*listing.ReservedUntil)
 		}
 
+		// The rows are locked, but the version checks keep a backend that
+		// cannot lock them from confirming twice.
 		// Update order
-		if err := tx.Model(&order).Updates(map[string]interface{}{
-			"status":  core.OrderConfirmed,
-			"tx_hash": txHash,
-		}).Error; err != nil {
-			return err
+		res := tx.Model(&core.Order{}).Where("id = ? AND version = ?", order.ID, order.Version).
+			Updates(bumpVersion(map[string]interface{}{
+				"status":  core.OrderConfirmed,
+				"tx_hash": txHash,
+			}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return concurrentUpdate("order")
 		}
 
 		// Update listing
-		if err := tx.Model(&listing).Update("status", core.ListingSold).Error; err != nil {
-			return err
+		res = tx.Model(&core.Listing{}).Where("id = ? AND version = ?", listing.ID, listing.Version).
+			Updates(bumpVersion(map[string]interface{}{
+				"status":               core.ListingSold,
+				"reserved_by_order_id": nil,
+				"reserved_until":       nil,
+			}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return concurrentUpdate("listing")
 		}
 
 		// Transfer NFT ownership
-		if err := tx.Model(&core.NFT{}).Where("id = ?", listing.NFTID).Update("owner_user_id", order.BuyerUserID).Error; err != nil {
+		if err := tx.Model(&core.NFT{}).Where("id = ?", listing.NFTID).
+			Updates(bumpVersion(map[string]interface{}{"owner_user_id": order.BuyerUserID})).Error; err != nil {
 			return err
 		}
 
+		for _, t := range []core.EventType{core.EventOrderConfirmed, core.EventListingSold, core.EventTransferIndexed} {
+			e, err := orderEvent(tx, t, &order, map[string]interface{}{"tx_hash": txHash})
+			if err != nil {
+				return err
+			}
+			if err := events.record(e); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 }
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Chain intent methods
+
+// CreateIntent stores a new intent, leased to its creator for lease. A burn
+// is refused while another burn of the same NFT is open.
+func (r *Repository) CreateIntent(intent *core.ChainIntent, lease time.Duration) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if intent.Kind == core.IntentBurn && intent.NFTID != nil {
+			var open int64
+			if err := tx.Model(&core.ChainIntent{}).
+				Where("kind = ? AND nft_id = ? AND status IN ?", core.IntentBurn, *intent.NFTID, openIntentStatuses).
+				Count(&open).Error; err != nil {
+				return err
+			}
+			if open > 0 {
+				return core.Conflict("burn_pending", "a burn of this nft is already in progress")
+			}
+		}
+		lockedUntil := time.Now().Add(lease)
+		if intent.Status == "" {
+			intent.Status = core.IntentPending
+		}
+		intent.LockedUntil = &lockedUntil
+		return tx.Create(intent).Error
+	})
+}
+
+var openIntentStatuses = []core.IntentStatus{core.IntentPending, core.IntentSent}
+
+func (r *Repository) GetIntent(userID, id uint) (*core.ChainIntent, error) {
+	var intent core.ChainIntent
+	if err := r.db.Where("user_id = ?", userID).First(&intent, id).Error; err != nil {
+		return nil, notFound(err, "intent")
+	}
+	return &intent, nil
+}
+
+// ClaimIntents leases up to limit open intents, oldest first, that no worker
+// holds. Intents whose worker died are claimed again once the lease runs out.
+func (r *Repository) ClaimIntents(limit int, lease time.Duration) ([]core.ChainIntent, error) {
+	var intents []core.ChainIntent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(skipLocked).
+			Where("status IN ? AND (locked_until IS NULL OR locked_until < ?)", openIntentStatuses, now).
+			Order("id").Limit(limit).Find(&intents).Error; err != nil {
+			return err
+		}
+		if len(intents) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(intents))
+		for i := range intents {
+			ids[i] = intents[i].ID
+		}
+		return tx.Model(&core.ChainIntent{}).Where("id IN ?", ids).Update("locked_until", now.Add(lease)).Error
+	})
+	return intents, err
+}
+
+// ReleaseIntent gives up the lease on an intent so any worker may pick it up.
+func (r *Repository) ReleaseIntent(id uint) error {
+	return r.db.Model(&core.ChainIntent{}).Where("id = ?", id).Update("locked_until", nil).Error
+}
+
+// SaveIntentTx stores the signed transaction of a pending intent; it must be
+// saved before the transaction is broadcast.
+func (r *Repository) SaveIntentTx(intent *core.ChainIntent, txHash, rawTx string) error {
+	res := r.db.Model(&core.ChainIntent{}).Where("id = ? AND status = ?", intent.ID, core.IntentPending).
+		Updates(map[string]interface{}{"status": core.IntentSent, "tx_hash": txHash, "raw_tx": rawTx})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return core.InvalidState("intent_not_pending", "intent is not pending")
+	}
+	intent.Status, intent.TxHash, intent.RawTx = core.IntentSent, txHash, rawTx
+	return nil
+}
+
+// ResetIntent returns an intent whose transaction can never be mined to
+// pending, to be signed again.
+func (r *Repository) ResetIntent(intent *core.ChainIntent, reason string) error {
+	intent.Status, intent.TxHash, intent.RawTx, intent.Error = core.IntentPending, "", "", reason
+	return r.db.Model(&core.ChainIntent{}).Where("id = ?", intent.ID).
+		Updates(map[string]interface{}{"status": intent.Status, "tx_hash": "", "raw_tx": "", "error": reason}).Error
+}
+
+// RecordIntentAttempt counts a failed attempt at an intent and its error.
+func (r *Repository) RecordIntentAttempt(intent *core.ChainIntent, reason string) error {
+	intent.Attempts++
+	intent.Error = reason
+	return r.db.Model(&core.ChainIntent{}).Where("id = ?", intent.ID).
+		Updates(map[string]interface{}{"attempts": intent.Attempts, "error": reason}).Error
+}
+
+func (r *Repository) FailIntent(intent *core.ChainIntent, reason string) error {
+	intent.Status, intent.Error = core.IntentFailed, reason
+	return r.db.Model(&core.ChainIntent{}).Where("id = ?", intent.ID).
+		Updates(map[string]interface{}{"status": core.IntentFailed, "error": reason, "locked_until": nil}).Error
+}
+
+// ConfirmMintIntent registers the NFT minted by a mined mint intent with
+// tokenID and marks the intent confirmed. An intent no longer awaiting its
+// transaction is refused, so a mint is never registered twice.
+func (r *Repository) ConfirmMintIntent(intent *core.ChainIntent, contract, chain, tokenID string) (*core.NFT, error) {
+	mint := intent.Mint
+	nft := &core.NFT{
+		TokenID:         tokenID,
+		ContractAddress: contract,
+		Chain:           chain,
+		CollectionID:    mint.CollectionID,
+		OwnerUserID:     intent.UserID,
+		Name:            mint.Name,
+		Description:     mint.Description,
+		MetadataURL:     mint.TokenURI,
+		Attributes:      mint.Attributes,
+	}
+	err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		if err := confirmIntent(tx, intent); err != nil {
+			return err
+		}
+		if err := tx.Create(nft).Error; err != nil {
+			return conflict(err, "nft_exists", "nft is already registered")
+		}
+		if err := adjustTraitCounts(tx, nft.CollectionID, nft.Attributes, 1); err != nil {
+			return err
+		}
+		intent.NFTID = &nft.ID
+		if err := tx.Model(&core.ChainIntent{}).Where("id = ?", intent.ID).Update("nft_id", nft.ID).Error; err != nil {
+			return err
+		}
+		return events.record(txMinedEvent(intent, nft, "mint"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nft, nil
+}
+
+// ConfirmBurnIntent burns the NFT of a mined burn intent and marks the
+// intent confirmed.
+func (r *Repository) ConfirmBurnIntent(intent *core.ChainIntent) (*core.NFT, error) {
+	var nft core.NFT
+	err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		if err := confirmIntent(tx, intent); err != nil {
+			return err
+		}
+		if err := burnNFT(tx, events, *intent.NFTID); err != nil {
+			return err
+		}
+		if err := tx.First(&nft, *intent.NFTID).Error; err != nil {
+			return err
+		}
+		return events.record(txMinedEvent(intent, &nft, "burn"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &nft, nil
+}
+
+// confirmIntent marks a sent intent confirmed, failing if another worker
+// got there first.
+func confirmIntent(tx *gorm.DB, intent *core.ChainIntent) error {
+	res := tx.Model(&core.ChainIntent{}).Where("id = ? AND status = ?", intent.ID, core.IntentSent).
+		Updates(map[string]interface{}{"status": core.IntentConfirmed, "error": "", "locked_until": nil})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return core.InvalidState("intent_not_sent", "intent is not awaiting its transaction")
+	}
+	intent.Status, intent.Error = core.IntentConfirmed, ""
+	return nil
+}
+
+func txMinedEvent(intent *core.ChainIntent, nft *core.NFT, action string) *core.Event {
+	return &core.Event{
+		Type:         core.EventTxMined,
+		CollectionID: nft.CollectionID,
+		NFTID:        nft.ID,
+		UserID:       nft.OwnerUserID,
+		Data: map[string]interface{}{
+			"tx_hash":   intent.TxHash,
+			"action":    action,
+			"token_id":  nft.TokenID,
+			"intent_id": intent.ID,
+		},
+	}
+}
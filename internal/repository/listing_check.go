@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// openListingStatuses are the statuses of listings that can still be sold
+// or cancelled.
+var openListingStatuses = []core.ListingStatus{core.ListingActive, core.ListingInvalid}
+
+// ListChainListings returns up to limit open listings of NFTs of contract on
+// chain with ids after afterID, oldest first, with their NFTs and sellers.
+func (r *Repository) ListChainListings(chain, contract string, afterID uint, limit int) ([]core.Listing, error) {
+	var listings []core.Listing
+	err := r.db.Preload("NFT").Preload("Seller").
+		Joins("JOIN nft ON nft.id = listing.nft_id").
+		Where("listing.status IN ? AND listing.id > ? AND nft.chain = ? AND LOWER(nft.contract_address) = LOWER(?)",
+			openListingStatuses, afterID, chain, contract).
+		Order("listing.id").Limit(limit).Find(&listings).Error
+	return listings, err
+}
+
+// SetListingValidity records the outcome of checking an open listing: INVALID
+// for reason, or ACTIVE when reason is empty. An event is recorded when the
+// status or reason changes. The listing is updated in place; it is left
+// alone when it was sold or cancelled since it was read.
+func (r *Repository) SetListingValidity(listing *core.Listing, reason string) error {
+	return r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		var current core.Listing
+		if err := tx.First(&current, listing.ID).Error; err != nil {
+			return notFound(err, "listing")
+		}
+		if !current.IsOpen() {
+			listing.Status = current.Status
+			return nil
+		}
+
+		status := core.ListingActive
+		if reason != "" {
+			status = core.ListingInvalid
+		}
+		now := time.Now()
+		updates := map[string]interface{}{"status": status, "invalid_reason": reason, "checked_at": now}
+		if status != current.Status || reason != current.InvalidReason {
+			// Checks that change nothing leave the version alone
+			bumpVersion(updates)
+		}
+		res := tx.Model(&core.Listing{}).Where("id = ? AND version = ?", current.ID, current.Version).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// Sold or cancelled meanwhile; the next check sees it
+			return nil
+		}
+		listing.Status, listing.InvalidReason, listing.CheckedAt = status, reason, &now
+
+		var t core.EventType
+		switch {
+		case status == core.ListingInvalid && (current.Status != status || current.InvalidReason != reason):
+			t = core.EventListingInvalidated
+		case status == core.ListingActive && current.Status == core.ListingInvalid:
+			t = core.EventListingRestored
+		default:
+			return nil
+		}
+		var data map[string]interface{}
+		if reason != "" {
+			data = map[string]interface{}{"reason": reason}
+		}
+		e, err := listingEvent(tx, t, &current, 0, data)
+		if err != nil {
+			return err
+		}
+		return events.record(e)
+	})
+}
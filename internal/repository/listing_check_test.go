@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// A listing goes INVALID and back to ACTIVE with one event per change;
+// checks that change nothing record none.
+func TestSetListingValidity(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+		filter := core.EventFilter{Types: []core.EventType{core.EventListingInvalidated, core.EventListingRestored}}
+
+		steps := []struct {
+			reason string
+			status core.ListingStatus
+			events []core.EventType
+		}{
+			{"", core.ListingActive, nil},
+			{"seller no longer owns the token", core.ListingInvalid, []core.EventType{core.EventListingInvalidated}},
+			{"seller no longer owns the token", core.ListingInvalid, []core.EventType{core.EventListingInvalidated}},
+			{"", core.ListingActive, []core.EventType{core.EventListingInvalidated, core.EventListingRestored}},
+		}
+		for i, step := range steps {
+			if err := s.SetListingValidity(listing, step.reason); err != nil {
+				t.Fatalf("step %d: %v", i, err)
+			}
+			stored, err := s.GetListingByID(listing.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != step.status || stored.InvalidReason != step.reason || listing.Status != step.status {
+				t.Fatalf("step %d: listing %s (%q), want %s (%q)", i, stored.Status, stored.InvalidReason, step.status, step.reason)
+			}
+			events, err := s.ListEvents(filter, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != len(step.events) {
+				t.Fatalf("step %d: %d events, want %v", i, len(events), step.events)
+			}
+			for j, e := range events {
+				if e.Type != step.events[j] {
+					t.Fatalf("step %d: event %d is %s, want %s", i, j, e.Type, step.events[j])
+				}
+			}
+		}
+
+		// A cancelled listing is no longer checked
+		stored, err := s.GetListingByID(listing.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateListingStatus(stored, core.ListingCancelled); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetListingValidity(listing, "seller no longer owns the token"); err != nil {
+			t.Fatal(err)
+		}
+		if listing.Status != core.ListingCancelled {
+			t.Fatalf("cancelled listing checked as %s", listing.Status)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Locking. Listings, orders and NFTs carry a version that every change to
+// their state increments. Changes are conditional updates on the state they
+// were decided from, the status and, where the row was read earlier, its
+// version, so of two racing changes the second affects no rows instead of
+// overwriting the first.
+//
+// SQLite ignores the row locks below: there transactions run one at a time
+// and take the write lock when they begin, which guards what the row locks
+// guard and more.
+
+// forUpdate locks the rows read until the transaction ends.
+var forUpdate = clause.Locking{Strength: "UPDATE"}
+
+// skipLocked makes concurrent workers on other replicas pass over the rows
+// one is already handling.
+var skipLocked = clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}
+
+// bumpVersion adds the version increment to the column updates of a change.
+func bumpVersion(updates map[string]interface{}) map[string]interface{} {
+	updates["version"] = gorm.Expr("version + 1")
+	return updates
+}
+
+// concurrentUpdate reports a row that changed since it was read.
+func concurrentUpdate(what string) error {
+	return core.Conflict("concurrent_update", what+" was changed by another request")
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+const buyerWallet = "0x00000000000000000000000000000000000000b2"
+
+func TestUsersByWallet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		user := seedUser(t, s, "0x00000000000000000000000000000000000000Ab")
+
+		// Wallets are unique in any letter case
+		again := seedUser(t, s, "0x00000000000000000000000000000000000000aB")
+		if again.ID != user.ID {
+			t.Fatalf("created user %d for a wallet user %d has", again.ID, user.ID)
+		}
+		found, err := s.GetUserByWallet("0x00000000000000000000000000000000000000AB")
+		if err != nil || found.ID != user.ID {
+			t.Fatalf("GetUserByWallet: user %v, err %v", found, err)
+		}
+		if _, err := s.GetUserByID(user.ID + 1); !errors.Is(err, core.NotFound("user")) {
+			t.Fatalf("GetUserByID of a missing user: %v", err)
+		}
+	})
+}
+
+func TestCreateNFT(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		nft := seedNFT(t, s, collection, owner, 1, "Color", "Red")
+		seedNFT(t, s, collection, owner, 2, "Color", "Red", "Hat", "Cap")
+
+		got, err := s.GetNFTByID(nft.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Attributes) != 1 || got.Attributes[0].TraitType != "Color" || got.Attributes[0].Value != "Red" {
+			t.Fatalf("attributes %+v, want Color Red", got.Attributes)
+		}
+
+		counts, err := s.ListTraitCounts(collection.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byTrait := make(map[string]int)
+		for _, c := range counts {
+			byTrait[c.TraitType+":"+c.Value] = c.TokenCount
+		}
+		// Tokens are also counted by how many traits they have
+		want := map[string]int{"Color:Red": 2, "Hat:Cap": 1, core.TraitCountType + ":1": 1, core.TraitCountType + ":2": 1}
+		if !reflect.DeepEqual(byTrait, want) {
+			t.Fatalf("trait counts %v, want %v", byTrait, want)
+		}
+
+		dup := &core.NFT{TokenID: "1", ContractAddress: nft.ContractAddress, Chain: nft.Chain,
+			CollectionID: collection.ID, OwnerUserID: owner.ID}
+		if err := s.CreateNFT(dup); !errors.Is(err, core.Conflict("nft_exists", "")) {
+			t.Fatalf("registering a token twice: %v", err)
+		}
+	})
+}
+
+func TestCurrencies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		eth := &core.Currency{Symbol: "ETH", Chain: "test", Decimals: 18, Enabled: true}
+		if err := s.EnsureCurrency(eth); err != nil {
+			t.Fatal(err)
+		}
+		again := &core.Currency{Symbol: "ETH", Chain: "test", Decimals: 18, Enabled: true}
+		if err := s.EnsureCurrency(again); err != nil || again.ID != eth.ID {
+			t.Fatalf("EnsureCurrency again: id %d, err %v; want id %d", again.ID, err, eth.ID)
+		}
+		dup := &core.Currency{Symbol: "ETH", Chain: "test", Decimals: 18}
+		if err := s.CreateCurrency(dup); !errors.Is(err, core.Conflict("currency_exists", "")) {
+			t.Fatalf("CreateCurrency of a registered currency: %v", err)
+		}
+
+		if _, err := s.SetCurrencyEnabled(eth.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.GetCurrency("test", "ETH")
+		if err != nil || got.Enabled {
+			t.Fatalf("GetCurrency after disabling: %+v, err %v", got, err)
+		}
+		if _, err := s.GetCurrency("test", "USDC"); !errors.Is(err, core.ErrNotFound) {
+			t.Fatalf("GetCurrency of a missing currency: %v", err)
+		}
+	})
+}
+
+func TestUpdateListingStatusFromStaleRead(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+		stale := *listing
+
+		if err := s.UpdateListingStatus(listing, core.ListingCancelled); err != nil {
+			t.Fatal(err)
+		}
+		if listing.Status != core.ListingCancelled || listing.Version != stale.Version+1 {
+			t.Fatalf("listing %s version %d after cancelling", listing.Status, listing.Version)
+		}
+		if err := s.UpdateListingStatus(&stale, core.ListingSold); !errors.Is(err, core.Conflict("concurrent_update", "")) {
+			t.Fatalf("updating a stale listing: %v", err)
+		}
+		got, _ := s.GetListingByID(listing.ID)
+		if got.Status != core.ListingCancelled {
+			t.Fatalf("listing %s after the stale update, want CANCELLED", got.Status)
+		}
+	})
+}
+
+func TestCreateOrderReservesListing(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+
+		until := time.Now().Add(time.Minute).Truncate(time.Second)
+		order := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+		if err := s.CreateOrder(order, &until); err != nil {
+			t.Fatal(err)
+		}
+		if id := order.Listing.ReservedByOrderID; id == nil || *id != order.ID {
+			t.Fatalf("listing reserved by %v, want order %d", id, order.ID)
+		}
+
+		err := s.CreateOrder(&core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}, &until)
+		var coreErr *core.Error
+		if !errors.As(err, &coreErr) || coreErr.Code != "listing_reserved" || !coreErr.RetryAt.Equal(until) {
+			t.Fatalf("ordering a reserved listing: %v", err)
+		}
+
+		// The reservation stops holding the listing once it runs out
+		if n, err := s.ReleaseReservations(until); err != nil || n != 1 {
+			t.Fatalf("ReleaseReservations: %d, %v", n, err)
+		}
+		later := until.Add(time.Minute)
+		if err := s.CreateOrder(&core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}, &later); err != nil {
+			t.Fatalf("ordering a released listing: %v", err)
+		}
+	})
+}
+
+func TestConfirmOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		nft := seedNFT(t, s, collection, owner, 1)
+		listing := seedListing(t, s, nft, 100)
+		until := time.Now().Add(time.Minute)
+		order := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+		if err := s.CreateOrder(order, &until); err != nil {
+			t.Fatal(err)
+		}
+
+		const txHash = "0x01"
+		if err := s.ConfirmOrder(order.ID, txHash); err != nil {
+			t.Fatal(err)
+		}
+		gotOrder, _ := s.GetOrderByID(order.ID)
+		if gotOrder.Status != core.OrderConfirmed || gotOrder.TxHash == nil || *gotOrder.TxHash != txHash {
+			t.Fatalf("order %s with hash %v after confirming", gotOrder.Status, gotOrder.TxHash)
+		}
+		gotListing, _ := s.GetListingByID(listing.ID)
+		if gotListing.Status != core.ListingSold || gotListing.ReservedByOrderID != nil {
+			t.Fatalf("listing %s reserved by %v after confirming", gotListing.Status, gotListing.ReservedByOrderID)
+		}
+		gotNFT, _ := s.GetNFTByID(nft.ID)
+		if gotNFT.OwnerUserID != buyer.ID {
+			t.Fatalf("nft owned by %d after confirming, want the buyer %d", gotNFT.OwnerUserID, buyer.ID)
+		}
+
+		events, err := s.ListEvents(core.EventFilter{NFTID: nft.ID}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []core.EventType
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		want := []core.EventType{core.EventListingCreated, core.EventOrderCreated,
+			core.EventOrderConfirmed, core.EventListingSold, core.EventTransferIndexed}
+		if !reflect.DeepEqual(types, want) {
+			t.Fatalf("events %v, want %v", types, want)
+		}
+
+		if err := s.ConfirmOrder(order.ID, txHash); !errors.Is(err, core.InvalidState("order_not_pending", "")) {
+			t.Fatalf("confirming twice: %v", err)
+		}
+	})
+}
+
+// A confirmation refused for the listing's state changes nothing.
+func TestConfirmOrderOfCancelledListing(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		nft := seedNFT(t, s, collection, owner, 1)
+		listing := seedListing(t, s, nft, 100)
+		order := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+		if err := s.CreateOrder(order, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateListingStatus(listing, core.ListingCancelled); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.ConfirmOrder(order.ID, "0x01"); !errors.Is(err, core.InvalidState("listing_not_active", "")) {
+			t.Fatalf("confirming an order of a cancelled listing: %v", err)
+		}
+		gotOrder, _ := s.GetOrderByID(order.ID)
+		gotNFT, _ := s.GetNFTByID(nft.ID)
+		if gotOrder.Status != core.OrderPending || gotOrder.TxHash != nil || gotNFT.OwnerUserID != owner.ID {
+			t.Fatalf("order %s with hash %v, nft owned by %d after the refused confirmation",
+				gotOrder.Status, gotOrder.TxHash, gotNFT.OwnerUserID)
+		}
+	})
+}
+
+func TestExpireOrdersReleasesReservation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		listing := seedListing(t, s, seedNFT(t, s, collection, owner, 1), 100)
+		expires := time.Now().Add(time.Minute)
+		order := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID, ExpiresAt: &expires}
+		if err := s.CreateOrder(order, &expires); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := s.ExpireOrders(expires.Add(time.Second)); err != nil || n != 1 {
+			t.Fatalf("ExpireOrders: %d, %v", n, err)
+		}
+		gotOrder, _ := s.GetOrderByID(order.ID)
+		gotListing, _ := s.GetListingByID(listing.ID)
+		if gotOrder.Status != core.OrderExpired || gotListing.ReservedByOrderID != nil || gotListing.ReservedUntil != nil {
+			t.Fatalf("order %s, listing reserved by %v until %v after expiring",
+				gotOrder.Status, gotListing.ReservedByOrderID, gotListing.ReservedUntil)
+		}
+		if err := s.ConfirmOrder(order.ID, "0x01"); !errors.Is(err, core.InvalidState("order_expired", "")) {
+			t.Fatalf("confirming an expired order: %v", err)
+		}
+	})
+}
+
+// An order taking over a listing whose reservation ran out expires the order
+// that held it, so that it can no longer be paid for.
+func TestCreateOrderExpiresSupersededOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		buyer := seedUser(t, s, buyerWallet)
+		nft := seedNFT(t, s, collection, owner, 1)
+		listing := seedListing(t, s, nft, 100)
+
+		lapsed := time.Now().Add(-time.Second)
+		first := &core.Order{ListingID: listing.ID, BuyerUserID: buyer.ID}
+		if err := s.CreateOrder(first, &lapsed); err != nil {
+			t.Fatal(err)
+		}
+		until := time.Now().Add(time.Minute)
+		second := &core.Order{ListingID: listing.ID, BuyerUserID: owner.ID}
+		if err := s.CreateOrder(second, &until); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := s.GetOrderByID(first.ID); got.Status != core.OrderExpired {
+			t.Fatalf("superseded order %s, want EXPIRED", got.Status)
+		}
+		if err := s.ConfirmOrder(first.ID, "0x01"); !errors.Is(err, core.InvalidState("order_expired", "")) {
+			t.Fatalf("confirming a superseded order: %v", err)
+		}
+		events, err := s.ListEvents(core.EventFilter{NFTID: nft.ID}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []core.EventType
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		want := []core.EventType{core.EventListingCreated, core.EventOrderCreated, core.EventOrderExpired, core.EventOrderCreated}
+		if !reflect.DeepEqual(types, want) {
+			t.Fatalf("events %v, want %v", types, want)
+		}
+		if err := s.ConfirmOrder(second.ID, "0x02"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,865 @@
+package repository
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// MemoryRepository keeps the marketplace in process memory, for unit tests
+// and the demo mode; nothing survives a restart. Every method holds one lock
+// throughout and checks all it depends on before changing anything, so it is
+// as atomic as the transaction the SQL repository runs it in, and the version
+// checks that guard against concurrent updates there always pass here.
+type MemoryRepository struct {
+	mu     sync.Mutex
+	events EventPublisher
+	lastID map[string]uint
+
+	users       map[uint]core.User
+	collections map[uint]core.Collection
+	nfts        map[uint]core.NFT
+	traitCounts map[traitKey]core.TraitCount
+	currencies  map[uint]core.Currency
+	listings    map[uint]core.Listing
+	orders      map[uint]core.Order
+	intents     map[uint]core.ChainIntent
+	runs        map[uint]core.ReconciliationRun
+	drifts      map[uint]core.OwnershipDrift
+	auditLog    map[uint]core.AuditLog
+	nonces      map[uint]core.AuthNonce
+	sessions    map[uint]core.Session
+	apiKeys     map[uint]core.APIKey
+	apiKeyUsage map[usageKey]core.APIKeyUsage
+	eventLog    map[uint]core.Event
+	webhooks    map[uint]core.Webhook
+	deliveries  map[uint]core.WebhookDelivery
+	attempts    map[uint]core.WebhookAttempt
+}
+
+type traitKey struct {
+	collectionID     uint
+	traitType, value string
+}
+
+type usageKey struct {
+	apiKeyID uint
+	day      time.Time
+}
+
+// NewMemoryRepository returns an empty repository that publishes the events
+// it records to events, which may be nil.
+func NewMemoryRepository(events EventPublisher) *MemoryRepository {
+	return &MemoryRepository{
+		events:      events,
+		lastID:      make(map[string]uint),
+		users:       make(map[uint]core.User),
+		collections: make(map[uint]core.Collection),
+		nfts:        make(map[uint]core.NFT),
+		traitCounts: make(map[traitKey]core.TraitCount),
+		currencies:  make(map[uint]core.Currency),
+		listings:    make(map[uint]core.Listing),
+		orders:      make(map[uint]core.Order),
+		intents:     make(map[uint]core.ChainIntent),
+		runs:        make(map[uint]core.ReconciliationRun),
+		drifts:      make(map[uint]core.OwnershipDrift),
+		auditLog:    make(map[uint]core.AuditLog),
+		nonces:      make(map[uint]core.AuthNonce),
+		sessions:    make(map[uint]core.Session),
+		apiKeys:     make(map[uint]core.APIKey),
+		apiKeyUsage: make(map[usageKey]core.APIKeyUsage),
+		eventLog:    make(map[uint]core.Event),
+		webhooks:    make(map[uint]core.Webhook),
+		deliveries:  make(map[uint]core.WebhookDelivery),
+		attempts:    make(map[uint]core.WebhookAttempt),
+	}
+}
+
+// nextID returns the next id of table; like a sequence, ids taken by a
+// failed change are not reused.
+func (m *MemoryRepository) nextID(table string) uint {
+	m.lastID[table]++
+	return m.lastID[table]
+}
+
+// memoryLog collects the events of a change, logged only if it succeeds.
+type memoryLog struct {
+	recorded []*core.Event
+}
+
+func (l *memoryLog) record(events ...*core.Event) {
+	l.recorded = append(l.recorded, events...)
+}
+
+// transaction runs fn under the lock, logs the events it recorded if it
+// succeeds and publishes them once the lock is released.
+func (m *MemoryRepository) transaction(fn func(events *memoryLog) error) error {
+	m.mu.Lock()
+	events := &memoryLog{}
+	err := fn(events)
+	var recorded []core.Event
+	if err == nil {
+		now := time.Now()
+		for _, e := range events.recorded {
+			e.ID = m.nextID("event")
+			e.CreatedAt = now
+			m.eventLog[e.ID] = *e
+			recorded = append(recorded, *e)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(recorded) > 0 && m.events != nil {
+		m.events.Publish(recorded...)
+	}
+	return err
+}
+
+// sortedByID returns the values of rows in id order.
+func sortedByID[T any](rows map[uint]T) []T {
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	values := make([]T, len(ids))
+	for i, id := range ids {
+		values[i] = rows[id]
+	}
+	return values
+}
+
+// compare orders two values of the key as the database does.
+func (k sortKey[T]) compare(a, b string) (int, error) {
+	switch k.kind {
+	case kindNumeric, kindWei:
+		x, okA := new(big.Int).SetString(a, 10)
+		y, okB := new(big.Int).SetString(b, 10)
+		if !okA || !okB {
+			return 0, core.ErrInvalidCursor
+		}
+		return x.Cmp(y), nil
+	case kindTime:
+		x, errA := time.Parse(time.RFC3339Nano, a)
+		y, errB := time.Parse(time.RFC3339Nano, b)
+		if errA != nil || errB != nil {
+			return 0, core.ErrInvalidCursor
+		}
+		return x.Compare(y), nil
+	default:
+		return strings.Compare(a, b), nil
+	}
+}
+
+// page is find for rows held in memory, with relations the sort key reads
+// already filled in.
+func (q listQuery[T]) page(rows []T, page core.PageRequest) (*core.Page[T], error) {
+	spec, err := q.resolve(page)
+	if err != nil {
+		return nil, err
+	}
+	// order compares a row's sort value and id with another's in page order
+	order := func(value string, id uint, otherValue string, otherID uint) (int, error) {
+		c, err := spec.key.compare(value, otherValue)
+		if err != nil {
+			return 0, err
+		}
+		if c == 0 {
+			c = compareIDs(id, otherID)
+		}
+		if spec.desc {
+			c = -c
+		}
+		return c, nil
+	}
+
+	if spec.after != nil {
+		if _, err := spec.key.compare(spec.after.Value, spec.after.Value); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		c, _ := order(spec.key.value(&rows[i]), q.id(&rows[i]), spec.key.value(&rows[j]), q.id(&rows[j]))
+		return c < 0
+	})
+
+	items := make([]T, 0, spec.limit+1)
+	for i := range rows {
+		if spec.after != nil {
+			if c, _ := order(spec.key.value(&rows[i]), q.id(&rows[i]), spec.after.Value, spec.after.ID); c <= 0 {
+				continue
+			}
+		}
+		items = append(items, rows[i])
+		if len(items) > spec.limit {
+			break
+		}
+	}
+	return q.result(spec, items), nil
+}
+
+func compareIDs(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (m *MemoryRepository) Ping() error {
+	return nil
+}
+
+// User methods
+func (m *MemoryRepository) CreateUser(user *core.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.userByWallet(user.WalletAddress); ok {
+		*user = existing
+		return nil
+	}
+	m.insertUser(user)
+	return nil
+}
+
+// userByWallet finds the first user of wallet, in any letter case.
+func (m *MemoryRepository) userByWallet(wallet string) (core.User, bool) {
+	for _, u := range sortedByID(m.users) {
+		if strings.EqualFold(u.WalletAddress, wallet) {
+			return u, true
+		}
+	}
+	return core.User{}, false
+}
+
+func (m *MemoryRepository) insertUser(user *core.User) {
+	user.ID = m.nextID("user")
+	if user.Role == "" {
+		user.Role = core.RoleUser
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	m.users[user.ID] = *user
+}
+
+func (m *MemoryRepository) GetUserByID(id uint) (*core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil, core.NotFound("user")
+	}
+	return &user, nil
+}
+
+func (m *MemoryRepository) GetUserByWallet(wallet string) (*core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.userByWallet(wallet)
+	if !ok {
+		return nil, core.NotFound("user")
+	}
+	return &user, nil
+}
+
+// Collection methods
+func (m *MemoryRepository) CreateCollection(collection *core.Collection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	collection.ID = m.nextID("collection")
+	if collection.CreatedAt.IsZero() {
+		collection.CreatedAt = time.Now()
+	}
+	m.collections[collection.ID] = *collection
+	return nil
+}
+
+func (m *MemoryRepository) ListCollections(filter core.CollectionFilter, page core.PageRequest) (*core.Page[core.Collection], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows []core.Collection
+	for _, c := range m.collections {
+		if filter.CreatorID != 0 && c.CreatorUserID != filter.CreatorID {
+			continue
+		}
+		rows = append(rows, c)
+	}
+	return collectionQuery.page(rows, page)
+}
+
+func (m *MemoryRepository) FindCollectionByOwner(ownerID uint) (*core.Collection, error) {
+	return m.findCollection(func(c *core.Collection) bool { return c.CreatorUserID == ownerID })
+}
+
+func (m *MemoryRepository) FindCollectionByName(ownerID uint, name string) (*core.Collection, error) {
+	return m.findCollection(func(c *core.Collection) bool { return c.CreatorUserID == ownerID && c.Name == name })
+}
+
+func (m *MemoryRepository) findCollection(match func(*core.Collection) bool) (*core.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range sortedByID(m.collections) {
+		if match(&c) {
+			return &c, nil
+		}
+	}
+	return nil, core.NotFound("collection")
+}
+
+// NFT methods
+func (m *MemoryRepository) CreateNFT(nft *core.NFT) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.insertNFT(nft)
+}
+
+// insertNFT stores a new NFT with its attributes and counts its traits.
+// Tokens are unique per chain and contract, in any letter case.
+func (m *MemoryRepository) insertNFT(nft *core.NFT) error {
+	for _, n := range m.nfts {
+		if n.Chain == nft.Chain && strings.EqualFold(n.ContractAddress, nft.ContractAddress) && n.TokenID == nft.TokenID {
+			return core.Conflict("nft_exists", "nft is already registered")
+		}
+	}
+	nft.ID = m.nextID("nft")
+	if nft.Version == 0 {
+		nft.Version = 1
+	}
+	if nft.CreatedAt.IsZero() {
+		nft.CreatedAt = time.Now()
+	}
+	for i := range nft.Attributes {
+		nft.Attributes[i].ID = m.nextID("nft_attribute")
+		nft.Attributes[i].NFTID = nft.ID
+	}
+	stored := copyNFT(*nft)
+	stored.Collection, stored.Owner = core.Collection{}, core.User{}
+	m.nfts[nft.ID] = stored
+	m.adjustTraitCounts(nft.CollectionID, nft.Attributes, 1)
+	return nil
+}
+
+// copyNFT copies an NFT with its attributes, so callers cannot change the
+// stored ones.
+func copyNFT(nft core.NFT) core.NFT {
+	nft.Attributes = append([]core.NFTAttribute{}, nft.Attributes...)
+	return nft
+}
+
+// bareNFT is an NFT without its attributes, as the SQL repository loads a
+// listing's NFT.
+func bareNFT(nft core.NFT) core.NFT {
+	nft.Attributes = nil
+	return nft
+}
+
+func (m *MemoryRepository) GetNFTByID(id uint) (*core.NFT, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nft, ok := m.nfts[id]
+	if !ok {
+		return nil, core.NotFound("nft")
+	}
+	nft = copyNFT(nft)
+	return &nft, nil
+}
+
+// burnNFT is the SQL repository's burnNFT.
+func (m *MemoryRepository) burnNFT(events *memoryLog, id uint) error {
+	nft, ok := m.nfts[id]
+	if !ok {
+		return core.NotFound("nft")
+	}
+	if nft.BurnedAt != nil {
+		return core.InvalidState("nft_burned", "nft is already burned")
+	}
+	now := time.Now()
+	nft.BurnedAt = &now
+	nft.RarityRank = 0
+	nft.Version++
+	m.nfts[id] = nft
+
+	m.adjustTraitCounts(nft.CollectionID, nft.Attributes, -1)
+	events.record(&core.Event{
+		Type:         core.EventNFTBurned,
+		CollectionID: nft.CollectionID,
+		NFTID:        nft.ID,
+		UserID:       nft.OwnerUserID,
+		Data:         map[string]interface{}{"token_id": nft.TokenID, "contract_address": nft.ContractAddress},
+	})
+
+	for _, listing := range sortedByID(m.listings) {
+		if listing.NFTID == id && listing.IsOpen() {
+			m.cancelListing(events, &listing, "nft burned")
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ListNFTs(filter core.NFTFilter, page core.PageRequest) (*core.Page[core.NFT], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows []core.NFT
+	for _, n := range m.nfts {
+		if n.BurnedAt != nil ||
+			filter.OwnerID != 0 && n.OwnerUserID != filter.OwnerID ||
+			filter.CollectionID != 0 && n.CollectionID != filter.CollectionID ||
+			filter.Chain != "" && n.Chain != filter.Chain {
+			continue
+		}
+		rows = append(rows, copyNFT(n))
+	}
+	return nftQuery.page(rows, page)
+}
+
+// Rarity methods
+func (m *MemoryRepository) adjustTraitCounts(collectionID uint, attrs []core.NFTAttribute, delta int) {
+	keys := make([]traitKey, 0, len(attrs)+1)
+	for _, a := range attrs {
+		keys = append(keys, traitKey{collectionID, a.TraitType, a.Value})
+	}
+	keys = append(keys, traitKey{collectionID, core.TraitCountType, fmt.Sprint(len(attrs))})
+
+	for _, key := range keys {
+		count, ok := m.traitCounts[key]
+		if !ok {
+			count = core.TraitCount{ID: m.nextID("trait_count"), CollectionID: collectionID, TraitType: key.traitType, Value: key.value}
+		}
+		count.TokenCount += delta
+		m.traitCounts[key] = count
+	}
+	if c, ok := m.collections[collectionID]; ok {
+		c.RarityStale = true
+		m.collections[collectionID] = c
+	}
+}
+
+func (m *MemoryRepository) ListTraitCounts(collectionID uint) ([]core.TraitCount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := []core.TraitCount{}
+	for _, c := range m.traitCounts {
+		if c.CollectionID == collectionID && c.TokenCount > 0 {
+			counts = append(counts, c)
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].TraitType != counts[j].TraitType {
+			return counts[i].TraitType < counts[j].TraitType
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	return counts, nil
+}
+
+func (m *MemoryRepository) ListCollectionNFTs(collectionID uint) ([]core.NFT, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nfts := []core.NFT{}
+	for _, n := range sortedByID(m.nfts) {
+		if n.CollectionID == collectionID && n.BurnedAt == nil {
+			nfts = append(nfts, copyNFT(n))
+		}
+	}
+	return nfts, nil
+}
+
+func (m *MemoryRepository) UpdateNFTRarity(scores map[uint]float64, ranks map[uint]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, score := range scores {
+		nft, ok := m.nfts[id]
+		if !ok {
+			continue
+		}
+		nft.RarityScore, nft.RarityRank = score, ranks[id]
+		m.nfts[id] = nft
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ClaimStaleRarity(limit int) ([]uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := []uint{}
+	for _, c := range sortedByID(m.collections) {
+		if len(ids) == limit {
+			break
+		}
+		if c.RarityStale {
+			c.RarityStale = false
+			m.collections[c.ID] = c
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MemoryRepository) MarkRarityStale(collectionID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.collections[collectionID]; ok {
+		c.RarityStale = true
+		m.collections[collectionID] = c
+	}
+	return nil
+}
+
+// Currency methods
+func (m *MemoryRepository) CreateCurrency(currency *core.Currency) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.currency(currency.Chain, currency.Symbol); ok {
+		return core.Conflict("currency_exists", "currency is already registered on this chain")
+	}
+	m.insertCurrency(currency)
+	return nil
+}
+
+func (m *MemoryRepository) EnsureCurrency(currency *core.Currency) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.currency(currency.Chain, currency.Symbol); ok {
+		*currency = existing
+		return nil
+	}
+	m.insertCurrency(currency)
+	return nil
+}
+
+func (m *MemoryRepository) currency(chain, symbol string) (core.Currency, bool) {
+	for _, c := range m.currencies {
+		if c.Chain == chain && c.Symbol == symbol {
+			return c, true
+		}
+	}
+	return core.Currency{}, false
+}
+
+func (m *MemoryRepository) insertCurrency(currency *core.Currency) {
+	currency.ID = m.nextID("currency")
+	if currency.CreatedAt.IsZero() {
+		currency.CreatedAt = time.Now()
+	}
+	m.currencies[currency.ID] = *currency
+}
+
+func (m *MemoryRepository) GetCurrency(chain, symbol string) (*core.Currency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	currency, ok := m.currency(chain, symbol)
+	if !ok {
+		return nil, core.NotFound("currency")
+	}
+	return &currency, nil
+}
+
+func (m *MemoryRepository) ListCurrencies(chain string) ([]core.Currency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	currencies := []core.Currency{}
+	for _, c := range m.currencies {
+		if chain == "" || c.Chain == chain {
+			currencies = append(currencies, c)
+		}
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		if currencies[i].Chain != currencies[j].Chain {
+			return currencies[i].Chain < currencies[j].Chain
+		}
+		return currencies[i].Symbol < currencies[j].Symbol
+	})
+	return currencies, nil
+}
+
+func (m *MemoryRepository) SetCurrencyEnabled(id uint, enabled bool) (*core.Currency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	currency, ok := m.currencies[id]
+	if !ok {
+		return nil, core.NotFound("currency")
+	}
+	currency.Enabled = enabled
+	m.currencies[id] = currency
+	return &currency, nil
+}
+
+// Listing methods
+func (m *MemoryRepository) CreateListing(listing *core.Listing) error {
+	return m.transaction(func(events *memoryLog) error {
+		listing.ID = m.nextID("listing")
+		if listing.Currency == "" {
+			listing.Currency = "ETH"
+		}
+		if listing.Status == "" {
+			listing.Status = core.ListingActive
+		}
+		if listing.Version == 0 {
+			listing.Version = 1
+		}
+		if listing.CreatedAt.IsZero() {
+			listing.CreatedAt = time.Now()
+		}
+		m.saveListing(*listing)
+		events.record(m.listingEvent(core.EventListingCreated, listing, 0, nil))
+		return nil
+	})
+}
+
+// saveListing stores a listing without its relations.
+func (m *MemoryRepository) saveListing(listing core.Listing) {
+	listing.NFT, listing.Seller = core.NFT{}, core.User{}
+	m.listings[listing.ID] = listing
+}
+
+// withRelations fills in a listing's NFT and seller.
+func (m *MemoryRepository) withRelations(listing core.Listing) core.Listing {
+	listing.NFT = bareNFT(m.nfts[listing.NFTID])
+	listing.Seller = m.users[listing.SellerUserID]
+	return listing
+}
+
+func (m *MemoryRepository) GetListingByID(id uint) (*core.Listing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	listing, ok := m.listings[id]
+	if !ok {
+		return nil, core.NotFound("listing")
+	}
+	return &listing, nil
+}
+
+func (m *MemoryRepository) ListActiveListings(filter core.ListingFilter, page core.PageRequest) (*core.Page[core.Listing], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var rows []core.Listing
+	for _, l := range m.listings {
+		if !(l.Status == core.ListingActive || filter.IncludeInvalid && l.Status == core.ListingInvalid) || l.IsExpired(now) {
+			continue
+		}
+		if filter.MinPrice != nil && l.PriceWei.Cmp(*filter.MinPrice) < 0 ||
+			filter.MaxPrice != nil && l.PriceWei.Cmp(*filter.MaxPrice) > 0 ||
+			filter.Currency != "" && l.Currency != filter.Currency ||
+			filter.SellerID != 0 && l.SellerUserID != filter.SellerID {
+			continue
+		}
+		nft := m.nfts[l.NFTID]
+		if filter.CollectionID != 0 && nft.CollectionID != filter.CollectionID ||
+			filter.Chain != "" && nft.Chain != filter.Chain {
+			continue
+		}
+		rows = append(rows, m.withRelations(l))
+	}
+	return listingQuery.page(rows, page)
+}
+
+func (m *MemoryRepository) UpdateListingStatus(listing *core.Listing, status core.ListingStatus) error {
+	return m.transaction(func(events *memoryLog) error {
+		current, ok := m.listings[listing.ID]
+		if !ok || current.Status != listing.Status || current.Version != listing.Version {
+			return concurrentUpdate("listing")
+		}
+		current.Status = status
+		current.Version++
+		m.saveListing(current)
+		listing.Status = status
+		listing.Version++
+
+		if t, ok := listingStatusEvents[status]; ok {
+			events.record(m.listingEvent(t, listing, 0, nil))
+		}
+		return nil
+	})
+}
+
+// cancelListing cancels an open listing.
+func (m *MemoryRepository) cancelListing(events *memoryLog, listing *core.Listing, reason string) {
+	listing.Status = core.ListingCancelled
+	listing.Version++
+	m.saveListing(*listing)
+	events.record(m.listingEvent(core.EventListingCancelled, listing, 0, map[string]interface{}{"reason": reason}))
+}
+
+// listingEvent is the SQL repository's listingEvent.
+func (m *MemoryRepository) listingEvent(t core.EventType, listing *core.Listing, buyerID uint, data map[string]interface{}) *core.Event {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["listing_id"] = listing.ID
+	data["price_wei"] = listing.PriceWei
+	data["currency"] = listing.Currency
+	return &core.Event{
+		Type:               t,
+		CollectionID:       m.nfts[listing.NFTID].CollectionID,
+		NFTID:              listing.NFTID,
+		UserID:             listing.SellerUserID,
+		CounterpartyUserID: buyerID,
+		Data:               data,
+	}
+}
+
+// orderEvent is the SQL repository's orderEvent.
+func (m *MemoryRepository) orderEvent(t core.EventType, order *core.Order, data map[string]interface{}) *core.Event {
+	listing := m.listings[order.ListingID]
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["order_id"] = order.ID
+	return m.listingEvent(t, &listing, order.BuyerUserID, data)
+}
+
+// Order methods
+func (m *MemoryRepository) CreateOrder(order *core.Order, reservedUntil *time.Time) error {
+	return m.transaction(func(events *memoryLog) error {
+		now := time.Now()
+		var listing core.Listing
+		if reservedUntil != nil {
+			var ok bool
+			if listing, ok = m.listings[order.ListingID]; !ok {
+				return core.NotFound("listing")
+			}
+			if listing.Status != core.ListingActive {
+				return core.InvalidState("listing_not_active", "listing is not active")
+			}
+			if listing.ReservedUntil != nil && listing.ReservedUntil.After(now) {
+				return core.ListingReserved(*listing.ReservedUntil)
+			}
+		}
+
+		order.ID = m.nextID("order")
+		if order.Status == "" {
+			order.Status = core.OrderPending
+		}
+		if order.Version == 0 {
+			order.Version = 1
+		}
+		if order.CreatedAt.IsZero() {
+			order.CreatedAt = now
+		}
+		m.saveOrder(*order)
+
+		var data map[string]interface{}
+		if reservedUntil != nil {
+			until := *reservedUntil
+			listing.ReservedByOrderID = &order.ID
+			listing.ReservedUntil = &until
+			listing.Version++
+			m.saveListing(listing)
+			order.Listing = listing
+			m.expireSuperseded(events, order)
+			data = map[string]interface{}{"reserved_until": until}
+		}
+		events.record(m.orderEvent(core.EventOrderCreated, order, data))
+		return nil
+	})
+}
+
+// expireSuperseded expires the other pending orders of the listing order has
+// just reserved, so that they cannot be paid for once it is sold.
+func (m *MemoryRepository) expireSuperseded(events *memoryLog, order *core.Order) {
+	var superseded []core.Order
+	for _, o := range m.orders {
+		if o.ListingID == order.ListingID && o.Status == core.OrderPending && o.ID != order.ID {
+			superseded = append(superseded, o)
+		}
+	}
+	sort.Slice(superseded, func(i, j int) bool { return superseded[i].ID < superseded[j].ID })
+	for i := range superseded {
+		o := &superseded[i]
+		o.Status = core.OrderExpired
+		o.Version++
+		m.saveOrder(*o)
+		events.record(m.orderEvent(core.EventOrderExpired, o, map[string]interface{}{"superseded_by_order_id": order.ID}))
+	}
+}
+
+// saveOrder stores an order without its relations.
+func (m *MemoryRepository) saveOrder(order core.Order) {
+	order.Listing, order.Buyer = core.Listing{}, core.User{}
+	m.orders[order.ID] = order
+}
+
+// releaseReservation frees the listing reserved by an order, if any.
+func (m *MemoryRepository) releaseReservation(orderID uint) {
+	for id, l := range m.listings {
+		if l.ReservedByOrderID != nil && *l.ReservedByOrderID == orderID {
+			l.ReservedByOrderID, l.ReservedUntil = nil, nil
+			l.Version++
+			m.listings[id] = l
+		}
+	}
+}
+
+func (m *MemoryRepository) GetOrderByID(id uint) (*core.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return nil, core.NotFound("order")
+	}
+	return &order, nil
+}
+
+// ConfirmOrder is the SQL repository's ConfirmOrder: either the order is
+// confirmed, its listing sold and the NFT transferred, or nothing changes.
+func (m *MemoryRepository) ConfirmOrder(orderID uint, txHash string) error {
+	return m.transaction(func(events *memoryLog) error {
+		order, ok := m.orders[orderID]
+		if !ok {
+			return core.NotFound("order")
+		}
+
+		now := time.Now()
+		if order.Status == core.OrderExpired || order.Status == core.OrderPending && order.IsExpired(now) {
+			return core.InvalidState("order_expired", "order has expired")
+		}
+		if order.Status != core.OrderPending {
+			return core.InvalidState("order_not_pending", "order is not pending")
+		}
+
+		listing, ok := m.listings[order.ListingID]
+		if !ok {
+			return core.NotFound("listing")
+		}
+		if listing.Status == core.ListingExpired || listing.IsExpired(now) {
+			return core.InvalidState("listing_expired", "listing has expired")
+		}
+		if !listing.IsOpen() {
+			return core.InvalidState("listing_not_active", "listing is not active")
+		}
+		if listing.ReservedByOther(order.ID, now) {
+			return core.ListingReserved(*listing.ReservedUntil)
+		}
+
+		hash := txHash
+		order.Status = core.OrderConfirmed
+		order.TxHash = &hash
+		order.Version++
+		m.saveOrder(order)
+
+		listing.Status = core.ListingSold
+		listing.ReservedByOrderID, listing.ReservedUntil = nil, nil
+		listing.Version++
+		m.saveListing(listing)
+
+		if nft, ok := m.nfts[listing.NFTID]; ok {
+			nft.OwnerUserID = order.BuyerUserID
+			nft.Version++
+			m.nfts[nft.ID] = nft
+		}
+
+		for _, t := range []core.EventType{core.EventOrderConfirmed, core.EventListingSold, core.EventTransferIndexed} {
+			events.record(m.orderEvent(t, &order, map[string]interface{}{"tx_hash": txHash}))
+		}
+		return nil
+	})
+}
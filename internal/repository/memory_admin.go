@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// Admin methods, audited like the SQL repository's.
+
+func (m *MemoryRepository) audit(entry core.AuditLog) {
+	entry.ID = m.nextID("audit_log")
+	entry.CreatedAt = time.Now()
+	if entry.ActorUserID != nil {
+		actor := *entry.ActorUserID
+		entry.ActorUserID = &actor
+	}
+	m.auditLog[entry.ID] = entry
+}
+
+func (m *MemoryRepository) ListUsers(filter core.UserFilter, page core.PageRequest) (*core.Page[core.User], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows []core.User
+	for _, u := range m.users {
+		if filter.Role == "" || u.Role == filter.Role {
+			rows = append(rows, u)
+		}
+	}
+	return userQuery.page(rows, page)
+}
+
+func (m *MemoryRepository) SetUserRole(actorID *uint, userID uint, role core.Role, reason string) (*core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, core.NotFound("user")
+	}
+	previous := user.Role
+	user.Role = role
+	m.users[userID] = user
+	m.audit(core.AuditLog{
+		ActorUserID: actorID,
+		Action:      core.AuditUserRoleChanged,
+		TargetType:  "user",
+		TargetID:    userID,
+		Reason:      reason,
+		Details:     map[string]interface{}{"from": previous, "to": role},
+	})
+	return &user, nil
+}
+
+func (m *MemoryRepository) SetCollectionVerified(actorID, collectionID uint, verified bool, reason string) (*core.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	collection, ok := m.collections[collectionID]
+	if !ok {
+		return nil, core.NotFound("collection")
+	}
+	collection.Verified = verified
+	m.collections[collectionID] = collection
+	m.audit(core.AuditLog{
+		ActorUserID: &actorID,
+		Action:      core.AuditCollectionVerified,
+		TargetType:  "collection",
+		TargetID:    collectionID,
+		Reason:      reason,
+		Details:     map[string]interface{}{"verified": verified},
+	})
+	return &collection, nil
+}
+
+func (m *MemoryRepository) ForceCancelListing(actorID, listingID uint, reason string) error {
+	return m.transaction(func(events *memoryLog) error {
+		listing, ok := m.listings[listingID]
+		if !ok || !listing.IsOpen() {
+			return core.InvalidState("listing_not_active", "listing is not active")
+		}
+		m.audit(core.AuditLog{
+			ActorUserID: &actorID,
+			Action:      core.AuditListingForceCancel,
+			TargetType:  "listing",
+			TargetID:    listingID,
+			Reason:      reason,
+		})
+		m.cancelListing(events, &listing, reason)
+		return nil
+	})
+}
+
+func (m *MemoryRepository) MarkOrderFailed(actorID, orderID uint, reason string) error {
+	return m.transaction(func(events *memoryLog) error {
+		order, ok := m.orders[orderID]
+		if !ok || order.Status != core.OrderPending {
+			return core.InvalidState("order_not_pending", "order is not pending")
+		}
+		order.Status = core.OrderFailed
+		order.Version++
+		m.saveOrder(order)
+		m.releaseReservation(orderID)
+		m.audit(core.AuditLog{
+			ActorUserID: &actorID,
+			Action:      core.AuditOrderMarkedFailed,
+			TargetType:  "order",
+			TargetID:    orderID,
+			Reason:      reason,
+		})
+		events.record(m.orderEvent(core.EventOrderFailed, &order, map[string]interface{}{"reason": reason}))
+		return nil
+	})
+}
+
+func (m *MemoryRepository) ListAuditLog(filter core.AuditFilter, page core.PageRequest) (*core.Page[core.AuditLog], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows []core.AuditLog
+	for _, a := range m.auditLog {
+		if filter.ActorID != 0 && (a.ActorUserID == nil || *a.ActorUserID != filter.ActorID) ||
+			filter.TargetType != "" && a.TargetType != filter.TargetType ||
+			filter.TargetID != 0 && a.TargetID != filter.TargetID {
+			continue
+		}
+		rows = append(rows, a)
+	}
+	return auditLogQuery.page(rows, page)
+}
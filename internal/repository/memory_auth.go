@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// Auth methods
+func (m *MemoryRepository) CreateAuthNonce(nonce *core.AuthNonce) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nonce.ID = m.nextID("auth_nonce")
+	if nonce.CreatedAt.IsZero() {
+		nonce.CreatedAt = time.Now()
+	}
+	m.nonces[nonce.ID] = *nonce
+	return nil
+}
+
+func (m *MemoryRepository) ConsumeAuthNonce(nonce string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, n := range m.nonces {
+		if n.Nonce == nonce && n.UsedAt == nil && n.ExpiresAt.After(now) {
+			n.UsedAt = &now
+			m.nonces[id] = n
+			return nil
+		}
+	}
+	return core.NotFound("nonce")
+}
+
+func (m *MemoryRepository) FindOrCreateUserByWallet(wallet string) (*core.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.userByWallet(wallet); ok {
+		return &user, nil
+	}
+	user := &core.User{WalletAddress: wallet}
+	m.insertUser(user)
+	return user, nil
+}
+
+func (m *MemoryRepository) CreateSession(session *core.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.insertSession(session)
+	return nil
+}
+
+func (m *MemoryRepository) insertSession(session *core.Session) {
+	session.ID = m.nextID("session")
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	m.sessions[session.ID] = *session
+}
+
+func (m *MemoryRepository) GetSession(id uint) (*core.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, core.NotFound("session")
+	}
+	return &session, nil
+}
+
+func (m *MemoryRepository) GetSessionByRefreshHash(hash string) (*core.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.RefreshTokenHash == hash {
+			return &s, nil
+		}
+	}
+	return nil, core.NotFound("session")
+}
+
+func (m *MemoryRepository) RotateSession(old, next *core.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.sessions[old.ID]
+	if !ok || current.RevokedAt != nil {
+		return core.Conflict("session_revoked", "session was already rotated")
+	}
+	m.insertSession(next)
+	now := time.Now()
+	current.RevokedAt = &now
+	current.ReplacedByID = &next.ID
+	m.sessions[old.ID] = current
+	return nil
+}
+
+func (m *MemoryRepository) RevokeSession(id uint) error {
+	return m.revokeSessions(func(s *core.Session) bool { return s.ID == id })
+}
+
+func (m *MemoryRepository) RevokeSessionFamily(familyID string) error {
+	return m.revokeSessions(func(s *core.Session) bool { return s.FamilyID == familyID })
+}
+
+func (m *MemoryRepository) RevokeUserSessions(userID uint) error {
+	return m.revokeSessions(func(s *core.Session) bool { return s.UserID == userID })
+}
+
+func (m *MemoryRepository) revokeSessions(match func(*core.Session) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, s := range m.sessions {
+		if s.RevokedAt == nil && match(&s) {
+			s.RevokedAt = &now
+			m.sessions[id] = s
+		}
+	}
+	return nil
+}
+
+// API key methods
+func (m *MemoryRepository) CreateAPIKey(key *core.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key.ID = m.nextID("api_key")
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	m.apiKeys[key.ID] = copyAPIKey(*key)
+	return nil
+}
+
+func copyAPIKey(key core.APIKey) core.APIKey {
+	key.Scopes = append([]core.APIScope(nil), key.Scopes...)
+	return key
+}
+
+func (m *MemoryRepository) GetAPIKeyByHash(hash string) (*core.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.apiKeys {
+		if k.KeyHash == hash {
+			k = copyAPIKey(k)
+			return &k, nil
+		}
+	}
+	return nil, core.NotFound("api_key")
+}
+
+func (m *MemoryRepository) GetAPIKey(userID, id uint) (*core.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return nil, core.NotFound("api_key")
+	}
+	key = copyAPIKey(key)
+	return &key, nil
+}
+
+func (m *MemoryRepository) ListAPIKeys(userID uint) ([]core.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := []core.APIKey{}
+	all := sortedByID(m.apiKeys)
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].UserID == userID {
+			keys = append(keys, copyAPIKey(all[i]))
+		}
+	}
+	return keys, nil
+}
+
+func (m *MemoryRepository) RevokeAPIKey(userID, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return core.NotFound("api_key")
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	m.apiKeys[id] = key
+	return nil
+}
+
+func (m *MemoryRepository) RecordAPIKeyUse(id uint, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key, ok := m.apiKeys[id]; ok {
+		key.UsageCount++
+		key.LastUsedAt = &now
+		m.apiKeys[id] = key
+	}
+	k := usageKey{apiKeyID: id, day: now.UTC().Truncate(24 * time.Hour)}
+	usage := m.apiKeyUsage[k]
+	usage.APIKeyID, usage.Day = k.apiKeyID, k.day
+	usage.Requests++
+	m.apiKeyUsage[k] = usage
+	return nil
+}
+
+func (m *MemoryRepository) ListAPIKeyUsage(id uint, since time.Time) ([]core.APIKeyUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	from := since.UTC().Truncate(24 * time.Hour)
+	usage := []core.APIKeyUsage{}
+	for k, u := range m.apiKeyUsage {
+		if k.apiKeyID == id && !k.day.Before(from) {
+			usage = append(usage, u)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Day.Before(usage[j].Day) })
+	return usage, nil
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// Event methods
+func (m *MemoryRepository) RecordEvents(events ...*core.Event) error {
+	return m.transaction(func(l *memoryLog) error {
+		l.record(events...)
+		return nil
+	})
+}
+
+func (m *MemoryRepository) ListEvents(filter core.EventFilter, afterID uint, limit int) ([]core.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := []core.Event{}
+	for _, e := range sortedByID(m.eventLog) {
+		if len(events) == limit {
+			break
+		}
+		if e.ID > afterID && filter.Matches(&e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *MemoryRepository) PruneEvents(cutoff time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, e := range m.eventLog {
+		if e.CreatedAt.Before(cutoff) && e.DispatchedAt != nil {
+			delete(m.eventLog, id)
+		}
+	}
+	return nil
+}
+
+// Webhook methods
+func (m *MemoryRepository) CreateWebhook(hook *core.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hook.ID = m.nextID("webhook")
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now()
+	}
+	stored := *hook
+	stored.EventTypes = append([]core.EventType(nil), hook.EventTypes...)
+	m.webhooks[hook.ID] = stored
+	return nil
+}
+
+// ownsWebhook is ownedBy for a webhook held in memory.
+func ownsWebhook(owner core.WebhookOwner, hook *core.Webhook) bool {
+	return hook.UserID == owner.UserID &&
+		(owner.APIKeyID == 0 || hook.APIKeyID != nil && *hook.APIKeyID == owner.APIKeyID)
+}
+
+// webhookLive tells whether a webhook receives events: it has no API key,
+// or one neither revoked nor expired.
+func (m *MemoryRepository) webhookLive(hook *core.Webhook, now time.Time) bool {
+	if hook.APIKeyID == nil {
+		return true
+	}
+	key, ok := m.apiKeys[*hook.APIKeyID]
+	return ok && key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now))
+}
+
+func (m *MemoryRepository) CountWebhooks(userID uint) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, h := range m.webhooks {
+		if h.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryRepository) GetWebhook(owner core.WebhookOwner, id uint) (*core.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hook, ok := m.webhooks[id]
+	if !ok || !ownsWebhook(owner, &hook) {
+		return nil, core.NotFound("webhook")
+	}
+	return &hook, nil
+}
+
+func (m *MemoryRepository) ListWebhooks(owner core.WebhookOwner) ([]core.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hooks := []core.Webhook{}
+	all := sortedByID(m.webhooks)
+	for i := len(all) - 1; i >= 0; i-- {
+		if ownsWebhook(owner, &all[i]) {
+			hooks = append(hooks, all[i])
+		}
+	}
+	return hooks, nil
+}
+
+func (m *MemoryRepository) DeleteWebhook(owner core.WebhookOwner, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hook, ok := m.webhooks[id]
+	if !ok || !ownsWebhook(owner, &hook) {
+		return core.NotFound("webhook")
+	}
+	delete(m.webhooks, id)
+	for deliveryID, d := range m.deliveries {
+		if d.WebhookID == id {
+			m.deleteDelivery(deliveryID)
+		}
+	}
+	return nil
+}
+
+// deleteDelivery deletes a delivery with its history.
+func (m *MemoryRepository) deleteDelivery(id uint) {
+	delete(m.deliveries, id)
+	for attemptID, a := range m.attempts {
+		if a.DeliveryID == id {
+			delete(m.attempts, attemptID)
+		}
+	}
+}
+
+// Webhook delivery methods
+func (m *MemoryRepository) ListWebhookDeliveries(webhookID uint, filter core.DeliveryFilter, page core.PageRequest) (*core.Page[core.WebhookDelivery], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rows []core.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.WebhookID != webhookID || filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		for _, a := range sortedByID(m.attempts) {
+			if a.DeliveryID == d.ID {
+				d.History = append(d.History, a)
+			}
+		}
+		rows = append(rows, d)
+	}
+	return deliveryQuery.page(rows, page)
+}
+
+func (m *MemoryRepository) GetWebhookDelivery(owner core.WebhookOwner, id uint) (*core.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery, ok := m.deliveries[id]
+	if !ok {
+		return nil, core.NotFound("webhook_delivery")
+	}
+	if hook, ok := m.webhooks[delivery.WebhookID]; !ok || !ownsWebhook(owner, &hook) {
+		return nil, core.NotFound("webhook_delivery")
+	}
+	return &delivery, nil
+}
+
+func (m *MemoryRepository) CreateWebhookDelivery(delivery *core.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.insertDelivery(delivery)
+	return nil
+}
+
+// insertDelivery stores a new delivery without its relations.
+func (m *MemoryRepository) insertDelivery(delivery *core.WebhookDelivery) {
+	delivery.ID = m.nextID("webhook_delivery")
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	stored := *delivery
+	stored.Webhook, stored.History = core.Webhook{}, nil
+	m.deliveries[delivery.ID] = stored
+}
+
+func (m *MemoryRepository) DispatchEvents(limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pending []core.Event
+	for _, e := range sortedByID(m.eventLog) {
+		if len(pending) == limit {
+			break
+		}
+		if e.DispatchedAt == nil {
+			pending = append(pending, e)
+		}
+	}
+
+	// Encode every payload first, so a failure queues nothing
+	payloads := make([]string, len(pending))
+	for i := range pending {
+		payload, err := json.Marshal(&pending[i])
+		if err != nil {
+			return 0, err
+		}
+		payloads[i] = string(payload)
+	}
+
+	now := time.Now()
+	hooks := sortedByID(m.webhooks)
+	for i := range pending {
+		e := &pending[i]
+		for j := range hooks {
+			if !m.webhookLive(&hooks[j], now) || !hooks[j].Matches(e) {
+				continue
+			}
+			m.insertDelivery(&core.WebhookDelivery{
+				WebhookID:     hooks[j].ID,
+				EventID:       e.ID,
+				EventType:     e.Type,
+				Payload:       payloads[i],
+				Status:        core.DeliveryPending,
+				NextAttemptAt: now,
+			})
+		}
+		e.DispatchedAt = &now
+		m.eventLog[e.ID] = *e
+	}
+	return len(pending), nil
+}
+
+func (m *MemoryRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]core.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var due []core.WebhookDelivery
+	for _, d := range sortedByID(m.deliveries) {
+		hook, ok := m.webhooks[d.WebhookID]
+		if d.Status == core.DeliveryPending && !d.NextAttemptAt.After(now) && ok && m.webhookLive(&hook, now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		m.deliveries[due[i].ID] = due[i]
+		due[i].Webhook = m.webhooks[due[i].WebhookID]
+	}
+	return due, nil
+}
+
+func (m *MemoryRepository) RecordWebhookAttempt(delivery *core.WebhookDelivery, attempt *core.WebhookAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt.ID = m.nextID("webhook_attempt")
+	attempt.DeliveryID = delivery.ID
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	m.attempts[attempt.ID] = *attempt
+
+	if stored, ok := m.deliveries[delivery.ID]; ok {
+		stored.Status = delivery.Status
+		stored.Attempts = delivery.Attempts
+		stored.NextAttemptAt = delivery.NextAttemptAt
+		stored.LastAttemptAt = delivery.LastAttemptAt
+		stored.LastStatusCode = delivery.LastStatusCode
+		stored.LastError = delivery.LastError
+		m.deliveries[delivery.ID] = stored
+	}
+	return nil
+}
+
+func (m *MemoryRepository) PruneWebhookDeliveries(cutoff time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, d := range m.deliveries {
+		if d.Status != core.DeliveryPending && d.CreatedAt.Before(cutoff) {
+			m.deleteDelivery(id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// Chain intent methods
+func (m *MemoryRepository) CreateIntent(intent *core.ChainIntent, lease time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if intent.Kind == core.IntentBurn && intent.NFTID != nil {
+		for _, i := range m.intents {
+			if i.Kind == core.IntentBurn && i.NFTID != nil && *i.NFTID == *intent.NFTID && i.IsOpen() {
+				return core.Conflict("burn_pending", "a burn of this nft is already in progress")
+			}
+		}
+	}
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+	intent.ID = m.nextID("chain_intent")
+	if intent.Status == "" {
+		intent.Status = core.IntentPending
+	}
+	intent.LockedUntil = &lockedUntil
+	intent.CreatedAt, intent.UpdatedAt = now, now
+	m.intents[intent.ID] = copyIntent(*intent)
+	return nil
+}
+
+// copyIntent copies an intent with its mint, so callers cannot change the
+// stored one.
+func copyIntent(intent core.ChainIntent) core.ChainIntent {
+	if intent.Mint != nil {
+		mint := *intent.Mint
+		mint.Attributes = append([]core.NFTAttribute(nil), mint.Attributes...)
+		intent.Mint = &mint
+	}
+	return intent
+}
+
+// updateIntent applies change to the stored intent id.
+func (m *MemoryRepository) updateIntent(id uint, change func(*core.ChainIntent)) {
+	intent, ok := m.intents[id]
+	if !ok {
+		return
+	}
+	change(&intent)
+	intent.UpdatedAt = time.Now()
+	m.intents[id] = intent
+}
+
+func (m *MemoryRepository) GetIntent(userID, id uint) (*core.ChainIntent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent, ok := m.intents[id]
+	if !ok || intent.UserID != userID {
+		return nil, core.NotFound("intent")
+	}
+	intent = copyIntent(intent)
+	return &intent, nil
+}
+
+func (m *MemoryRepository) ClaimIntents(limit int, lease time.Duration) ([]core.ChainIntent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+	var intents []core.ChainIntent
+	for _, i := range sortedByID(m.intents) {
+		if len(intents) == limit {
+			break
+		}
+		if !i.IsOpen() || i.LockedUntil != nil && !i.LockedUntil.Before(now) {
+			continue
+		}
+		m.updateIntent(i.ID, func(i *core.ChainIntent) { i.LockedUntil = &lockedUntil })
+		intents = append(intents, copyIntent(m.intents[i.ID]))
+	}
+	return intents, nil
+}
+
+func (m *MemoryRepository) ReleaseIntent(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateIntent(id, func(i *core.ChainIntent) { i.LockedUntil = nil })
+	return nil
+}
+
+func (m *MemoryRepository) SaveIntentTx(intent *core.ChainIntent, txHash, rawTx string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.intents[intent.ID]; !ok || stored.Status != core.IntentPending {
+		return core.InvalidState("intent_not_pending", "intent is not pending")
+	}
+	m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.Status, i.TxHash, i.RawTx = core.IntentSent, txHash, rawTx })
+	intent.Status, intent.TxHash, intent.RawTx = core.IntentSent, txHash, rawTx
+	return nil
+}
+
+func (m *MemoryRepository) ResetIntent(intent *core.ChainIntent, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent.Status, intent.TxHash, intent.RawTx, intent.Error = core.IntentPending, "", "", reason
+	m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.Status, i.TxHash, i.RawTx, i.Error = core.IntentPending, "", "", reason })
+	return nil
+}
+
+func (m *MemoryRepository) RecordIntentAttempt(intent *core.ChainIntent, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent.Attempts++
+	intent.Error = reason
+	m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.Attempts, i.Error = intent.Attempts, reason })
+	return nil
+}
+
+func (m *MemoryRepository) FailIntent(intent *core.ChainIntent, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent.Status, intent.Error = core.IntentFailed, reason
+	m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.Status, i.Error, i.LockedUntil = core.IntentFailed, reason, nil })
+	return nil
+}
+
+func (m *MemoryRepository) ConfirmMintIntent(intent *core.ChainIntent, contract, chain, tokenID string) (*core.NFT, error) {
+	mint := intent.Mint
+	nft := &core.NFT{
+		TokenID:         tokenID,
+		ContractAddress: contract,
+		Chain:           chain,
+		CollectionID:    mint.CollectionID,
+		OwnerUserID:     intent.UserID,
+		Name:            mint.Name,
+		Description:     mint.Description,
+		MetadataURL:     mint.TokenURI,
+		Attributes:      append([]core.NFTAttribute(nil), mint.Attributes...),
+	}
+	err := m.transaction(func(events *memoryLog) error {
+		if err := m.checkIntentSent(intent); err != nil {
+			return err
+		}
+		if err := m.insertNFT(nft); err != nil {
+			return err
+		}
+		m.confirmIntent(intent)
+		intent.NFTID = &nft.ID
+		m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.NFTID = &nft.ID })
+		events.record(txMinedEvent(intent, nft, "mint"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nft, nil
+}
+
+func (m *MemoryRepository) ConfirmBurnIntent(intent *core.ChainIntent) (*core.NFT, error) {
+	var nft core.NFT
+	err := m.transaction(func(events *memoryLog) error {
+		if err := m.checkIntentSent(intent); err != nil {
+			return err
+		}
+		if err := m.burnNFT(events, *intent.NFTID); err != nil {
+			return err
+		}
+		m.confirmIntent(intent)
+		nft = bareNFT(m.nfts[*intent.NFTID])
+		events.record(txMinedEvent(intent, &nft, "burn"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &nft, nil
+}
+
+func (m *MemoryRepository) checkIntentSent(intent *core.ChainIntent) error {
+	if stored, ok := m.intents[intent.ID]; !ok || stored.Status != core.IntentSent {
+		return core.InvalidState("intent_not_sent", "intent is not awaiting its transaction")
+	}
+	return nil
+}
+
+func (m *MemoryRepository) confirmIntent(intent *core.ChainIntent) {
+	m.updateIntent(intent.ID, func(i *core.ChainIntent) { i.Status, i.Error, i.LockedUntil = core.IntentConfirmed, "", nil })
+	intent.Status, intent.Error = core.IntentConfirmed, ""
+}
+
+// Expiry methods
+func (m *MemoryRepository) ExpireListings(now time.Time) (int, error) {
+	var expired int
+	err := m.transaction(func(events *memoryLog) error {
+		var due []core.Listing
+		for _, l := range m.listings {
+			if l.IsOpen() && l.ExpiresAt != nil && !l.ExpiresAt.After(now) {
+				due = append(due, l)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool { return due[i].ExpiresAt.Before(*due[j].ExpiresAt) })
+		if len(due) > expiryBatch {
+			due = due[:expiryBatch]
+		}
+		for i := range due {
+			listing := &due[i]
+			listing.Status = core.ListingExpired
+			listing.Version++
+			m.saveListing(*listing)
+			events.record(m.listingEvent(core.EventListingExpired, listing, 0, map[string]interface{}{"expires_at": listing.ExpiresAt}))
+		}
+		expired = len(due)
+		return nil
+	})
+	return expired, err
+}
+
+func (m *MemoryRepository) ExpireOrders(now time.Time) (int, error) {
+	var expired int
+	err := m.transaction(func(events *memoryLog) error {
+		var due []core.Order
+		for _, o := range m.orders {
+			if o.Status == core.OrderPending && o.ExpiresAt != nil && !o.ExpiresAt.After(now) {
+				due = append(due, o)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool { return due[i].ExpiresAt.Before(*due[j].ExpiresAt) })
+		if len(due) > expiryBatch {
+			due = due[:expiryBatch]
+		}
+		for i := range due {
+			order := &due[i]
+			order.Status = core.OrderExpired
+			order.Version++
+			m.saveOrder(*order)
+			m.releaseReservation(order.ID)
+			events.record(m.orderEvent(core.EventOrderExpired, order, map[string]interface{}{"expires_at": order.ExpiresAt}))
+		}
+		expired = len(due)
+		return nil
+	})
+	return expired, err
+}
+
+func (m *MemoryRepository) ReleaseReservations(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var released int
+	for id, l := range m.listings {
+		if l.ReservedUntil != nil && !l.ReservedUntil.After(now) {
+			l.ReservedByOrderID, l.ReservedUntil = nil, nil
+			l.Version++
+			m.listings[id] = l
+			released++
+		}
+	}
+	return released, nil
+}
+
+// Listing check methods
+func (m *MemoryRepository) ListChainListings(chain, contract string, afterID uint, limit int) ([]core.Listing, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	listings := []core.Listing{}
+	for _, l := range sortedByID(m.listings) {
+		if len(listings) == limit {
+			break
+		}
+		nft := m.nfts[l.NFTID]
+		if l.IsOpen() && l.ID > afterID && nft.Chain == chain && strings.EqualFold(nft.ContractAddress, contract) {
+			listings = append(listings, m.withRelations(l))
+		}
+	}
+	return listings, nil
+}
+
+func (m *MemoryRepository) SetListingValidity(listing *core.Listing, reason string) error {
+	return m.transaction(func(events *memoryLog) error {
+		current, ok := m.listings[listing.ID]
+		if !ok {
+			return core.NotFound("listing")
+		}
+		if !current.IsOpen() {
+			listing.Status = current.Status
+			return nil
+		}
+
+		status := core.ListingActive
+		if reason != "" {
+			status = core.ListingInvalid
+		}
+		now := time.Now()
+		updated := current
+		updated.Status, updated.InvalidReason, updated.CheckedAt = status, reason, &now
+		if status != current.Status || reason != current.InvalidReason {
+			updated.Version++
+		}
+		m.saveListing(updated)
+		listing.Status, listing.InvalidReason, listing.CheckedAt = status, reason, &now
+
+		var t core.EventType
+		switch {
+		case status == core.ListingInvalid && (current.Status != status || current.InvalidReason != reason):
+			t = core.EventListingInvalidated
+		case status == core.ListingActive && current.Status == core.ListingInvalid:
+			t = core.EventListingRestored
+		default:
+			return nil
+		}
+		var data map[string]interface{}
+		if reason != "" {
+			data = map[string]interface{}{"reason": reason}
+		}
+		events.record(m.listingEvent(t, &current, 0, data))
+		return nil
+	})
+}
+
+// Reconciliation methods
+func (m *MemoryRepository) ListChainNFTs(chain, contract string, afterID uint, limit int) ([]core.NFT, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nfts := []core.NFT{}
+	for _, n := range sortedByID(m.nfts) {
+		if len(nfts) == limit {
+			break
+		}
+		if n.Chain == chain && strings.EqualFold(n.ContractAddress, contract) && n.BurnedAt == nil && n.ID > afterID {
+			n = bareNFT(n)
+			n.Owner = m.users[n.OwnerUserID]
+			nfts = append(nfts, n)
+		}
+	}
+	return nfts, nil
+}
+
+func (m *MemoryRepository) CreateReconciliationRun(run *core.ReconciliationRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run.ID = m.nextID("reconciliation_run")
+	m.saveRun(*run)
+	return nil
+}
+
+// saveRun stores a run without its drifts.
+func (m *MemoryRepository) saveRun(run core.ReconciliationRun) {
+	run.Drifts = nil
+	m.runs[run.ID] = run
+}
+
+func (m *MemoryRepository) FinishReconciliationRun(run *core.ReconciliationRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	run.FinishedAt = &now
+	if _, ok := m.runs[run.ID]; ok {
+		m.saveRun(*run)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ListReconciliationRuns(page core.PageRequest) (*core.Page[core.ReconciliationRun], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return reconciliationRunQuery.page(sortedByID(m.runs), page)
+}
+
+func (m *MemoryRepository) GetReconciliationRun(id uint) (*core.ReconciliationRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.runs[id]
+	if !ok {
+		return nil, core.NotFound("reconciliation_run")
+	}
+	for _, d := range sortedByID(m.drifts) {
+		if d.RunID == id {
+			run.Drifts = append(run.Drifts, d)
+		}
+	}
+	return &run, nil
+}
+
+func (m *MemoryRepository) RecordDrift(drift *core.OwnershipDrift) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.insertDrift(drift)
+	return nil
+}
+
+func (m *MemoryRepository) insertDrift(drift *core.OwnershipDrift) {
+	drift.ID = m.nextID("ownership_drift")
+	if drift.CreatedAt.IsZero() {
+		drift.CreatedAt = time.Now()
+	}
+	m.drifts[drift.ID] = *drift
+}
+
+func (m *MemoryRepository) FixTransferredNFT(drift *core.OwnershipDrift, newOwnerID uint) (int, error) {
+	var cancelled int
+	err := m.transaction(func(events *memoryLog) error {
+		nft, ok := m.nfts[drift.NFTID]
+		if !ok || nft.OwnerUserID != drift.RecordedOwnerID || nft.BurnedAt != nil {
+			return core.InvalidState("nft_changed", "nft changed while it was reconciled")
+		}
+		nft.OwnerUserID = newOwnerID
+		nft.Version++
+		m.nfts[nft.ID] = nft
+		events.record(&core.Event{
+			Type:               core.EventTransferIndexed,
+			CollectionID:       nft.CollectionID,
+			NFTID:              nft.ID,
+			UserID:             drift.RecordedOwnerID,
+			CounterpartyUserID: newOwnerID,
+			Data:               map[string]interface{}{"token_id": nft.TokenID, "to": drift.ChainOwner, "source": "reconciliation"},
+		})
+
+		for _, listing := range sortedByID(m.listings) {
+			if listing.NFTID == nft.ID && listing.IsOpen() && listing.SellerUserID != newOwnerID {
+				m.cancelListing(events, &listing, "seller no longer owns the token")
+				cancelled++
+			}
+		}
+		drift.Fixed = true
+		drift.NewOwnerID = &newOwnerID
+		m.insertDrift(drift)
+		return nil
+	})
+	return cancelled, err
+}
+
+func (m *MemoryRepository) CancelUnapprovedListings(nftID, ownerID uint) (int, error) {
+	var cancelled int
+	err := m.transaction(func(events *memoryLog) error {
+		for _, listing := range sortedByID(m.listings) {
+			if listing.NFTID == nftID && listing.IsOpen() && listing.SellerUserID == ownerID {
+				m.cancelListing(events, &listing, "marketplace is no longer approved for the token")
+				cancelled++
+			}
+		}
+		return nil
+	})
+	return cancelled, err
+}
+
+func (m *MemoryRepository) FixBurnedNFT(drift *core.OwnershipDrift) (int, error) {
+	var cancelled int
+	err := m.transaction(func(events *memoryLog) error {
+		for _, l := range m.listings {
+			if l.NFTID == drift.NFTID && l.IsOpen() {
+				cancelled++
+			}
+		}
+		if err := m.burnNFT(events, drift.NFTID); err != nil {
+			return err
+		}
+		drift.Fixed = true
+		m.insertDrift(drift)
+		return nil
+	})
+	return cancelled, err
+}
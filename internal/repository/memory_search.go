@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/textsearch"
+)
+
+// nftText and collectionText are the documents searched for NFTs and
+// collections, matched as textsearch matches text.
+func nftText(nft *core.NFT) string {
+	return nft.Name + " " + nft.Description
+}
+
+func collectionText(c *core.Collection) string {
+	return c.Name + " " + c.Symbol
+}
+
+// searchRow is a live NFT with what searchScope joins to it; listed is
+// false when it has no active listing.
+type searchRow struct {
+	nft        core.NFT
+	collection core.Collection
+	creator    core.User
+	listed     bool
+	price      core.Wei
+}
+
+// searchRows returns the rows of searchScope, in id order.
+func (m *MemoryRepository) searchRows(q core.SearchQuery) []searchRow {
+	// The latest active listing of each NFT
+	active := make(map[uint]core.Listing)
+	for _, l := range m.listings {
+		if l.Status != core.ListingActive {
+			continue
+		}
+		if current, ok := active[l.NFTID]; !ok || l.CreatedAt.After(current.CreatedAt) {
+			active[l.NFTID] = l
+		}
+	}
+
+	words := textsearch.Words(q.Text)
+	var rows []searchRow
+	for _, nft := range sortedByID(m.nfts) {
+		collection, ok := m.collections[nft.CollectionID]
+		if nft.BurnedAt != nil || !ok {
+			continue
+		}
+		row := searchRow{nft: nft, collection: collection, creator: m.users[collection.CreatorUserID]}
+		if l, ok := active[nft.ID]; ok {
+			row.listed, row.price = true, l.PriceWei
+		}
+
+		if len(words) > 0 &&
+			!textsearch.MatchesPrefixes(words, nftText(&nft)) &&
+			!textsearch.MatchesPrefixes(words, collectionText(&collection)) &&
+			!textsearch.MatchesPrefixes(words, row.creator.Name) &&
+			textsearch.WordSimilarity(q.Text, nft.Name) <= fuzzyThreshold &&
+			textsearch.WordSimilarity(q.Text, collection.Name) <= fuzzyThreshold {
+			continue
+		}
+		if q.CollectionID != 0 && nft.CollectionID != q.CollectionID ||
+			q.Chain != "" && nft.Chain != q.Chain ||
+			q.Status == "listed" && !row.listed ||
+			q.Status == "unlisted" && row.listed ||
+			!hasTraits(&nft, q.Traits) {
+			continue
+		}
+		if q.MinPrice != nil && (!row.listed || row.price.Cmp(*q.MinPrice) < 0) ||
+			q.MaxPrice != nil && (!row.listed || row.price.Cmp(*q.MaxPrice) > 0) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func hasTraits(nft *core.NFT, traits []core.NFTAttribute) bool {
+	for _, t := range traits {
+		found := false
+		for _, a := range nft.Attributes {
+			if a.TraitType == t.TraitType && a.Value == t.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MemoryRepository) Search(q core.SearchQuery) (*core.SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := &core.SearchResult{}
+	rows := m.searchRows(q)
+
+	ranked := append([]searchRow(nil), rows...)
+	words := textsearch.Words(q.Text)
+	rank := func(r *searchRow) float64 {
+		if len(words) == 0 {
+			return 0
+		}
+		var score float64
+		if textsearch.MatchesPrefixes(words, nftText(&r.nft)) {
+			score = 1
+		}
+		return score + textsearch.WordSimilarity(q.Text, r.nft.Name)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ri, rj := rank(&ranked[i]), rank(&ranked[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return ranked[i].nft.ID > ranked[j].nft.ID
+	})
+	if q.Limit > 0 && len(ranked) > q.Limit {
+		ranked = ranked[:q.Limit]
+	}
+	for _, r := range ranked {
+		nft := copyNFT(r.nft)
+		nft.Collection = r.collection
+		result.NFTs = append(result.NFTs, nft)
+	}
+
+	if len(words) > 0 {
+		var collections []core.Collection
+		for _, c := range sortedByID(m.collections) {
+			if textsearch.MatchesPrefixes(words, collectionText(&c)) || textsearch.WordSimilarity(q.Text, c.Name) > fuzzyThreshold {
+				collections = append(collections, c)
+			}
+		}
+		sort.SliceStable(collections, func(i, j int) bool {
+			return textsearch.WordSimilarity(q.Text, collections[i].Name) > textsearch.WordSimilarity(q.Text, collections[j].Name)
+		})
+		if q.Limit > 0 && len(collections) > q.Limit {
+			collections = collections[:q.Limit]
+		}
+		result.Collections = collections
+	}
+
+	result.Facets = searchFacets(rows)
+	return result, nil
+}
+
+func searchFacets(rows []searchRow) core.Facets {
+	facets := core.Facets{
+		Collections:   []core.FacetCount{},
+		Chains:        []core.FacetCount{},
+		Traits:        []core.TraitFacet{},
+		ListingStatus: []core.FacetCount{},
+	}
+	collections := make(map[uint]*core.FacetCount)
+	chains := make(map[string]*core.FacetCount)
+	traits := make(map[core.NFTAttribute]*core.TraitFacet)
+	statuses := make(map[string]*core.FacetCount)
+	for i := range rows {
+		r := &rows[i]
+		if collections[r.collection.ID] == nil {
+			collections[r.collection.ID] = &core.FacetCount{Value: fmt.Sprint(r.collection.ID), Label: r.collection.Name}
+		}
+		collections[r.collection.ID].Count++
+		if chains[r.nft.Chain] == nil {
+			chains[r.nft.Chain] = &core.FacetCount{Value: r.nft.Chain}
+		}
+		chains[r.nft.Chain].Count++
+		for _, a := range r.nft.Attributes {
+			key := core.NFTAttribute{TraitType: a.TraitType, Value: a.Value}
+			if traits[key] == nil {
+				traits[key] = &core.TraitFacet{TraitType: a.TraitType, Value: a.Value}
+			}
+			traits[key].Count++
+		}
+		status := "unlisted"
+		if r.listed {
+			status = "listed"
+		}
+		if statuses[status] == nil {
+			statuses[status] = &core.FacetCount{Value: status}
+		}
+		statuses[status].Count++
+	}
+
+	for _, c := range collections {
+		facets.Collections = append(facets.Collections, *c)
+	}
+	sortFacetCounts(facets.Collections)
+	for _, c := range chains {
+		facets.Chains = append(facets.Chains, *c)
+	}
+	sortFacetCounts(facets.Chains)
+	for _, t := range traits {
+		facets.Traits = append(facets.Traits, *t)
+	}
+	sort.Slice(facets.Traits, func(i, j int) bool {
+		a, b := facets.Traits[i], facets.Traits[j]
+		if a.TraitType != b.TraitType {
+			return a.TraitType < b.TraitType
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Value < b.Value
+	})
+	for _, s := range []string{"listed", "unlisted"} {
+		if statuses[s] != nil {
+			facets.ListingStatus = append(facets.ListingStatus, *statuses[s])
+		}
+	}
+
+	for _, b := range priceBuckets() {
+		for i := range rows {
+			r := &rows[i]
+			if r.listed && r.price.Cmp(b.Min) >= 0 && (b.Max == nil || r.price.Cmp(*b.Max) < 0) {
+				b.Count++
+			}
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, b)
+	}
+	return facets
+}
+
+// sortFacetCounts orders facet values by count, most first.
+func sortFacetCounts(counts []core.FacetCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+}
+
+func (m *MemoryRepository) Suggest(text string, limit int) ([]core.Suggestion, error) {
+	words := textsearch.Words(text)
+	if len(words) == 0 {
+		return []core.Suggestion{}, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type scored struct {
+		core.Suggestion
+		score float64
+	}
+	var candidates []scored
+	for _, c := range m.collections {
+		score := textsearch.WordSimilarity(text, c.Name)
+		if textsearch.MatchesPrefixes(words, collectionText(&c)) || score > fuzzyThreshold {
+			candidates = append(candidates, scored{core.Suggestion{Type: "collection", ID: c.ID, Label: c.Name}, score})
+		}
+	}
+	for _, n := range m.nfts {
+		if n.BurnedAt != nil || n.Name == "" {
+			continue
+		}
+		score := textsearch.WordSimilarity(text, n.Name)
+		if textsearch.MatchesPrefixes(words, nftText(&n)) || score > fuzzyThreshold {
+			candidates = append(candidates, scored{core.Suggestion{Type: "nft", ID: n.ID, Label: n.Name}, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+
+	suggestions := []core.Suggestion{}
+	for _, c := range candidates {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, c.Suggestion)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type valueKind int
+
+const (
+	kindNumeric valueKind = iota
+	kindWei
+	kindTime
+	kindText
+)
+
+// sortKey describes a sortable column: the SQL expression ordered on, how
+// its cursor value is typed, and how to read that value back from a row.
+type sortKey[T any] struct {
+	expr  string
+	kind  valueKind
+	value func(*T) string
+}
+
+// listQuery is the shared keyset-pagination builder behind every list
+// endpoint. Rows are ordered by the sort key and then by id, so ordering is
+// stable even when sort values repeat.
+type listQuery[T any] struct {
+	idColumn    string
+	id          func(*T) uint
+	keys        map[string]sortKey[T]
+	defaultSort string
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, core.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, core.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// sql is the key's expression as ordered on in db.
+func (k sortKey[T]) sql(db *gorm.DB) string {
+	if k.kind == kindWei {
+		return weiSQL(db, k.expr)
+	}
+	return k.expr
+}
+
+// placeholder returns the bind placeholder and argument for a cursor value.
+func (k sortKey[T]) placeholder(db *gorm.DB, v string) (string, interface{}, error) {
+	switch k.kind {
+	case kindNumeric:
+		return "CAST(? AS NUMERIC)", v, nil
+	case kindWei:
+		if _, err := core.ParseWei(v); err != nil {
+			return "", nil, core.ErrInvalidCursor
+		}
+		if isSQLite(db) {
+			return weiSQL(db, "?"), v, nil
+		}
+		return "CAST(? AS NUMERIC)", v, nil
+	case kindTime:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return "", nil, core.ErrInvalidCursor
+		}
+		return "?", t, nil
+	default:
+		return "?", v, nil
+	}
+}
+
+// pageSpec is a page request resolved against a listQuery.
+type pageSpec[T any] struct {
+	sortName string
+	key      sortKey[T]
+	desc     bool
+	limit    int
+	// after is the decoded cursor, nil on the first page.
+	after *cursor
+}
+
+func (q listQuery[T]) resolve(page core.PageRequest) (pageSpec[T], error) {
+	spec := pageSpec[T]{sortName: page.Sort}
+	if spec.sortName == "" {
+		spec.sortName = q.defaultSort
+	}
+	name := strings.TrimPrefix(spec.sortName, "-")
+	spec.desc = strings.HasPrefix(spec.sortName, "-")
+	key, ok := q.keys[name]
+	if !ok {
+		return spec, core.ErrInvalidSort.Withf("unsupported sort %q", spec.sortName)
+	}
+	spec.key = key
+
+	spec.limit = page.Limit
+	if spec.limit <= 0 {
+		spec.limit = core.DefaultPageLimit
+	}
+	if spec.limit > core.MaxPageLimit {
+		spec.limit = core.MaxPageLimit
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return spec, err
+		}
+		if c.Sort != spec.sortName {
+			return spec, core.ErrInvalidCursor.Withf("cursor was issued for sort %q", c.Sort)
+		}
+		spec.after = &c
+	}
+	return spec, nil
+}
+
+// result turns up to limit+1 rows, in order, into a page.
+func (q listQuery[T]) result(spec pageSpec[T], items []T) *core.Page[T] {
+	result := &core.Page[T]{Items: items}
+	if len(items) > spec.limit {
+		result.Items = items[:spec.limit]
+		last := &result.Items[spec.limit-1]
+		result.NextCursor = encodeCursor(cursor{Sort: spec.sortName, Value: spec.key.value(last), ID: q.id(last)})
+	}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	return result
+}
+
+func (q listQuery[T]) find(db *gorm.DB, page core.PageRequest) (*core.Page[T], error) {
+	spec, err := q.resolve(page)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, cmp := "ASC", ">"
+	if spec.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	expr := spec.key.sql(db)
+	if spec.after != nil {
+		ph, v, err := spec.key.placeholder(db, spec.after.Value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(fmt.Sprintf("(%s, %s) %s (%s, ?)", expr, q.idColumn, cmp, ph), v, spec.after.ID)
+	}
+
+	var items []T
+	err = db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                fmt.Sprintf("%s %s, %s %s", expr, dir, q.idColumn, dir),
+		WithoutParentheses: true,
+	}}).Limit(spec.limit + 1).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return q.result(spec, items), nil
+}
+
+// Unranked tokens (rank 0) sort after every ranked one.
+const rarityRankExpr = "CASE WHEN %s = 0 THEN 2147483647 ELSE %s END"
+
+func rarityValue(rank int) string {
+	if rank == 0 {
+		return "2147483647"
+	}
+	return fmt.Sprint(rank)
+}
+
+var listingQuery = listQuery[core.Listing]{
+	idColumn:    "listing.id",
+	id:          func(l *core.Listing) uint { return l.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.Listing]{
+		"created_at": {
+			expr:  "listing.created_at",
+			kind:  kindTime,
+			value: func(l *core.Listing) string { return formatTime(l.CreatedAt) },
+		},
+		"price": {
+			expr:  "listing.price_wei",
+			kind:  kindWei,
+			value: func(l *core.Listing) string { return l.PriceWei.String() },
+		},
+		"rarity": {
+			expr:  fmt.Sprintf("(SELECT "+rarityRankExpr+" FROM nft WHERE nft.id = listing.nft_id)", "nft.rarity_rank", "nft.rarity_rank"),
+			kind:  kindNumeric,
+			value: func(l *core.Listing) string { return rarityValue(l.NFT.RarityRank) },
+		},
+	},
+}
+
+var nftQuery = listQuery[core.NFT]{
+	idColumn:    "nft.id",
+	id:          func(n *core.NFT) uint { return n.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.NFT]{
+		"created_at": {
+			expr:  "nft.created_at",
+			kind:  kindTime,
+			value: func(n *core.NFT) string { return formatTime(n.CreatedAt) },
+		},
+		"rarity": {
+			expr:  fmt.Sprintf(rarityRankExpr, "nft.rarity_rank", "nft.rarity_rank"),
+			kind:  kindNumeric,
+			value: func(n *core.NFT) string { return rarityValue(n.RarityRank) },
+		},
+	},
+}
+
+var collectionQuery = listQuery[core.Collection]{
+	idColumn:    "collection.id",
+	id:          func(c *core.Collection) uint { return c.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.Collection]{
+		"created_at": {
+			expr:  "collection.created_at",
+			kind:  kindTime,
+			value: func(c *core.Collection) string { return formatTime(c.CreatedAt) },
+		},
+		"name": {
+			expr:  "collection.name",
+			kind:  kindText,
+			value: func(c *core.Collection) string { return c.Name },
+		},
+	},
+}
+
+var userQuery = listQuery[core.User]{
+	idColumn:    `"user".id`,
+	id:          func(u *core.User) uint { return u.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.User]{
+		"created_at": {
+			expr:  `"user".created_at`,
+			kind:  kindTime,
+			value: func(u *core.User) string { return formatTime(u.CreatedAt) },
+		},
+	},
+}
+
+var auditLogQuery = listQuery[core.AuditLog]{
+	idColumn:    "audit_log.id",
+	id:          func(a *core.AuditLog) uint { return a.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.AuditLog]{
+		"created_at": {
+			expr:  "audit_log.created_at",
+			kind:  kindTime,
+			value: func(a *core.AuditLog) string { return formatTime(a.CreatedAt) },
+		},
+	},
+}
+
+var deliveryQuery = listQuery[core.WebhookDelivery]{
+	idColumn:    "webhook_delivery.id",
+	id:          func(d *core.WebhookDelivery) uint { return d.ID },
+	defaultSort: "-created_at",
+	keys: map[string]sortKey[core.WebhookDelivery]{
+		"created_at": {
+			expr:  "webhook_delivery.created_at",
+			kind:  kindTime,
+			value: func(d *core.WebhookDelivery) string { return formatTime(d.CreatedAt) },
+		},
+	},
+}
+
+var reconciliationRunQuery = listQuery[core.ReconciliationRun]{
+	idColumn:    "reconciliation_run.id",
+	id:          func(r *core.ReconciliationRun) uint { return r.ID },
+	defaultSort: "-started_at",
+	keys: map[string]sortKey[core.ReconciliationRun]{
+		"started_at": {
+			expr:  "reconciliation_run.started_at",
+			kind:  kindTime,
+			value: func(r *core.ReconciliationRun) string { return formatTime(r.StartedAt) },
+		},
+	},
+}
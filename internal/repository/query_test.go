@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := cursor{Sort: "-price", Value: "1500000000000000000", ID: 42}
+	got, err := decodeCursor(encodeCursor(c))
+	if err != nil || got != c {
+		t.Fatalf("decoded %+v, %v; want %+v", got, err, c)
+	}
+
+	spec, err := listingQuery.resolve(core.PageRequest{Sort: "-price", Cursor: encodeCursor(c), Limit: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spec.desc || spec.limit != core.MaxPageLimit || spec.after == nil || *spec.after != c {
+		t.Fatalf("resolved %+v", spec)
+	}
+}
+
+func TestResolveRejectsTamperedCursor(t *testing.T) {
+	for name, cur := range map[string]string{
+		"not base64":        "!!!",
+		"not JSON":          base64.RawURLEncoding.EncodeToString([]byte("price:100")),
+		"issued for a sort": encodeCursor(cursor{Sort: "price", Value: "100", ID: 1}),
+	} {
+		if _, err := listingQuery.resolve(core.PageRequest{Cursor: cur}); !errors.Is(err, core.ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want invalid_cursor", name, err)
+		}
+	}
+	if _, err := listingQuery.resolve(core.PageRequest{Sort: "seller"}); !errors.Is(err, core.ErrInvalidSort) {
+		t.Errorf("unsupported sort: err = %v, want invalid_sort", err)
+	}
+}
+
+// Pages follow each other without repeating or skipping listings, also
+// across equal prices, and a cursor with a forged value is refused.
+func TestListActiveListingsPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		prices := []int64{300, 100, 200, 100, 500}
+		for i, price := range prices {
+			seedListing(t, s, seedNFT(t, s, collection, owner, i+1), price)
+		}
+
+		for _, sort := range []string{"price", "-price"} {
+			var got []string
+			seen := make(map[uint]bool)
+			page := core.PageRequest{Sort: sort, Limit: 2}
+			for {
+				res, err := s.ListActiveListings(core.ListingFilter{}, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, l := range res.Items {
+					if seen[l.ID] {
+						t.Fatalf("%s: listing %d on two pages", sort, l.ID)
+					}
+					seen[l.ID] = true
+					got = append(got, l.PriceWei.String())
+				}
+				if res.NextCursor == "" {
+					break
+				}
+				page.Cursor = res.NextCursor
+			}
+			want := []string{"100", "100", "200", "300", "500"}
+			if sort == "-price" {
+				want = []string{"500", "300", "200", "100", "100"}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: prices %v, want %v", sort, got, want)
+			}
+		}
+
+		forged := encodeCursor(cursor{Sort: "price", Value: "100 OR 1=1", ID: 1})
+		if _, err := s.ListActiveListings(core.ListingFilter{}, core.PageRequest{Sort: "price", Cursor: forged}); !errors.Is(err, core.ErrInvalidCursor) {
+			t.Fatalf("forged cursor value: err = %v, want invalid_cursor", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestRarityStaleUntilClaimed(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		seedNFT(t, s, collection, owner, 1, "Color", "Red")
+		seedNFT(t, s, collection, owner, 2, "Color", "Blue")
+
+		ids, err := s.ClaimStaleRarity(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids, []uint{collection.ID}) {
+			t.Fatalf("claimed %v, want [%d]", ids, collection.ID)
+		}
+		if ids, _ := s.ClaimStaleRarity(10); len(ids) != 0 {
+			t.Fatalf("claimed %v again", ids)
+		}
+
+		if err := s.MarkRarityStale(collection.ID); err != nil {
+			t.Fatal(err)
+		}
+		if ids, _ := s.ClaimStaleRarity(10); len(ids) != 1 {
+			t.Fatalf("claimed %v after marking stale", ids)
+		}
+	})
+}
+
+func TestUpdateNFTRarity(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		owner, collection := seedCollection(t, s)
+		scores, ranks := map[uint]float64{}, map[uint]int{}
+		for i := 1; i <= 3; i++ {
+			nft := seedNFT(t, s, collection, owner, i)
+			scores[nft.ID], ranks[nft.ID] = float64(i)/4, 4-i
+		}
+		if err := s.UpdateNFTRarity(scores, ranks); err != nil {
+			t.Fatal(err)
+		}
+
+		nfts, err := s.ListCollectionNFTs(collection.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, nft := range nfts {
+			if nft.RarityScore != scores[nft.ID] || nft.RarityRank != ranks[nft.ID] {
+				t.Errorf("nft %d: score %v rank %d, want %v and %d",
+					nft.ID, nft.RarityScore, nft.RarityRank, scores[nft.ID], ranks[nft.ID])
+			}
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Reconciliation methods
+
+// ListChainNFTs returns up to limit unburned NFTs of contract on chain with
+// ids after afterID, oldest first, with their owners.
+func (r *Repository) ListChainNFTs(chain, contract string, afterID uint, limit int) ([]core.NFT, error) {
+	var nfts []core.NFT
+	err := r.db.Preload("Owner").
+		Where("chain = ? AND LOWER(contract_address) = LOWER(?) AND burned_at IS NULL AND id > ?", chain, contract, afterID).
+		Order("id").Limit(limit).Find(&nfts).Error
+	return nfts, err
+}
+
+func (r *Repository) CreateReconciliationRun(run *core.ReconciliationRun) error {
+	return r.db.Omit("Drifts").Create(run).Error
+}
+
+func (r *Repository) FinishReconciliationRun(run *core.ReconciliationRun) error {
+	now := time.Now()
+	run.FinishedAt = &now
+	return r.db.Model(run).Omit("Drifts").Select("*").Updates(run).Error
+}
+
+func (r *Repository) ListReconciliationRuns(page core.PageRequest) (*core.Page[core.ReconciliationRun], error) {
+	return reconciliationRunQuery.find(r.db.Model(&core.ReconciliationRun{}), page)
+}
+
+func (r *Repository) GetReconciliationRun(id uint) (*core.ReconciliationRun, error) {
+	var run core.ReconciliationRun
+	err := r.db.Preload("Drifts", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&run, id).Error
+	if err != nil {
+		return nil, notFound(err, "reconciliation_run")
+	}
+	return &run, nil
+}
+
+// RecordDrift reports drift left as it is.
+func (r *Repository) RecordDrift(drift *core.OwnershipDrift) error {
+	return r.db.Create(drift).Error
+}
+
+// FixTransferredNFT records the NFT of drift as owned by newOwnerID, as a
+// transfer, and cancels its listings by anyone else. It returns how many
+// listings were cancelled; an NFT whose owner changed since it was read is
+// left alone.
+func (r *Repository) FixTransferredNFT(drift *core.OwnershipDrift, newOwnerID uint) (int, error) {
+	var cancelled int
+	err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		res := tx.Model(&core.NFT{}).
+			Where("id = ? AND owner_user_id = ? AND burned_at IS NULL", drift.NFTID, drift.RecordedOwnerID).
+			Updates(bumpVersion(map[string]interface{}{"owner_user_id": newOwnerID}))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return core.InvalidState("nft_changed", "nft changed while it was reconciled")
+		}
+
+		var nft core.NFT
+		if err := tx.First(&nft, drift.NFTID).Error; err != nil {
+			return err
+		}
+		if err := events.record(&core.Event{
+			Type:               core.EventTransferIndexed,
+			CollectionID:       nft.CollectionID,
+			NFTID:              nft.ID,
+			UserID:             drift.RecordedOwnerID,
+			CounterpartyUserID: newOwnerID,
+			Data:               map[string]interface{}{"token_id": nft.TokenID, "to": drift.ChainOwner, "source": "reconciliation"},
+		}); err != nil {
+			return err
+		}
+
+		n, err := cancelListingsOf(tx, events, drift.NFTID, newOwnerID, "seller no longer owns the token")
+		if err != nil {
+			return err
+		}
+		cancelled = n
+		drift.Fixed = true
+		drift.NewOwnerID = &newOwnerID
+		return tx.Create(drift).Error
+	})
+	return cancelled, err
+}
+
+// FixBurnedNFT burns the NFT of drift, which no longer exists on chain,
+// cancelling its listings. It returns how many listings were cancelled.
+func (r *Repository) FixBurnedNFT(drift *core.OwnershipDrift) (int, error) {
+	var cancelled int64
+	err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		if err := tx.Model(&core.Listing{}).Where("nft_id = ? AND status IN ?", drift.NFTID, openListingStatuses).
+			Count(&cancelled).Error; err != nil {
+			return err
+		}
+		if err := burnNFT(tx, events, drift.NFTID); err != nil {
+			return err
+		}
+		drift.Fixed = true
+		return tx.Create(drift).Error
+	})
+	return int(cancelled), err
+}
+
+func (r *Repository) CancelUnapprovedListings(nftID, ownerID uint) (int, error) {
+	var cancelled int
+	err := r.transaction(func(tx *gorm.DB, events *eventLog) error {
+		var listings []core.Listing
+		if err := tx.Where("nft_id = ? AND status IN ? AND seller_user_id = ?", nftID, openListingStatuses, ownerID).
+			Find(&listings).Error; err != nil {
+			return err
+		}
+		for i := range listings {
+			if err := cancelListing(tx, events, &listings[i], "marketplace is no longer approved for the token"); err != nil {
+				return err
+			}
+		}
+		cancelled = len(listings)
+		return nil
+	})
+	return cancelled, err
+}
+
+// cancelListingsOf cancels the open listings of an NFT by sellers other
+// than ownerID and returns how many it cancelled.
+func cancelListingsOf(tx *gorm.DB, events *eventLog, nftID, ownerID uint, reason string) (int, error) {
+	var listings []core.Listing
+	if err := tx.Where("nft_id = ? AND status IN ? AND seller_user_id <> ?", nftID, openListingStatuses, ownerID).
+		Find(&listings).Error; err != nil {
+		return 0, err
+	}
+	for i := range listings {
+		if err := cancelListing(tx, events, &listings[i], reason); err != nil {
+			return 0, err
+		}
+	}
+	return len(listings), nil
+}
+
+// cancelListing cancels a listing read in tx, failing if it changed since.
+func cancelListing(tx *gorm.DB, events *eventLog, listing *core.Listing, reason string) error {
+	res := tx.Model(&core.Listing{}).Where("id = ? AND version = ?", listing.ID, listing.Version).
+		Updates(bumpVersion(map[string]interface{}{"status": core.ListingCancelled}))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return concurrentUpdate("listing")
+	}
+	e, err := listingEvent(tx, core.EventListingCancelled, listing, 0, map[string]interface{}{"reason": reason})
+	if err != nil {
+		return err
+	}
+	return events.record(e)
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/textsearch"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The documents searched. On Postgres they are matched as tsvectors, and the
+// expressions must match the GIN indexes created by the initial migration,
+// internal/db/migrations/0001_initial.up.sql.
+const (
+	nftDocument        = `coalesce(nft.name, '') || ' ' || coalesce(nft.description, '')`
+	collectionDocument = `coalesce(collection.name, '') || ' ' || coalesce(collection.symbol, '')`
+	creatorDocument    = `coalesce(creator.name, '')`
+
+	// Minimum pg_trgm word similarity for a fuzzy (typo-tolerant) match.
+	fuzzyThreshold = 0.3
+)
+
+// Price bucket boundaries in wei used for the price facet: 0.01, 0.1, 1 and 10 ETH.
+var priceBucketBounds = []string{
+	"10000000000000000",
+	"100000000000000000",
+	"1000000000000000000",
+	"10000000000000000000",
+}
+
+func priceBuckets() []core.PriceBucket {
+	buckets := make([]core.PriceBucket, 0, len(priceBucketBounds)+1)
+	lower := core.Wei{}
+	for _, b := range priceBucketBounds {
+		upper, _ := core.ParseWei(b)
+		buckets = append(buckets, core.PriceBucket{Min: lower, Max: &upper})
+		lower = upper
+	}
+	return append(buckets, core.PriceBucket{Min: lower})
+}
+
+// prefixQuery turns free text into a tsquery matching every word as a prefix,
+// e.g. "bored ap" becomes "bored:* & ap:*".
+func prefixQuery(text string) string {
+	words := textsearch.Words(text)
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// searchArgs are the named arguments of the search conditions for text.
+func searchArgs(text string) map[string]interface{} {
+	return map[string]interface{}{"tsq": prefixQuery(text), "text": text, "threshold": fuzzyThreshold}
+}
+
+// matchSQL is the condition that document has a word starting with each
+// word of @text. SQLite has no full-text search: there db provides
+// search_prefix_match and word_similarity from textsearch.
+func (r *Repository) matchSQL(document string) string {
+	if isSQLite(r.db) {
+		return "search_prefix_match(@text, " + document + ")"
+	}
+	return "to_tsvector('simple', " + document + ") @@ to_tsquery('simple', @tsq)"
+}
+
+// rankSQL ranks how well document matches @text; on SQLite it is 1 for a
+// match and 0 otherwise.
+func (r *Repository) rankSQL(document string) string {
+	if isSQLite(r.db) {
+		return "search_prefix_match(@text, " + document + ")"
+	}
+	return "ts_rank(to_tsvector('simple', " + document + "), to_tsquery('simple', @tsq))"
+}
+
+func orderByExpr(sql string, args map[string]interface{}) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.NamedExpr{SQL: sql, Vars: []interface{}{args}}}
+}
+
+// searchScope selects the live NFTs matching q, joined with their collection,
+// its creator and the NFT's active listing (if any) as "al".
+func (r *Repository) searchScope(q core.SearchQuery) *gorm.DB {
+	db := r.db.Table("nft").
+		Joins("JOIN collection ON collection.id = nft.collection_id").
+		Joins(`LEFT JOIN "user" creator ON creator.id = collection.creator_user_id`).
+		Joins(`LEFT JOIN (
+			SELECT nft_id, price_wei FROM (
+				SELECT nft_id, price_wei, ROW_NUMBER() OVER (PARTITION BY nft_id ORDER BY created_at DESC) AS n
+				FROM listing WHERE status = ?
+			) latest WHERE n = 1
+		) al ON al.nft_id = nft.id`, core.ListingActive).
+		Where("nft.burned_at IS NULL")
+
+	if prefixQuery(q.Text) != "" {
+		db = db.Where(
+			"("+r.matchSQL(nftDocument)+" OR "+
+				r.matchSQL(collectionDocument)+" OR "+
+				r.matchSQL(creatorDocument)+" OR "+
+				"word_similarity(@text, coalesce(nft.name, '')) > @threshold OR "+
+				"word_similarity(@text, collection.name) > @threshold)",
+			searchArgs(q.Text),
+		)
+	}
+	if q.CollectionID != 0 {
+		db = db.Where("nft.collection_id = ?", q.CollectionID)
+	}
+	if q.Chain != "" {
+		db = db.Where("nft.chain = ?", q.Chain)
+	}
+	switch q.Status {
+	case "listed":
+		db = db.Where("al.nft_id IS NOT NULL")
+	case "unlisted":
+		db = db.Where("al.nft_id IS NULL")
+	}
+	for _, t := range q.Traits {
+		db = db.Where("EXISTS (SELECT 1 FROM nft_attribute a WHERE a.nft_id = nft.id AND a.trait_type = ? AND a.value = ?)", t.TraitType, t.Value)
+	}
+	if q.MinPrice != nil {
+		db = db.Where(weiCondition(r.db, "al.price_wei", ">="), *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		db = db.Where(weiCondition(r.db, "al.price_wei", "<="), *q.MaxPrice)
+	}
+	return db
+}
+
+func (r *Repository) Search(q core.SearchQuery) (*core.SearchResult, error) {
+	result := &core.SearchResult{}
+
+	scope := r.searchScope(q)
+	if prefixQuery(q.Text) != "" {
+		scope = scope.Order(orderByExpr(
+			r.rankSQL(nftDocument)+" + word_similarity(@text, coalesce(nft.name, '')) DESC", searchArgs(q.Text),
+		))
+	}
+	var ids []uint
+	if err := scope.Order("nft.id DESC").Limit(q.Limit).Pluck("nft.id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		var nfts []core.NFT
+		if err := r.db.Preload("Collection").Preload("Attributes").Where("id IN ?", ids).Find(&nfts).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]core.NFT, len(nfts))
+		for _, n := range nfts {
+			byID[n.ID] = n
+		}
+		for _, id := range ids {
+			result.NFTs = append(result.NFTs, byID[id])
+		}
+	}
+
+	collections, err := r.searchCollections(q.Text, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	result.Collections = collections
+
+	if err := r.searchFacets(q, &result.Facets); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Repository) searchCollections(text string, limit int) ([]core.Collection, error) {
+	var collections []core.Collection
+	if prefixQuery(text) == "" {
+		return collections, nil
+	}
+	err := r.db.Table("collection").Select("collection.*").
+		Where("("+r.matchSQL(collectionDocument)+" OR word_similarity(@text, collection.name) > @threshold)", searchArgs(text)).
+		Order(orderByExpr("word_similarity(@text, collection.name) DESC", searchArgs(text))).Order("collection.id").
+		Limit(limit).Find(&collections).Error
+	return collections, err
+}
+
+func (r *Repository) searchFacets(q core.SearchQuery, facets *core.Facets) error {
+	matching := func() *gorm.DB { return r.searchScope(q).Select("nft.id") }
+
+	if err := r.db.Table("nft").
+		Select("CAST(nft.collection_id AS TEXT) AS value, collection.name AS label, COUNT(*) AS count").
+		Joins("JOIN collection ON collection.id = nft.collection_id").
+		Where("nft.id IN (?)", matching()).
+		Group("nft.collection_id, collection.name").Order("count DESC").
+		Scan(&facets.Collections).Error; err != nil {
+		return err
+	}
+
+	if err := r.db.Table("nft").
+		Select("nft.chain AS value, COUNT(*) AS count").
+		Where("nft.id IN (?)", matching()).
+		Group("nft.chain").Order("count DESC").
+		Scan(&facets.Chains).Error; err != nil {
+		return err
+	}
+
+	if err := r.db.Table("nft_attribute").
+		Select("trait_type, value, COUNT(*) AS count").
+		Where("nft_id IN (?)", matching()).
+		Group("trait_type, value").Order("trait_type, count DESC").
+		Scan(&facets.Traits).Error; err != nil {
+		return err
+	}
+
+	if err := r.searchScope(q).
+		Select("CASE WHEN al.nft_id IS NULL THEN 'unlisted' ELSE 'listed' END AS value, COUNT(*) AS count").
+		Group("value").Order("value").
+		Scan(&facets.ListingStatus).Error; err != nil {
+		return err
+	}
+
+	for _, b := range priceBuckets() {
+		bucket := r.searchScope(q).Where("al.nft_id IS NOT NULL").Where(weiCondition(r.db, "al.price_wei", ">="), b.Min)
+		if b.Max != nil {
+			bucket = bucket.Where(weiCondition(r.db, "al.price_wei", "<"), *b.Max)
+		}
+		if err := bucket.Count(&b.Count).Error; err != nil {
+			return err
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, b)
+	}
+	return nil
+}
+
+// Suggest returns NFT and collection names for the search box, matching
+// word prefixes and tolerating small typos.
+func (r *Repository) Suggest(text string, limit int) ([]core.Suggestion, error) {
+	if prefixQuery(text) == "" {
+		return []core.Suggestion{}, nil
+	}
+
+	args := searchArgs(text)
+	args["limit"] = limit
+	var suggestions []core.Suggestion
+	err := r.db.Raw(`
+		SELECT type, id, label FROM (
+			SELECT 'collection' AS type, collection.id, collection.name AS label,
+				word_similarity(@text, collection.name) AS score
+			FROM collection
+			WHERE `+r.matchSQL(collectionDocument)+` OR word_similarity(@text, collection.name) > @threshold
+			UNION ALL
+			SELECT 'nft' AS type, nft.id, nft.name AS label,
+				word_similarity(@text, coalesce(nft.name, '')) AS score
+			FROM nft
+			WHERE nft.burned_at IS NULL AND nft.name <> ''
+				AND (`+r.matchSQL(nftDocument)+` OR word_similarity(@text, coalesce(nft.name, '')) > @threshold)
+		) s
+		ORDER BY score DESC, type, id
+		LIMIT @limit`,
+		args,
+	).Scan(&suggestions).Error
+	return suggestions, err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// seedSearch registers a Golden Ape listed for 2 ETH, an unlisted Silver
+// Ape and a Robot Cat listed for 0.05 ETH.
+func seedSearch(t *testing.T, s core.Store) (golden, silver, robot *core.NFT) {
+	t.Helper()
+	owner, collection := seedCollection(t, s)
+	named := func(tokenID int, name, color string, listedFor string) *core.NFT {
+		nft := &core.NFT{TokenID: fmt.Sprint(tokenID), ContractAddress: "0x00000000000000000000000000000000000000c1",
+			Chain: "test", CollectionID: collection.ID, OwnerUserID: owner.ID, Name: name,
+			Attributes: []core.NFTAttribute{{TraitType: "Color", Value: color}}}
+		if err := s.CreateNFT(nft); err != nil {
+			t.Fatal(err)
+		}
+		if listedFor != "" {
+			price, _ := new(big.Int).SetString(listedFor, 10)
+			listing := &core.Listing{NFTID: nft.ID, SellerUserID: owner.ID, PriceWei: core.NewWei(price)}
+			if err := s.CreateListing(listing); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return nft
+	}
+	return named(1, "Golden Ape", "Gold", "2000000000000000000"),
+		named(2, "Silver Ape", "Silver", ""),
+		named(3, "Robot Cat", "Gold", "50000000000000000")
+}
+
+func nftIDs(nfts []core.NFT) []uint {
+	ids := []uint{}
+	for _, n := range nfts {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		golden, silver, robot := seedSearch(t, s)
+		min, _ := core.ParseWei("100000000000000000")
+
+		tests := []struct {
+			name string
+			q    core.SearchQuery
+			want []uint
+		}{
+			{"word prefix", core.SearchQuery{Text: "gold"}, []uint{golden.ID}},
+			// The Golden Ape is similar enough to follow
+			{"every word first", core.SearchQuery{Text: "ape silv"}, []uint{silver.ID, golden.ID}},
+			{"typo", core.SearchQuery{Text: "robto"}, []uint{robot.ID}},
+			{"listed", core.SearchQuery{Status: "listed"}, []uint{robot.ID, golden.ID}},
+			{"unlisted", core.SearchQuery{Status: "unlisted"}, []uint{silver.ID}},
+			{"trait", core.SearchQuery{Traits: []core.NFTAttribute{{TraitType: "Color", Value: "Gold"}}}, []uint{robot.ID, golden.ID}},
+			{"minimum price", core.SearchQuery{MinPrice: &min}, []uint{golden.ID}},
+			{"no match", core.SearchQuery{Text: "zebra"}, []uint{}},
+		}
+		for _, tt := range tests {
+			tt.q.Limit = 10
+			res, err := s.Search(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nftIDs(res.NFTs); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("%s: found %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestSearchFacets(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		seedSearch(t, s)
+		res, err := s.Search(core.SearchQuery{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := res.Facets
+
+		status := map[string]int64{}
+		for _, c := range f.ListingStatus {
+			status[c.Value] = c.Count
+		}
+		if status["listed"] != 2 || status["unlisted"] != 1 {
+			t.Errorf("listing status facet %+v", f.ListingStatus)
+		}
+		traits := map[string]int64{}
+		for _, c := range f.Traits {
+			traits[c.TraitType+":"+c.Value] = c.Count
+		}
+		if traits["Color:Gold"] != 2 || traits["Color:Silver"] != 1 {
+			t.Errorf("trait facet %+v", f.Traits)
+		}
+		if len(f.Collections) != 1 || f.Collections[0].Count != 3 || len(f.Chains) != 1 || f.Chains[0].Value != "test" {
+			t.Errorf("collection facet %+v, chain facet %+v", f.Collections, f.Chains)
+		}
+
+		// 0.05 ETH falls in [0.01, 0.1) and 2 ETH in [1, 10)
+		var counts []int64
+		for _, b := range f.PriceBuckets {
+			counts = append(counts, b.Count)
+		}
+		if fmt.Sprint(counts) != "[0 1 0 1 0]" {
+			t.Errorf("price bucket counts %v, want [0 1 0 1 0]", counts)
+		}
+	})
+}
+
+func TestSuggest(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s core.Store) {
+		golden, _, _ := seedSearch(t, s)
+		suggestions, err := s.Suggest("gol", 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(suggestions) != 1 || suggestions[0].Type != "nft" || suggestions[0].ID != golden.ID || suggestions[0].Label != "Golden Ape" {
+			t.Fatalf("suggestions %+v, want the Golden Ape", suggestions)
+		}
+		if suggestions, err := s.Suggest("  ", 5); err != nil || len(suggestions) != 0 {
+			t.Fatalf("suggestions for blank text: %+v, %v", suggestions, err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/db/dbtest"
+)
+
+// forEachStore runs test against MemoryRepository and against Repository on
+// a new SQLite database and, when TEST_POSTGRES_DB is set, on Postgres.
+func forEachStore(t *testing.T, test func(t *testing.T, s core.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepository(nil))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, NewRepository(dbtest.SQLite(t), nil))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, NewRepository(dbtest.Postgres(t), nil))
+	})
+}
+
+// seedUser creates a user with the given wallet.
+func seedUser(t *testing.T, s core.Store, wallet string) *core.User {
+	t.Helper()
+	user := &core.User{WalletAddress: wallet}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// seedCollection creates a user and a collection of theirs.
+func seedCollection(t *testing.T, s core.Store) (*core.User, *core.Collection) {
+	t.Helper()
+	user := &core.User{WalletAddress: "0x00000000000000000000000000000000000000a1", Name: "owner"}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	collection := &core.Collection{CreatorUserID: user.ID, Name: "Test", Symbol: "TST"}
+	if err := s.CreateCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+	return user, collection
+}
+
+// seedNFT registers token tokenID of collection, owned by owner, with attrs
+// given as trait type and value pairs.
+func seedNFT(t *testing.T, s core.Store, collection *core.Collection, owner *core.User, tokenID int, attrs ...string) *core.NFT {
+	t.Helper()
+	nft := &core.NFT{
+		TokenID:         fmt.Sprint(tokenID),
+		ContractAddress: "0x00000000000000000000000000000000000000c1",
+		Chain:           "test",
+		CollectionID:    collection.ID,
+		OwnerUserID:     owner.ID,
+		Name:            fmt.Sprintf("Token %d", tokenID),
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		nft.Attributes = append(nft.Attributes, core.NFTAttribute{TraitType: attrs[i], Value: attrs[i+1]})
+	}
+	if err := s.CreateNFT(nft); err != nil {
+		t.Fatal(err)
+	}
+	return nft
+}
+
+// seedListing lists nft by its owner for price wei.
+func seedListing(t *testing.T, s core.Store, nft *core.NFT, price int64) *core.Listing {
+	t.Helper()
+	listing := &core.Listing{NFTID: nft.ID, SellerUserID: nft.OwnerUserID, PriceWei: core.NewWei(big.NewInt(price))}
+	if err := s.CreateListing(listing); err != nil {
+		t.Fatal(err)
+	}
+	return listing
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"gorm.io/gorm"
+)
+
+// Webhook methods
+func (r *Repository) CreateWebhook(hook *core.Webhook) error {
+	return r.db.Create(hook).Error
+}
+
+// ownedBy limits a webhook query to the webhooks owner may manage.
+func ownedBy(query *gorm.DB, owner core.WebhookOwner) *gorm.DB {
+	query = query.Where("webhook.user_id = ?", owner.UserID)
+	if owner.APIKeyID != 0 {
+		query = query.Where("webhook.api_key_id = ?", owner.APIKeyID)
+	}
+	return query
+}
+
+// liveWebhookIDs selects the webhooks that receive events: those of no API
+// key, or of one neither revoked nor expired.
+func liveWebhookIDs(db *gorm.DB, now time.Time) *gorm.DB {
+	keys := db.Model(&core.APIKey{}).Select("id").
+		Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+	return db.Model(&core.Webhook{}).Select("id").Where("api_key_id IS NULL OR api_key_id IN (?)", keys)
+}
+
+func (r *Repository) CountWebhooks(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&core.Webhook{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *Repository) GetWebhook(owner core.WebhookOwner, id uint) (*core.Webhook, error) {
+	var hook core.Webhook
+	if err := ownedBy(r.db, owner).First(&hook, id).Error; err != nil {
+		return nil, notFound(err, "webhook")
+	}
+	return &hook, nil
+}
+
+func (r *Repository) ListWebhooks(owner core.WebhookOwner) ([]core.Webhook, error) {
+	var hooks []core.Webhook
+	if err := ownedBy(r.db, owner).Order("id DESC").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook deletes a webhook with its deliveries and their history.
+func (r *Repository) DeleteWebhook(owner core.WebhookOwner, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := ownedBy(tx, owner).Where("id = ?", id).Delete(&core.Webhook{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return core.NotFound("webhook")
+		}
+		deliveries := tx.Model(&core.WebhookDelivery{}).Select("id").Where("webhook_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&core.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&core.WebhookDelivery{}).Error
+	})
+}
+
+// Webhook delivery methods
+func (r *Repository) ListWebhookDeliveries(webhookID uint, filter core.DeliveryFilter, page core.PageRequest) (*core.Page[core.WebhookDelivery], error) {
+	query := r.db.Model(&core.WebhookDelivery{}).
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("webhook_delivery.webhook_id = ?", webhookID)
+	if filter.Status != "" {
+		query = query.Where("webhook_delivery.status = ?", filter.Status)
+	}
+	return deliveryQuery.find(query, page)
+}
+
+func (r *Repository) GetWebhookDelivery(owner core.WebhookOwner, id uint) (*core.WebhookDelivery, error) {
+	var delivery core.WebhookDelivery
+	hooks := ownedBy(r.db.Model(&core.Webhook{}).Select("id"), owner)
+	if err := r.db.Where("webhook_id IN (?)", hooks).First(&delivery, id).Error; err != nil {
+		return nil, notFound(err, "webhook_delivery")
+	}
+	return &delivery, nil
+}
+
+func (r *Repository) CreateWebhookDelivery(delivery *core.WebhookDelivery) error {
+	return r.db.Omit("Webhook", "History").Create(delivery).Error
+}
+
+// DispatchEvents queues a delivery of each undispatched event, oldest first,
+// to every live webhook subscribed to it and marks the events dispatched. It
+// handles up to limit events and returns how many it did.
+func (r *Repository) DispatchEvents(limit int) (int, error) {
+	var dispatched int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var events []core.Event
+		if err := tx.Clauses(skipLocked).Where("dispatched_at IS NULL").
+			Order("id").Limit(limit).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		var hooks []core.Webhook
+		if err := tx.Where("id IN (?)", liveWebhookIDs(tx, now)).Find(&hooks).Error; err != nil {
+			return err
+		}
+
+		var deliveries []core.WebhookDelivery
+		ids := make([]uint, 0, len(events))
+		for i := range events {
+			e := &events[i]
+			ids = append(ids, e.ID)
+			payload, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			for j := range hooks {
+				if !hooks[j].Matches(e) {
+					continue
+				}
+				deliveries = append(deliveries, core.WebhookDelivery{
+					WebhookID:     hooks[j].ID,
+					EventID:       e.ID,
+					EventType:     e.Type,
+					Payload:       string(payload),
+					Status:        core.DeliveryPending,
+					NextAttemptAt: now,
+				})
+			}
+		}
+		if len(deliveries) > 0 {
+			if err := tx.Omit("Webhook", "History").CreateInBatches(deliveries, 100).Error; err != nil {
+				return err
+			}
+		}
+		dispatched = len(events)
+		return tx.Model(&core.Event{}).Where("id IN ?", ids).Update("dispatched_at", now).Error
+	})
+	return dispatched, err
+}
+
+// ClaimWebhookDeliveries leases up to limit due deliveries to live webhooks
+// for lease, during which other workers pass over them. A delivery whose
+// worker dies is retried once its lease runs out.
+func (r *Repository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]core.WebhookDelivery, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var due []core.WebhookDelivery
+		if err := tx.Clauses(skipLocked).Select("id").
+			Where("status = ? AND next_attempt_at <= ?", core.DeliveryPending, now).
+			Where("webhook_id IN (?)", liveWebhookIDs(tx, now)).
+			Order("next_attempt_at").Limit(limit).Find(&due).Error; err != nil {
+			return err
+		}
+		for _, d := range due {
+			ids = append(ids, d.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&core.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []core.WebhookDelivery
+	if err := r.db.Preload("Webhook").Where("id IN ?", ids).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt logs an attempt at delivery and saves the delivery's
+// resulting state.
+func (r *Repository) RecordWebhookAttempt(delivery *core.WebhookDelivery, attempt *core.WebhookAttempt) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&core.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_attempt_at":  delivery.LastAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+		}).Error
+	})
+}
+
+// PruneWebhookDeliveries deletes finished deliveries created before cutoff
+// and their history.
+func (r *Repository) PruneWebhookDeliveries(cutoff time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		finished := tx.Model(&core.WebhookDelivery{}).Select("id").
+			Where("status <> ? AND created_at < ?", core.DeliveryPending, cutoff)
+		if err := tx.Where("delivery_id IN (?)", finished).Delete(&core.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("status <> ? AND created_at < ?", core.DeliveryPending, cutoff).
+			Delete(&core.WebhookDelivery{}).Error
+	})
+}
@@ -6,8 +6,14 @@ import (
 
     "github.com/gin-gonic/gin"
     "github.com/user/nft-marketplace/internal/config"
+    "github.com/user/nft-marketplace/internal/core"
     "github.com/user/nft-marketplace/internal/handler"
+    "github.com/user/nft-marketplace/internal/openapi"
+    "github.com/user/nft-marketplace/internal/platform/events"
+    "github.com/user/nft-marketplace/internal/platform/idempotency"
+    "github.com/user/nft-marketplace/internal/platform/metrics"
     "github.com/user/nft-marketplace/internal/platform/middleware"
+    "github.com/user/nft-marketplace/internal/platform/ratelimit"
     "gorm.io/gorm"
 )
 
@@ -16,54 +22,152 @@ type Server struct {
     Gin     *gin.Engine
     DB      *gorm.DB
     Handler *handler.Handler
+    Auth    middleware.Authenticator
+    Limiter ratelimit.Limiter
+    Idem    idempotency.Store
+    Events  *events.Hub
 }
 
-func NewServer(cfg *config.Config, router *gin.Engine, db *gorm.DB, h *handler.Handler) *Server {
+func NewServer(cfg *config.Config, router *gin.Engine, db *gorm.DB, h *handler.Handler, auth middleware.Authenticator, limiter ratelimit.Limiter, idem idempotency.Store, hub *events.Hub) *Server {
     return &Server{
         Cfg:     cfg,
         Gin:     router,
         DB:      db,
         Handler: h,
+        Auth:    auth,
+        Limiter: limiter,
+        Idem:    idem,
+        Events:  hub,
     }
 }
 
 func (s *Server) Shutdown(ctx context.Context, srv *http.Server) error {
+    // End open event streams, which would otherwise hold up the shutdown
+    s.Events.Close()
     return srv.Shutdown(ctx)
 }
 
+// UndocumentedRoutes are left out of the API documentation: the
+// documentation itself and the Prometheus metrics.
+var UndocumentedRoutes = []string{"/openapi.json", "/docs", "/metrics"}
+
 func ConfigRoutesAndSchedulers(s *Server) {
     // Middleware
     s.Gin.Use(gin.Logger())
     s.Gin.Use(gin.Recovery())
-    s.Gin.Use(middleware.CORS())
+    s.Gin.Use(middleware.Errors())
+    s.Gin.Use(middleware.CORS(s.Cfg.HTTP.CORS))
+    s.Gin.Use(middleware.SecurityHeaders(s.Cfg.HTTP))
+    s.Gin.Use(middleware.BodyLimit(s.Cfg.HTTP.MaxBodyBytes))
 
     h := s.Handler
 
     // Health check
     s.Gin.GET("/health", h.Health)
 
-    v1 := s.Gin.Group("/v1")
+    limit := middleware.RateLimit(s.Limiter, s.Cfg.RateLimit.Limits)
+    read := limit(ratelimit.ClassRead)
+
+    // API documentation
+    s.Gin.GET("/openapi.json", read, openapi.ServeDocument)
+    s.Gin.GET("/docs", read, openapi.ServeDocs)
+
+    // Prometheus metrics
+    s.Gin.GET("/metrics", gin.WrapH(metrics.Handler()))
+    write := limit(ratelimit.ClassWrite)
+    mint := limit(ratelimit.ClassMint)
+    chain := limit(ratelimit.ClassChain)
+
+    idem := middleware.Idempotency(s.Idem, s.Cfg.Idempotency.TTL, s.Cfg.Idempotency.Wait)
+
+    // Credentials are limited per client IP before they are checked, so
+    // failed authentication is limited too
+    authLimit := middleware.WithCredentials(limit(ratelimit.ClassAuth))
+
+    v1 := s.Gin.Group("/v1", authLimit, middleware.Authenticate(s.Auth), idem)
     {
+        // Auth
+        v1.GET("/auth/nonce", read, h.AuthNonce)
+        v1.POST("/auth/verify", write, h.AuthVerify)
+        v1.POST("/auth/refresh", write, h.AuthRefresh)
+
         // Users
-        v1.POST("/users", h.CreateUser)
-        v1.GET("/users/:id", h.GetUser)
+        v1.GET("/users/:id", read, h.GetUser)
 
         // Collections
-        v1.POST("/collections", h.CreateCollection)
-        v1.GET("/collections", h.ListCollections)
+        v1.GET("/collections", read, h.ListCollections)
+        v1.GET("/collections/:id/traits", read, h.ListCollectionTraits)
 
         // NFTs
-        v1.POST("/nfts", h.RegisterNFT)
-        v1.GET("/nfts", h.ListNFTs)
-        v1.POST("/nfts/mint", h.MintNFT)
+        v1.GET("/nfts", read, h.ListNFTs)
+
+        // Search
+        v1.GET("/search", read, h.Search)
+        v1.GET("/search/suggest", read, h.Suggest)
+
+        // Currencies
+        v1.GET("/currencies", read, h.ListCurrencies)
 
         // Listings
-        v1.POST("/listings", h.CreateListing)
-        v1.GET("/listings", h.ListListings)
-        v1.POST("/listings/:id/cancel", h.CancelListing)
+        v1.GET("/listings", read, h.ListListings)
+
+        // Events
+        v1.GET("/events/stream", read, h.StreamEvents)
+        v1.GET("/events/ws", read, middleware.RequireOrigin(s.Cfg.HTTP.CORS), h.EventsWebSocket)
+    }
 
-        // Orders
-        v1.POST("/orders", h.CreateOrder)
-        v1.POST("/orders/:id/confirm", h.ConfirmOrder)
+    // Routes acting on behalf of a user take the actor from the session or
+    // API key; those beyond trading also require a permission of the user's
+    // role, and API keys must carry the route's scope.
+    authed := v1.Group("", middleware.RequireAuth())
+    can := middleware.RequirePermission
+    scope := middleware.RequireScope
+    session := middleware.RequireSession()
+    {
+        authed.GET("/auth/me", read, scope(core.ScopeRead), h.AuthMe)
+        authed.POST("/auth/logout", write, session, h.AuthLogout)
+
+        // API keys are managed from a signed-in session only
+        authed.POST("/auth/api-keys", write, session, h.CreateAPIKey)
+        authed.GET("/auth/api-keys", read, session, h.ListAPIKeys)
+        authed.DELETE("/auth/api-keys/:id", write, session, h.RevokeAPIKey)
+        authed.GET("/auth/api-keys/:id/usage", read, session, h.APIKeyUsage)
+
+        authed.POST("/collections", write, scope(core.ScopeMint), can(core.PermCreateCollection), h.CreateCollection)
+
+        authed.POST("/nfts", mint, scope(core.ScopeMint), can(core.PermMint), h.RegisterNFT)
+        authed.POST("/nfts/mint", mint, chain, scope(core.ScopeMint), can(core.PermMint), h.MintNFT)
+        authed.POST("/nfts/:id/burn", chain, scope(core.ScopeMint), h.BurnNFT)
+        authed.GET("/intents/:id", read, scope(core.ScopeMint), h.GetIntent)
+
+        authed.POST("/currencies", write, session, can(core.PermManageCurrencies), h.CreateCurrency)
+        authed.PATCH("/currencies/:id", write, session, can(core.PermManageCurrencies), h.UpdateCurrency)
+
+        authed.POST("/listings", write, scope(core.ScopeList), h.CreateListing)
+        authed.POST("/listings/:id/cancel", write, scope(core.ScopeList), h.CancelListing)
+
+        authed.POST("/orders", write, scope(core.ScopeTrade), h.CreateOrder)
+        authed.POST("/orders/:id/confirm", write, scope(core.ScopeTrade), h.ConfirmOrder)
+
+        // Webhooks made with an API key belong to it and end with it
+        authed.POST("/webhooks", write, scope(core.ScopeWebhooks), h.CreateWebhook)
+        authed.GET("/webhooks", read, scope(core.ScopeWebhooks), h.ListWebhooks)
+        authed.DELETE("/webhooks/:id", write, scope(core.ScopeWebhooks), h.DeleteWebhook)
+        authed.GET("/webhooks/:id/deliveries", read, scope(core.ScopeWebhooks), h.ListWebhookDeliveries)
+        authed.POST("/webhook-deliveries/:id/redeliver", write, scope(core.ScopeWebhooks), h.RedeliverWebhook)
+    }
+
+    // Admin; API keys may read but not act
+    admin := authed.Group("/admin")
+    {
+        admin.GET("/users", read, scope(core.ScopeRead), can(core.PermViewUsers), h.AdminListUsers)
+        admin.PATCH("/users/:id", write, session, can(core.PermManageUsers), h.AdminUpdateUser)
+        admin.POST("/collections/:id/verify", write, session, can(core.PermVerifyCollection), h.AdminVerifyCollection)
+        admin.POST("/listings/:id/cancel", write, session, can(core.PermModerateListings), h.AdminCancelListing)
+        admin.POST("/orders/:id/fail", write, session, can(core.PermManageOrders), h.AdminFailOrder)
+        admin.GET("/audit-log", read, scope(core.ScopeRead), can(core.PermViewAuditLog), h.AdminAuditLog)
+        admin.GET("/reconciliation/runs", read, scope(core.ScopeRead), can(core.PermViewReconcile), h.AdminListReconciliationRuns)
+        admin.GET("/reconciliation/runs/:id", read, scope(core.ScopeRead), can(core.PermViewReconcile), h.AdminGetReconciliationRun)
+        admin.POST("/reconciliation/runs", write, session, can(core.PermRunReconcile), h.AdminStartReconciliation)
     }
 }
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/openapi"
+	"github.com/user/nft-marketplace/internal/platform/events"
+)
+
+// Every route must be described in the OpenAPI document, and every
+// operation it describes must have a route.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := &config.Config{
+		HTTP:        config.LoadHTTPConfig(),
+		RateLimit:   config.LoadRateLimitConfig(),
+		Idempotency: config.LoadIdempotencyConfig(),
+	}
+	s := NewServer(cfg, router, nil, nil, nil, nil, nil, events.NewHub(1, 1))
+	ConfigRoutesAndSchedulers(s)
+	if err := openapi.CheckRoutes(router.Routes(), UndocumentedRoutes...); err != nil {
+		t.Fatal(err)
+	}
+}
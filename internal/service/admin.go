@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/validation"
+)
+
+func (s *MarketplaceService) ListUsers(filter core.UserFilter, page core.PageRequest) (*core.Page[core.User], error) {
+	return s.repo.ListUsers(filter, page)
+}
+
+func (s *MarketplaceService) SetUserRole(actorID, userID uint, role core.Role, reason string) (*core.User, error) {
+	// Keep admins from locking themselves out
+	if actorID == userID && role != core.RoleAdmin {
+		return nil, core.Forbidden("own_role", "admins cannot change their own role")
+	}
+	return s.repo.SetUserRole(&actorID, userID, role, reason)
+}
+
+func (s *MarketplaceService) VerifyCollection(actorID, collectionID uint, verified bool, reason string) (*core.Collection, error) {
+	return s.repo.SetCollectionVerified(actorID, collectionID, verified, reason)
+}
+
+func (s *MarketplaceService) ForceCancelListing(actorID, listingID uint, reason string) error {
+	if _, err := s.repo.GetListingByID(listingID); err != nil {
+		return err
+	}
+	return s.repo.ForceCancelListing(actorID, listingID, reason)
+}
+
+func (s *MarketplaceService) MarkOrderFailed(actorID, orderID uint, reason string) error {
+	if _, err := s.repo.GetOrderByID(orderID); err != nil {
+		return err
+	}
+	return s.repo.MarkOrderFailed(actorID, orderID, reason)
+}
+
+func (s *MarketplaceService) ListAuditLog(filter core.AuditFilter, page core.PageRequest) (*core.Page[core.AuditLog], error) {
+	return s.repo.ListAuditLog(filter, page)
+}
+
+// EnsureAdmins grants the admin role to the configured wallets, creating
+// their users if they have not signed in yet.
+func (s *AuthService) EnsureAdmins() error {
+	for _, wallet := range s.cfg.AdminWallets {
+		wallet, err := validation.NormalizeAddress(wallet)
+		if err != nil {
+			return fmt.Errorf("ADMIN_WALLETS: %w", err)
+		}
+		user, err := s.repo.FindOrCreateUserByWallet(wallet)
+		if err != nil {
+			return err
+		}
+		if user.Role == core.RoleAdmin {
+			continue
+		}
+		// Granted by the system rather than a user
+		if _, err := s.repo.SetUserRole(nil, user.ID, core.RoleAdmin, "ADMIN_WALLETS"); err != nil {
+			return err
+		}
+		log.Printf("Granted admin role to %s", wallet)
+	}
+	return nil
+}
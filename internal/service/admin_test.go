@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestSetUserRole(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	admin := newTestUser(t, repo, ownerWallet)
+	user := newTestUser(t, repo, otherWallet)
+
+	got, err := svc.SetUserRole(admin.ID, user.ID, core.RoleCreator, "verified artist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != core.RoleCreator || !got.Role.Can(core.PermMint) || got.Role.Can(core.PermManageUsers) {
+		t.Fatalf("role %s after granting creator", got.Role)
+	}
+	log, err := svc.ListAuditLog(core.AuditFilter{ActorID: admin.ID}, core.PageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Items) != 1 || log.Items[0].Action != core.AuditUserRoleChanged || log.Items[0].TargetID != user.ID {
+		t.Fatalf("audit log %+v, want the role change of user %d", log.Items, user.ID)
+	}
+
+	if _, err := svc.SetUserRole(admin.ID, admin.ID, core.RoleUser, ""); !errors.Is(err, core.Forbidden("own_role", "")) {
+		t.Fatalf("admin demoting themselves: err = %v, want own_role", err)
+	}
+}
+
+// Admin grants from ADMIN_WALLETS are logged as made by the system, which
+// every store accepts, and are not repeated on the next startup.
+func TestEnsureAdmins(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repo core.Store) {
+		newTestUser(t, repo, ownerWallet)
+		auth := newTestAuthService(t, repo)
+		auth.cfg.AdminWallets = []string{ownerWallet, otherWallet}
+
+		for i := 0; i < 2; i++ {
+			if err := auth.EnsureAdmins(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, wallet := range auth.cfg.AdminWallets {
+			user, err := repo.GetUserByWallet(wallet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Role != core.RoleAdmin {
+				t.Fatalf("user %s has role %s, want admin", wallet, user.Role)
+			}
+		}
+
+		log, err := repo.ListAuditLog(core.AuditFilter{TargetType: "user"}, core.PageRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(log.Items) != 2 {
+			t.Fatalf("%d audit log entries, want one grant per wallet", len(log.Items))
+		}
+		for _, entry := range log.Items {
+			if entry.ActorUserID != nil {
+				t.Errorf("grant to user %d logged as made by user %d", entry.TargetID, *entry.ActorUserID)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/auth"
+)
+
+// apiKeyPrefix marks a bearer token as an API key rather than a JWT.
+const apiKeyPrefix = "nftm_"
+
+// CreateAPIKey issues a key for userID. The plaintext key is returned only
+// here; afterwards it is identified by its prefix.
+func (s *AuthService) CreateAPIKey(userID uint, name string, scopes []core.APIScope, expiresAt *time.Time) (*core.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", core.Validation("scopes_required", "at least one scope is required")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", core.Validation("invalid_expiry", "expires_at must be in the future")
+	}
+	secret, err := auth.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := apiKeyPrefix + secret
+
+	key := &core.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plaintext[:len(apiKeyPrefix)+6],
+		KeyHash:   auth.HashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+func (s *AuthService) ListAPIKeys(userID uint) ([]core.APIKey, error) {
+	return s.repo.ListAPIKeys(userID)
+}
+
+func (s *AuthService) RevokeAPIKey(userID, id uint) error {
+	return s.repo.RevokeAPIKey(userID, id)
+}
+
+// APIKeyUsage returns the daily request counts of a key over the last days.
+func (s *AuthService) APIKeyUsage(userID, id uint, days int) ([]core.APIKeyUsage, error) {
+	if _, err := s.repo.GetAPIKey(userID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAPIKeyUsage(id, time.Now().AddDate(0, 0, -days+1))
+}
+
+func (s *AuthService) authenticateAPIKey(token string) (*core.Principal, error) {
+	key, err := s.repo.GetAPIKeyByHash(auth.HashToken(token))
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrUnauthorized
+	}
+	user, err := s.repo.GetUserByID(key.UserID)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	if err := s.repo.RecordAPIKeyUse(key.ID, now); err != nil {
+		log.Printf("Failed to record usage of API key %d: %v", key.ID, err)
+	}
+	return &core.Principal{UserID: user.ID, Role: user.Role, APIKey: key}, nil
+}
+
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
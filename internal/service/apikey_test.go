@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestAPIKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repo core.Store) {
+		auth := newTestAuthService(t, repo)
+		user := newTestUser(t, repo, ownerWallet)
+
+		key, plaintext, err := auth.CreateAPIKey(user.ID, "ci", []core.APIScope{core.ScopeRead}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isAPIKey(plaintext) || !strings.HasPrefix(plaintext, key.Prefix) {
+			t.Fatalf("key %q with prefix %q", plaintext, key.Prefix)
+		}
+
+		// Only a hash of the key is stored
+		stored, err := repo.GetAPIKey(user.ID, key.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := reflect.ValueOf(*stored)
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.Kind() == reflect.String && strings.Contains(f.String(), plaintext) {
+				t.Fatalf("stored key holds the plaintext key in %s", v.Type().Field(i).Name)
+			}
+		}
+
+		principal, err := auth.Authenticate(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if principal.UserID != user.ID || principal.APIKey == nil || principal.APIKey.ID != key.ID {
+			t.Fatalf("key authenticated as %+v", principal)
+		}
+		if !principal.HasScope(core.ScopeRead) || principal.HasScope(core.ScopeTrade) {
+			t.Fatalf("key with scopes %v", principal.APIKey.Scopes)
+		}
+
+		if err := auth.RevokeAPIKey(user.ID, key.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := auth.Authenticate(plaintext); !errors.Is(err, core.ErrUnauthorized) {
+			t.Fatalf("authenticating with a revoked key: err = %v, want unauthorized", err)
+		}
+		if _, err := auth.Authenticate(plaintext + "0"); !errors.Is(err, core.ErrUnauthorized) {
+			t.Fatalf("authenticating with an unknown key: err = %v, want unauthorized", err)
+		}
+	})
+}
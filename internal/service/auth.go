@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/auth"
+	"github.com/user/nft-marketplace/internal/platform/siwe"
+)
+
+var ErrUnauthorized = core.Unauthorized("invalid_credentials", "invalid or expired credentials")
+
+// siweError rejects a sign-in attempt with a client-safe reason.
+func siweError(format string, args ...interface{}) error {
+	return core.Unauthorized("siwe_invalid", fmt.Sprintf(format, args...))
+}
+
+// AuthService implements Sign-In With Ethereum and the sessions issued after it.
+type AuthService struct {
+	repo    core.Store
+	cfg     config.AuthConfig
+	chainID int64
+	tokens  *auth.TokenIssuer
+}
+
+func NewAuthService(cfg *config.Config, repo core.Store) (*AuthService, error) {
+	secret := cfg.Auth.JWTSecret
+	if secret == "" {
+		generated, err := auth.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+		log.Printf("Warning: JWT_SECRET is not set; using a random secret, sessions will not survive a restart")
+	}
+	return &AuthService{
+		repo:    repo,
+		cfg:     *cfg.Auth,
+		chainID: cfg.Ethereum.ChainID,
+		tokens:  auth.NewTokenIssuer(secret, cfg.Auth.Issuer),
+	}, nil
+}
+
+// Nonce issues a single-use nonce to embed in a SIWE message.
+func (s *AuthService) Nonce() (*core.AuthNonce, error) {
+	value, err := auth.RandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	nonce := &core.AuthNonce{
+		// SIWE nonces must be alphanumeric
+		Nonce:     alphanumeric(value),
+		ExpiresAt: time.Now().Add(s.cfg.NonceTTL),
+	}
+	if err := s.repo.CreateAuthNonce(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+func alphanumeric(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+// Verify checks a signed SIWE message and starts a session for the wallet
+// that signed it, creating its user on first login.
+func (s *AuthService) Verify(message, signature, userAgent, ip string) (*core.TokenPair, error) {
+	msg, err := siwe.Parse(message)
+	if err != nil {
+		return nil, siweError("%v", err)
+	}
+	if s.cfg.SIWEDomain != "" {
+		if msg.Domain != s.cfg.SIWEDomain {
+			return nil, siweError("SIWE message is for domain %q", msg.Domain)
+		}
+		if uri, err := url.Parse(msg.URI); err != nil || uri.Host != s.cfg.SIWEDomain {
+			return nil, siweError("SIWE message URI %q is not on domain %q", msg.URI, s.cfg.SIWEDomain)
+		}
+	}
+	if msg.ChainID != s.chainID {
+		return nil, siweError("SIWE message is for chain %d, expected %d", msg.ChainID, s.chainID)
+	}
+	if err := msg.ValidAt(time.Now()); err != nil {
+		return nil, siweError("%v", err)
+	}
+	if err := siwe.VerifySignature(message, msg.Address, signature); err != nil {
+		return nil, siweError("%v", err)
+	}
+	if err := s.repo.ConsumeAuthNonce(msg.Nonce); errors.Is(err, core.ErrNotFound) {
+		return nil, siweError("nonce is unknown, expired or already used")
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindOrCreateUserByWallet(msg.Address.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	family, err := auth.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	session, refreshToken, err := s.newSession(user.ID, family, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return s.tokenPair(user, session, refreshToken)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated revokes its whole session family, since it has likely leaked.
+func (s *AuthService) Refresh(refreshToken, userAgent, ip string) (*core.TokenPair, error) {
+	session, err := s.repo.GetSessionByRefreshHash(auth.HashToken(refreshToken))
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	if session.RevokedAt != nil {
+		if session.ReplacedByID != nil {
+			log.Printf("Refresh token reuse detected for session family %s; revoking", session.FamilyID)
+			if err := s.repo.RevokeSessionFamily(session.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrUnauthorized
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.repo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	next, nextToken, err := s.newSession(user.ID, session.FamilyID, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RotateSession(session, next); err != nil {
+		return nil, ErrUnauthorized
+	}
+	return s.tokenPair(user, next, nextToken)
+}
+
+func (s *AuthService) Logout(sessionID uint) error {
+	return s.repo.RevokeSession(sessionID)
+}
+
+func (s *AuthService) LogoutAll(userID uint) error {
+	return s.repo.RevokeUserSessions(userID)
+}
+
+// Authenticate resolves an access token or API key to its principal; the
+// token's session must still be live.
+func (s *AuthService) Authenticate(accessToken string) (*core.Principal, error) {
+	if isAPIKey(accessToken) {
+		return s.authenticateAPIKey(accessToken)
+	}
+	claims, err := s.tokens.Parse(accessToken)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	session, err := s.repo.GetSession(claims.SessionID)
+	if err != nil || session.UserID != userID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrUnauthorized
+	}
+	// The role is read on every request so role changes apply immediately.
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	return &core.Principal{UserID: userID, SessionID: session.ID, Role: user.Role}, nil
+}
+
+func (s *AuthService) newSession(userID uint, family, userAgent, ip string) (*core.Session, string, error) {
+	refreshToken, err := auth.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	return &core.Session{
+		UserID:           userID,
+		FamilyID:         family,
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		UserAgent:        userAgent,
+		IP:               ip,
+		ExpiresAt:        time.Now().Add(s.cfg.RefreshTTL),
+	}, refreshToken, nil
+}
+
+func (s *AuthService) tokenPair(user *core.User, session *core.Session, refreshToken string) (*core.TokenPair, error) {
+	access, _, err := s.tokens.Issue(user.ID, session.ID, s.cfg.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &core.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.cfg.AccessTTL.Seconds()),
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             user,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/repository"
+)
+
+// siweMessage holds the fields of a sign-in message that the tests vary.
+type siweMessage struct {
+	domain, uri, nonce string
+	chainID            int64
+	issuedAt           time.Time
+	expires, notBefore time.Time
+}
+
+func (m siweMessage) String(key *ecdsa.PrivateKey) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s wants you to sign in with your Ethereum account:\n%s\n\nSign in to the marketplace.\n\n",
+		m.domain, crypto.PubkeyToAddress(key.PublicKey).Hex())
+	fmt.Fprintf(&b, "URI: %s\nVersion: 1\nChain ID: %d\nNonce: %s\nIssued At: %s",
+		m.uri, m.chainID, m.nonce, m.issuedAt.Format(time.RFC3339))
+	if !m.expires.IsZero() {
+		fmt.Fprintf(&b, "\nExpiration Time: %s", m.expires.Format(time.RFC3339))
+	}
+	if !m.notBefore.IsZero() {
+		fmt.Fprintf(&b, "\nNot Before: %s", m.notBefore.Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// personalSign signs message as wallets do for personal_sign.
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerify(t *testing.T) {
+	forEachStore(t, func(t *testing.T, repo core.Store) {
+		auth := newTestAuthService(t, repo)
+		key := newKey(t)
+		nonce, err := auth.Nonce()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := siweMessage{domain: "example.com", uri: "https://example.com/login", nonce: nonce.Nonce, chainID: 1,
+			issuedAt: time.Now().Add(-time.Minute), expires: time.Now().Add(time.Hour)}.String(key)
+
+		pair, err := auth.Verify(msg, personalSign(t, key, msg), "test", "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallet := crypto.PubkeyToAddress(key.PublicKey).Hex()
+		if pair.User == nil || pair.User.WalletAddress != wallet || pair.AccessToken == "" || pair.RefreshToken == "" {
+			t.Fatalf("token pair %+v, want tokens for %s", pair, wallet)
+		}
+		principal, err := auth.Authenticate(pair.AccessToken)
+		if err != nil || principal.UserID != pair.User.ID {
+			t.Fatalf("access token authenticates as %+v, err %v", principal, err)
+		}
+
+		// Nonces are single use
+		if _, err := auth.Verify(msg, personalSign(t, key, msg), "test", "127.0.0.1"); !errors.Is(err, core.Unauthorized("siwe_invalid", "")) {
+			t.Fatalf("reusing a nonce: err = %v, want siwe_invalid", err)
+		}
+	})
+}
+
+func TestVerifyRejects(t *testing.T) {
+	repo := repository.NewMemoryRepository(nil)
+	auth := newTestAuthService(t, repo)
+	key, other := newKey(t), newKey(t)
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		edit func(m *siweMessage)
+		// signer signs the message instead of key
+		signer *ecdsa.PrivateKey
+		raw    string
+	}{
+		{name: "other domain", edit: func(m *siweMessage) { m.domain = "evil.example" }},
+		{name: "URI on another domain", edit: func(m *siweMessage) { m.uri = "https://evil.example/login" }},
+		{name: "other chain", edit: func(m *siweMessage) { m.chainID = 5 }},
+		{name: "expired", edit: func(m *siweMessage) { m.expires = now.Add(-time.Second) }},
+		{name: "not yet valid", edit: func(m *siweMessage) { m.notBefore = now.Add(time.Hour) }},
+		{name: "issued in the future", edit: func(m *siweMessage) { m.issuedAt = now.Add(time.Hour) }},
+		{name: "unknown nonce", edit: func(m *siweMessage) { m.nonce = "unknown0nonce" }},
+		{name: "signed by another wallet", signer: other},
+		{name: "malformed", raw: "example.com wants you to sign in\nwith your Ethereum account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce, err := auth.Nonce()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := siweMessage{domain: "example.com", uri: "https://example.com/login", nonce: nonce.Nonce, chainID: 1,
+				issuedAt: now.Add(-time.Minute)}
+			if tt.edit != nil {
+				tt.edit(&m)
+			}
+			msg := m.String(key)
+			if tt.raw != "" {
+				msg = tt.raw
+			}
+			signer := key
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+
+			if _, err := auth.Verify(msg, personalSign(t, signer, msg), "test", "127.0.0.1"); !errors.Is(err, core.Unauthorized("siwe_invalid", "")) {
+				t.Fatalf("err = %v, want siwe_invalid", err)
+			}
+			// A forged signature does not use up the wallet's nonce
+			if tt.signer != nil {
+				m := m.String(key)
+				if _, err := auth.Verify(m, personalSign(t, key, m), "test", "127.0.0.1"); err != nil {
+					t.Fatalf("signing in with the nonce afterwards: %v", err)
+				}
+			}
+		})
+	}
+	if _, err := repo.GetUserByWallet(crypto.PubkeyToAddress(other.PublicKey).Hex()); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("user of a wallet whose sign-ins were rejected: err = %v, want not found", err)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+const (
+	ownerWallet = "0x00000000000000000000000000000000000000a1"
+	otherWallet = "0x00000000000000000000000000000000000000b2"
+)
+
+// mintTo mints a token to the wallet of user through the service.
+func mintTo(t *testing.T, svc *MarketplaceService, user *core.User) *core.NFT {
+	t.Helper()
+	nft, _, err := svc.MintNFT(user.ID, "Token", "TST", "", "ipfs://token", "Test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nft == nil {
+		t.Fatal("mint was not confirmed")
+	}
+	return nft
+}
+
+func TestBurnNFTSentByOwnerWallet(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	owner := newTestUser(t, repo, ownerWallet)
+	nft := mintTo(t, svc, owner)
+
+	txHash, err := chain.Burn(owner.WalletAddress, nft.TokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intent, err := svc.BurnNFT(nft.ID, owner.ID, txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intent.Status != core.IntentConfirmed {
+		t.Fatalf("intent %s (%s), want CONFIRMED", intent.Status, intent.Error)
+	}
+	burned, err := repo.GetNFTByID(nft.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burned.BurnedAt == nil {
+		t.Fatal("nft was not burned")
+	}
+}
+
+func TestBurnNFTRejectsOtherTransactions(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	owner := newTestUser(t, repo, ownerWallet)
+	other := newTestUser(t, repo, otherWallet)
+	nft := mintTo(t, svc, owner)
+	otherNFT := mintTo(t, svc, other)
+
+	// The contract reverts burns by anyone but the owner
+	reverted, err := chain.Burn(other.WalletAddress, nft.TokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BurnNFT(nft.ID, owner.ID, reverted); !errors.Is(err, core.ChainFailure("", nil)) {
+		t.Fatalf("burn by a reverted transaction: err = %v, want a chain failure", err)
+	}
+
+	// A burn of another token does not burn this one
+	burnedOther, err := chain.Burn(other.WalletAddress, otherNFT.TokenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BurnNFT(nft.ID, owner.ID, burnedOther); !errors.Is(err, core.ChainFailure("", nil)) {
+		t.Fatalf("burn by another token's transaction: err = %v, want a chain failure", err)
+	}
+
+	// Not mined yet: left open for the intent worker
+	intent, err := svc.BurnNFT(nft.ID, owner.ID, "0x"+strings.Repeat("11", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intent.Status != core.IntentSent {
+		t.Fatalf("intent of an unmined transaction is %s, want SENT", intent.Status)
+	}
+
+	if n, _ := repo.GetNFTByID(nft.ID); n.BurnedAt != nil {
+		t.Fatal("nft was burned")
+	}
+	if _, err := svc.BurnNFT(nft.ID, other.ID, reverted); !errors.Is(err, core.Forbidden("not_owner", "")) {
+		t.Fatalf("burn by another user: err = %v, want not_owner", err)
+	}
+}
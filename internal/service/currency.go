@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/validation"
+)
+
+// EnsureNativeCurrency registers the configured chain's native currency so
+// listings priced in it validate out of the box.
+func (s *MarketplaceService) EnsureNativeCurrency() error {
+	return s.repo.EnsureCurrency(&core.Currency{
+		Symbol:   s.nativeCurrency,
+		Chain:    s.chain,
+		Decimals: core.NativeDecimals,
+		Enabled:  true,
+	})
+}
+
+// RegisterCurrency adds a payment currency. For ERC20s on the configured
+// chain, decimals are read from the token contract when not given.
+func (s *MarketplaceService) RegisterCurrency(symbol, chain, tokenAddress string, decimals *uint8) (*core.Currency, error) {
+	if tokenAddress != "" {
+		var err error
+		if tokenAddress, err = validation.NormalizeAddress(tokenAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	currency := &core.Currency{
+		Symbol:       symbol,
+		Chain:        chain,
+		TokenAddress: tokenAddress,
+		Enabled:      true,
+	}
+	switch {
+	case decimals != nil:
+		currency.Decimals = *decimals
+	case tokenAddress != "" && chain == s.chain:
+		d, err := s.eth.TokenDecimals(tokenAddress)
+		if err != nil {
+			return nil, core.ChainFailure("could not read token decimals", err)
+		}
+		currency.Decimals = d
+	default:
+		return nil, core.Validation("decimals_required", "decimals are required")
+	}
+
+	if err := s.repo.CreateCurrency(currency); err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+func (s *MarketplaceService) ListCurrencies(chain string) ([]core.Currency, error) {
+	return s.repo.ListCurrencies(chain)
+}
+
+func (s *MarketplaceService) SetCurrencyEnabled(id uint, enabled bool) (*core.Currency, error) {
+	return s.repo.SetCurrencyEnabled(id, enabled)
+}
+
+// listingCurrency resolves the currency a listing on chain would be priced in.
+func (s *MarketplaceService) listingCurrency(chain, symbol string) (*core.Currency, error) {
+	if symbol == "" {
+		symbol = s.nativeCurrency
+	}
+	currency, err := s.repo.GetCurrency(chain, symbol)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, core.Validation("unsupported_currency", "currency %s is not supported on %s", symbol, chain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !currency.Enabled {
+		return nil, core.Validation("unsupported_currency", "currency %s is disabled on %s", symbol, chain)
+	}
+	return currency, nil
+}
+
+// decorateListings renders listing prices with the decimals of their currency.
+// Listings must have their NFT loaded to resolve the chain.
+func (s *MarketplaceService) decorateListings(listings []core.Listing) error {
+	currencies, err := s.repo.ListCurrencies("")
+	if err != nil {
+		return err
+	}
+	decimals := make(map[string]uint8, len(currencies))
+	for _, c := range currencies {
+		decimals[c.Chain+"/"+c.Symbol] = c.Decimals
+	}
+	for i := range listings {
+		l := &listings[i]
+		d, ok := decimals[l.NFT.Chain+"/"+l.Currency]
+		if !ok {
+			d = core.NativeDecimals
+		}
+		l.Price = core.NewAmount(l.PriceWei, d)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/events"
+)
+
+const (
+	// replayBatch is how many logged events are read at a time when a
+	// stream catches up.
+	replayBatch = 500
+	// replayOverlap is how long after catching up a stream remembers the
+	// events it replayed, to skip them if they are also published live.
+	// Events are published as soon as they are logged, so it is ample.
+	replayOverlap = time.Minute
+	// commitWindow is how long before the event a stream resumes from it
+	// replays the log. Transactions commit out of id order, so events with
+	// lower ids may have been logged since the client received it; none
+	// holds its id for this long before committing.
+	commitWindow = 30 * time.Second
+)
+
+type EventService struct {
+	repo      core.EventStore
+	hub       *events.Hub
+	heartbeat time.Duration
+}
+
+// NewEventService returns a service whose streams report being idle every
+// heartbeat, so that connections can be kept alive.
+func NewEventService(repo core.EventStore, hub *events.Hub, heartbeat time.Duration) *EventService {
+	return &EventService{repo: repo, hub: hub, heartbeat: heartbeat}
+}
+
+// EventStream yields the events matching a filter: first those logged after
+// the id the client resumed from, then live ones as they are published.
+type EventStream struct {
+	repo     core.EventStore
+	sub      *events.Subscription
+	wait     time.Duration
+	filter   core.EventFilter
+	lastID   uint
+	backlog  []core.Event
+	caughtUp bool
+	// replayed holds the ids sent from the log, which may also come live,
+	// until forgetAt has passed and no live event is waiting. Ids are not compared: transactions commit out of id
+	// order, so a live event may have a lower id than one already sent.
+	replayed map[uint]struct{}
+	forgetAt time.Time
+}
+
+// Subscribe opens a stream of the events matching filter after lastID; zero
+// starts with the next event published. Events logged shortly before lastID
+// are replayed as well, so the client may get some it already has.
+func (s *EventService) Subscribe(filter core.EventFilter, lastID uint) (*EventStream, error) {
+	// Subscribe before reading the log so that no event falls in between
+	sub, err := s.hub.Subscribe(filter)
+	if errors.Is(err, events.ErrTooManySubscribers) {
+		return nil, core.Unavailable("too_many_streams", "too many open event streams, retry later")
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := &EventStream{
+		repo:     s.repo,
+		sub:      sub,
+		wait:     s.heartbeat,
+		filter:   filter,
+		lastID:   lastID,
+		caughtUp: lastID == 0,
+		replayed: make(map[uint]struct{}),
+	}
+	if lastID != 0 {
+		// Replay by when lastID was logged rather than by id, to include
+		// lower ids that committed after it
+		last, err := s.repo.ListEvents(core.EventFilter{}, lastID-1, 1)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+		if len(last) > 0 {
+			st.filter.Since = last[0].CreatedAt.Add(-commitWindow)
+			st.lastID = 0
+		}
+	}
+	return st, nil
+}
+
+// Next returns the stream's next event, waiting up to a heartbeat for a
+// live one. It returns nil if none arrived in time, and ok false once the
+// stream has ended because ctx is done or the client fell too far behind.
+func (st *EventStream) Next(ctx context.Context) (e *core.Event, ok bool, err error) {
+	if !st.caughtUp && len(st.backlog) == 0 {
+		st.backlog, err = st.repo.ListEvents(st.filter, st.lastID, replayBatch)
+		if err != nil {
+			return nil, false, err
+		}
+		st.caughtUp = len(st.backlog) < replayBatch
+		if st.caughtUp {
+			st.forgetAt = time.Now().Add(replayOverlap)
+		}
+	}
+	if len(st.backlog) > 0 {
+		e, st.backlog = &st.backlog[0], st.backlog[1:]
+		st.lastID = e.ID
+		st.replayed[e.ID] = struct{}{}
+		return e, true, nil
+	}
+	if st.replayed != nil && time.Now().After(st.forgetAt) && len(st.sub.Events()) == 0 {
+		st.replayed = nil
+	}
+
+	timer := time.NewTimer(st.wait)
+	defer timer.Stop()
+	for {
+		select {
+		case live, open := <-st.sub.Events():
+			if !open {
+				return nil, false, nil
+			}
+			// Already sent while replaying the log
+			if _, ok := st.replayed[live.ID]; ok {
+				delete(st.replayed, live.ID)
+				continue
+			}
+			return &live, true, nil
+		case <-timer.C:
+			return nil, true, nil
+		case <-ctx.Done():
+			return nil, false, nil
+		}
+	}
+}
+
+func (st *EventStream) Close() {
+	st.sub.Close()
+}
+
+// PruneEvents drops events older than retention from the log.
+func (s *EventService) PruneEvents(retention time.Duration) error {
+	return s.repo.PruneEvents(time.Now().Add(-retention))
+}
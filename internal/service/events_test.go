@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/events"
+)
+
+// eventLog is an event store holding a fixed log.
+type eventLog []core.Event
+
+func (l eventLog) RecordEvents(...*core.Event) error { return nil }
+func (l eventLog) PruneEvents(time.Time) error       { return nil }
+
+func (l eventLog) ListEvents(filter core.EventFilter, afterID uint, limit int) ([]core.Event, error) {
+	var out []core.Event
+	for _, e := range l {
+		if e.ID > afterID && filter.Matches(&e) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func streamIDs(t *testing.T, st *EventStream, n int) []uint {
+	t.Helper()
+	var ids []uint
+	for len(ids) < n {
+		e, ok, err := st.Next(context.Background())
+		if err != nil || !ok {
+			t.Fatalf("Next: ok %v, err %v", ok, err)
+		}
+		if e == nil {
+			t.Fatalf("stream idle after %v, want %d events", ids, n)
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestEventStreamDeliversLiveEventsOutOfOrder(t *testing.T) {
+	hub := events.NewHub(16, 10)
+	svc := NewEventService(eventLog{}, hub, 10*time.Millisecond)
+	st, err := svc.Subscribe(core.EventFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// 8 commits before 7, whose transaction took longer
+	hub.Publish(core.Event{ID: 8}, core.Event{ID: 7})
+	if ids := streamIDs(t, st, 2); ids[0] != 8 || ids[1] != 7 {
+		t.Fatalf("got events %v, want [8 7]", ids)
+	}
+}
+
+func TestEventStreamSkipsOnlyReplayedEvents(t *testing.T) {
+	hub := events.NewHub(16, 10)
+	svc := NewEventService(eventLog{{ID: 3}, {ID: 5}}, hub, 10*time.Millisecond)
+	st, err := svc.Subscribe(core.EventFilter{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// 5 was logged before the stream read the log and is published after;
+	// 4 commits late, after 5 was replayed
+	hub.Publish(core.Event{ID: 5}, core.Event{ID: 4}, core.Event{ID: 6})
+	ids := streamIDs(t, st, 4)
+	want := []uint{3, 5, 4, 6}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("got events %v, want %v", ids, want)
+		}
+	}
+	if e, ok, _ := st.Next(context.Background()); !ok || e != nil {
+		t.Fatalf("got event %v after the last one", e)
+	}
+}
+
+// Event 4 commits after the client received 5: resuming from 5 replays it,
+// with the other events logged around then, but not older ones.
+func TestEventStreamResumesWithLateCommits(t *testing.T) {
+	now := time.Now()
+	log := eventLog{
+		{ID: 3, CreatedAt: now.Add(-time.Hour)},
+		{ID: 4, CreatedAt: now.Add(-time.Second)},
+		{ID: 5, CreatedAt: now},
+		{ID: 6, CreatedAt: now},
+	}
+	svc := NewEventService(log, events.NewHub(16, 10), 10*time.Millisecond)
+	st, err := svc.Subscribe(core.EventFilter{}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	ids := streamIDs(t, st, 3)
+	want := []uint{4, 5, 6}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("got events %v, want %v", ids, want)
+		}
+	}
+	if e, ok, _ := st.Next(context.Background()); !ok || e != nil {
+		t.Fatalf("got event %v after the last one", e)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunExpiry moves listings and orders past their expiry to EXPIRED and
+// clears lapsed listing reservations every expiry interval until ctx is
+// done.
+func (s *MarketplaceService) RunExpiry(ctx context.Context) {
+	ticker := time.NewTicker(s.expiry.Interval)
+	defer ticker.Stop()
+	for {
+		s.expire()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MarketplaceService) expire() {
+	now := time.Now()
+	for _, step := range []struct {
+		what   string
+		expire func(time.Time) (int, error)
+	}{
+		{"listings", s.repo.ExpireListings},
+		{"orders", s.repo.ExpireOrders},
+		{"listing reservations", s.repo.ReleaseReservations},
+	} {
+		// Each call takes the next batch until none are left
+		for {
+			n, err := step.expire(now)
+			if err != nil {
+				log.Printf("Failed to expire %s: %v", step.what, err)
+				break
+			}
+			if n == 0 {
+				break
+			}
+			log.Printf("Expired %d %s", n, step.what)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// intentBatch bounds how many intents a poll works through; they are
+// handled one at a time so their nonces follow each other.
+const intentBatch = 20
+
+func (s *MarketplaceService) GetIntent(userID, id uint) (*core.ChainIntent, error) {
+	return s.repo.GetIntent(userID, id)
+}
+
+// runIntent carries an intent just created by a request as far as it gets
+// within the configured wait, then leaves the rest to RunIntents. An intent
+// that failed for good is reported as a chain failure with message.
+func (s *MarketplaceService) runIntent(intent *core.ChainIntent, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.intents.Wait)
+	defer cancel()
+
+	err := s.advanceIntent(ctx, intent)
+	if intent.IsOpen() {
+		if err != nil {
+			log.Printf("Intent %d left to the intent worker: %v", intent.ID, err)
+		}
+		if err := s.repo.ReleaseIntent(intent.ID); err != nil {
+			log.Printf("Failed to release intent %d: %v", intent.ID, err)
+		}
+		return nil
+	}
+	if err != nil {
+		var domainErr *core.Error
+		if errors.As(err, &domainErr) {
+			return err
+		}
+		return core.ChainFailure(message, err)
+	}
+	return nil
+}
+
+// RunIntents finishes open intents every poll interval until ctx is done.
+// The first pass, at startup, recovers intents a previous run left open:
+// those with a stored transaction are matched against its receipt or
+// broadcast again, never signed anew.
+func (s *MarketplaceService) RunIntents(ctx context.Context) {
+	ticker := time.NewTicker(s.intents.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.advanceIntents(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MarketplaceService) advanceIntents(ctx context.Context) {
+	intents, err := s.repo.ClaimIntents(intentBatch, s.intents.Lease)
+	if err != nil {
+		log.Printf("Failed to claim chain intents: %v", err)
+		return
+	}
+	for i := range intents {
+		intent := &intents[i]
+		wait, cancel := context.WithTimeout(ctx, s.intents.Wait)
+		if err := s.advanceIntent(wait, intent); err != nil {
+			log.Printf("Intent %d (%s, %s): %v", intent.ID, intent.Kind, intent.Status, err)
+		}
+		cancel()
+		if intent.IsOpen() {
+			if err := s.repo.ReleaseIntent(intent.ID); err != nil {
+				log.Printf("Failed to release intent %d: %v", intent.ID, err)
+			}
+		}
+	}
+}
+
+// advanceIntent signs a pending intent, storing the transaction before it
+// is broadcast, and finishes a sent one once its receipt is in. Errors
+// leave the intent open unless it has run out of attempts; so does a
+// transaction sent by the user's wallet that is not mined in time.
+func (s *MarketplaceService) advanceIntent(ctx context.Context, intent *core.ChainIntent) error {
+	if intent.Status == core.IntentPending {
+		signed, err := s.signIntent(intent)
+		if err != nil {
+			return s.intentAttemptFailed(intent, err)
+		}
+		if err := s.repo.SaveIntentTx(intent, signed.Hash, signed.Raw); err != nil {
+			return err
+		}
+	}
+
+	receipt, err := s.eth.Receipt(ctx, intent.TxHash)
+	if err != nil {
+		return err
+	}
+	if receipt == nil && intent.RawTx == "" {
+		// Sent by the user's wallet: there is nothing to broadcast again
+		if receipt, err = s.eth.WaitReceipt(ctx, intent.TxHash); err != nil {
+			return s.intentAttemptFailed(intent, fmt.Errorf("transaction not mined: %w", err))
+		}
+	}
+	if receipt == nil {
+		// Broadcasting again is harmless: the node already has it or it was dropped
+		if sendErr := s.eth.SendRaw(intent.RawTx); sendErr != nil {
+			if !errors.Is(sendErr, core.ErrNonceUsed) {
+				return s.intentAttemptFailed(intent, sendErr)
+			}
+			// The nonce may have been taken by this very transaction
+			if receipt, err = s.eth.Receipt(ctx, intent.TxHash); err != nil {
+				return err
+			}
+			if receipt == nil {
+				log.Printf("Intent %d transaction %s was replaced; signing it again", intent.ID, intent.TxHash)
+				return s.repo.ResetIntent(intent, sendErr.Error())
+			}
+		}
+		if receipt == nil {
+			if receipt, err = s.eth.WaitReceipt(ctx, intent.TxHash); err != nil {
+				return err
+			}
+		}
+	}
+	return s.finishIntent(intent, receipt)
+}
+
+func (s *MarketplaceService) signIntent(intent *core.ChainIntent) (*core.SignedTx, error) {
+	switch intent.Kind {
+	case core.IntentMint:
+		return s.eth.SignMint(intent.Mint.To, intent.Mint.TokenURI)
+	}
+	return nil, fmt.Errorf("unknown intent kind %q", intent.Kind)
+}
+
+// finishIntent applies the database changes of a mined intent.
+func (s *MarketplaceService) finishIntent(intent *core.ChainIntent, receipt *types.Receipt) error {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return s.failIntent(intent, errors.New("transaction reverted"))
+	}
+
+	var nft *core.NFT
+	var err error
+	switch intent.Kind {
+	case core.IntentMint:
+		tokenID, terr := s.eth.MintedTokenID(receipt)
+		if terr != nil {
+			return s.failIntent(intent, terr)
+		}
+		nft, err = s.repo.ConfirmMintIntent(intent, s.eth.GetNFTAddress(), s.chain, tokenID)
+	case core.IntentBurn:
+		if err := s.checkBurn(intent, receipt); err != nil {
+			return err
+		}
+		nft, err = s.repo.ConfirmBurnIntent(intent)
+		if errors.Is(err, core.InvalidState("nft_burned", "")) {
+			return s.failIntent(intent, err)
+		}
+	default:
+		return s.failIntent(intent, fmt.Errorf("unknown intent kind %q", intent.Kind))
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Intent %d (%s) confirmed: TokenID=%s, TxHandle=%s", intent.ID, intent.Kind, nft.TokenID, intent.TxHash)
+	return nil
+}
+
+// checkBurn fails the intent unless receipt is of a transaction that
+// burned its token: the hash came from the user, not from a transaction the
+// API signed.
+func (s *MarketplaceService) checkBurn(intent *core.ChainIntent, receipt *types.Receipt) error {
+	nft, err := s.repo.GetNFTByID(*intent.NFTID)
+	if err != nil {
+		return err
+	}
+	tokenID, err := s.eth.BurnedTokenID(receipt)
+	if err != nil {
+		return s.failIntent(intent, err)
+	}
+	if tokenID != nft.TokenID {
+		return s.failIntent(intent, fmt.Errorf("transaction burned token %s, not %s", tokenID, nft.TokenID))
+	}
+	return nil
+}
+
+// intentAttemptFailed counts a failed attempt at an intent, failing it
+// once the attempts run out.
+func (s *MarketplaceService) intentAttemptFailed(intent *core.ChainIntent, cause error) error {
+	if err := s.repo.RecordIntentAttempt(intent, cause.Error()); err != nil {
+		return err
+	}
+	if intent.Attempts >= s.intents.MaxAttempts {
+		return s.failIntent(intent, cause)
+	}
+	return cause
+}
+
+func (s *MarketplaceService) failIntent(intent *core.ChainIntent, cause error) error {
+	if err := s.repo.FailIntent(intent, cause.Error()); err != nil {
+		return err
+	}
+	return cause
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/metrics"
+)
+
+// listingCheckBatch bounds how many listings are checked against the chain
+// at a time.
+const listingCheckBatch = 200
+
+// listingCheckTimeout bounds the chain reads of a check made on read.
+const listingCheckTimeout = 5 * time.Second
+
+// RunListingChecks validates every open listing against the chain each check
+// interval until ctx is done.
+func (s *MarketplaceService) RunListingChecks(ctx context.Context) {
+	ticker := time.NewTicker(s.listings.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.checkAllListings(ctx); err != nil {
+			log.Printf("Listing check failed: %v", err)
+			metrics.ListingCheckFailures.Inc()
+		}
+	}
+}
+
+func (s *MarketplaceService) checkAllListings(ctx context.Context) error {
+	var afterID uint
+	for {
+		listings, err := s.repo.ListChainListings(s.chain, s.eth.GetNFTAddress(), afterID, listingCheckBatch)
+		if err != nil || len(listings) == 0 {
+			return err
+		}
+		afterID = listings[len(listings)-1].ID
+		batch := make([]*core.Listing, len(listings))
+		for i := range listings {
+			batch[i] = &listings[i]
+		}
+		if err := s.checkListings(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// refreshListings checks the listings, which must have their NFT and seller
+// loaded, whose last check is older than the configured maximum age. When
+// the chain cannot be read they are left as they were.
+func (s *MarketplaceService) refreshListings(listings []*core.Listing) error {
+	if s.listings.MaxAge <= 0 {
+		return nil
+	}
+	var stale []*core.Listing
+	for _, listing := range listings {
+		if listing.CheckedAt == nil || time.Since(*listing.CheckedAt) > s.listings.MaxAge {
+			stale = append(stale, listing)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), listingCheckTimeout)
+	defer cancel()
+	if err := s.checkListings(ctx, stale); err != nil {
+		metrics.ListingCheckFailures.Inc()
+		return err
+	}
+	return nil
+}
+
+// refreshListing is refreshListings for a listing read without its NFT and
+// seller, failing when the chain cannot be read.
+func (s *MarketplaceService) refreshListing(listing *core.Listing) error {
+	if !listing.IsOpen() {
+		return nil
+	}
+	nft, err := s.repo.GetNFTByID(listing.NFTID)
+	if err != nil {
+		return err
+	}
+	seller, err := s.repo.GetUserByID(listing.SellerUserID)
+	if err != nil {
+		return err
+	}
+	listing.NFT, listing.Seller = *nft, *seller
+	if err := s.refreshListings([]*core.Listing{listing}); err != nil {
+		return core.ChainFailure("could not check the listing against the chain", err)
+	}
+	return nil
+}
+
+// checkListings validates open listings of NFTs of our contract: the seller
+// must still own the token, the marketplace must still be approved for it
+// and its on-chain listing must still be active and the seller's. Listings
+// found wanting become INVALID with the reason; INVALID ones that pass are
+// active again. Other listings are left alone.
+func (s *MarketplaceService) checkListings(ctx context.Context, listings []*core.Listing) error {
+	contract := s.eth.GetNFTAddress()
+	var ours []*core.Listing
+	for _, listing := range listings {
+		if listing.IsOpen() && listing.NFT.Chain == s.chain && strings.EqualFold(listing.NFT.ContractAddress, contract) {
+			ours = append(ours, listing)
+		}
+	}
+	if len(ours) == 0 {
+		return nil
+	}
+
+	tokenIds := make([]string, len(ours))
+	tokens := make([]core.TokenOwner, len(ours))
+	for i, listing := range ours {
+		tokenIds[i] = listing.NFT.TokenID
+		tokens[i] = core.TokenOwner{TokenID: listing.NFT.TokenID, Owner: listing.Seller.WalletAddress}
+	}
+	owners, err := s.eth.OwnersOf(ctx, tokenIds)
+	if err != nil {
+		return err
+	}
+	approved, err := s.eth.MarketplaceApproved(ctx, tokens)
+	if err != nil {
+		return err
+	}
+	onChain, err := s.eth.MarketListings(ctx, tokenIds)
+	if err != nil {
+		return err
+	}
+
+	for i, listing := range ours {
+		seller := listing.Seller.WalletAddress
+		var reason string
+		switch {
+		case listing.NFT.OwnerUserID != listing.SellerUserID || !strings.EqualFold(owners[i], seller):
+			reason = core.InvalidSellerNotOwner
+		case !approved[i]:
+			reason = core.InvalidNotApproved
+		case !onChain[i].Active || !strings.EqualFold(onChain[i].Seller, seller):
+			reason = core.InvalidNotListed
+		}
+		wasInvalid := listing.Status == core.ListingInvalid && listing.InvalidReason == reason
+		if err := s.repo.SetListingValidity(listing, reason); err != nil {
+			return err
+		}
+		if reason != "" && !wasInvalid {
+			metrics.ListingsInvalidated.WithLabelValues(reason).Inc()
+		}
+	}
+	return nil
+}
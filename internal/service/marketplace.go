@@ -4,36 +4,52 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/user/nft-marketplace/internal/config"
 	"github.com/user/nft-marketplace/internal/core"
-	"github.com/user/nft-marketplace/internal/platform/eth"
-	"github.com/user/nft-marketplace/internal/repository"
+	"github.com/user/nft-marketplace/internal/platform/validation"
+	"github.com/user/nft-marketplace/internal/rarity"
 )
 
 type MarketplaceService struct {
-	repo *repository.Repository
-	eth  *eth.Client
+	repo           core.Store
+	eth            core.ChainClient
+	intents        *config.IntentConfig
+	reconcile      *config.ReconcileConfig
+	listings       *config.ListingConfig
+	expiry         *config.ExpiryConfig
+	reconciling    sync.Mutex
+	rarityMethod   rarity.Method
+	rarityInterval time.Duration
+	chain          string
+	nativeCurrency string
 }
 
-func NewMarketplaceService(repo *repository.Repository, ethClient *eth.Client) *MarketplaceService {
-	return &MarketplaceService{repo: repo, eth: ethClient}
+func NewMarketplaceService(cfg *config.Config, repo core.Store, ethClient core.ChainClient) (*MarketplaceService, error) {
+	method, err := rarity.ParseMethod(cfg.Rarity.Method)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketplaceService{
+		repo:           repo,
+		eth:            ethClient,
+		intents:        cfg.Intent,
+		reconcile:      cfg.Reconcile,
+		listings:       cfg.Listing,
+		expiry:         cfg.Expiry,
+		rarityMethod:   method,
+		rarityInterval: cfg.Rarity.Interval,
+		chain:          cfg.Ethereum.ChainName,
+		nativeCurrency: cfg.Ethereum.NativeCurrency,
+	}, nil
 }
 
 func (s *MarketplaceService) Health() error {
 	return s.repo.Ping()
 }
 
-func (s *MarketplaceService) CreateUser(wallet string, name string) (*core.User, error) {
-	user := &core.User{
-		WalletAddress: wallet,
-		Name:          name,
-	}
-	if err := s.repo.CreateUser(user); err != nil {
-		return nil, err
-	}
-	return user, nil
-}
-
 func (s *MarketplaceService) GetUser(id uint) (*core.User, error) {
 	return s.repo.GetUserByID(id)
 }
@@ -50,14 +66,17 @@ func (s *MarketplaceService) CreateCollection(creatorID uint, name string, symbo
 	return collection, nil
 }
 
-func (s *MarketplaceService) ListCollections() ([]core.Collection, error) {
-	return s.repo.ListCollections()
+func (s *MarketplaceService) ListCollections(filter core.CollectionFilter, page core.PageRequest) (*core.Page[core.Collection], error) {
+	return s.repo.ListCollections(filter, page)
 }
 
-func (s *MarketplaceService) MintNFT(ownerID uint, name, symbol, desc, imageURL, collectionName string) (*core.NFT, error) {
+// MintNFT mints a token to the owner's wallet and registers it. When the
+// mint is not mined within the configured wait it returns only the intent,
+// which is finished in the background.
+func (s *MarketplaceService) MintNFT(ownerID uint, name, symbol, desc, imageURL, collectionName string, attrs []core.NFTAttribute) (*core.NFT, *core.ChainIntent, error) {
 	user, err := s.repo.GetUserByID(ownerID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Find or Create Collection
@@ -72,7 +91,7 @@ func (s *MarketplaceService) MintNFT(ownerID uint, name, symbol, desc, imageURL,
 				Symbol:        "NFT", // Default symbol, logic could be better
 			}
 			if err := s.repo.CreateCollection(newCol); err != nil {
-				return nil, fmt.Errorf("failed to auto-create collection: %w", err)
+				return nil, nil, fmt.Errorf("failed to auto-create collection: %w", err)
 			}
 			collection = newCol
 		}
@@ -92,43 +111,58 @@ func (s *MarketplaceService) MintNFT(ownerID uint, name, symbol, desc, imageURL,
 					Symbol:        "DEF",
 				}
 				if err := s.repo.CreateCollection(newCol); err != nil {
-					return nil, fmt.Errorf("failed to create default collection: %w", err)
+					return nil, nil, fmt.Errorf("failed to create default collection: %w", err)
 				}
 				collection = newCol
 			}
 		}
 	}
 
-	// 1. Mint on blockchain
-	txHash, tokenID, err := s.eth.Mint(user.WalletAddress, imageURL)
-	if err != nil {
-		return nil, fmt.Errorf("blockchain mint failure: %w", err)
+	// The mint is stored as an intent first, so it is finished even if this
+	// request is not around to see it mined
+	intent := &core.ChainIntent{
+		Kind:   core.IntentMint,
+		UserID: ownerID,
+		Mint: &core.MintIntent{
+			To:           user.WalletAddress,
+			TokenURI:     imageURL,
+			CollectionID: collection.ID,
+			Name:         name,
+			Description:  desc,
+			Attributes:   attrs,
+		},
 	}
-	log.Printf("Minted NFT: TokenID=%s, TxHandle=%s", tokenID, txHash)
-
-	// 2. Register in DB
-	nft := &core.NFT{
-		TokenID:         tokenID,
-		ContractAddress: s.eth.GetNFTAddress(),
-		Chain:           "Qubetics",
-		CollectionID:    collection.ID,
-		OwnerUserID:     ownerID,
-		MetadataURL:     imageURL,
+	if err := s.repo.CreateIntent(intent, s.intents.Lease); err != nil {
+		return nil, nil, err
 	}
-	if err := s.repo.CreateNFT(nft); err != nil {
-		return nil, err
+	if err := s.runIntent(intent, "blockchain mint failed"); err != nil {
+		return nil, nil, err
 	}
-	return nft, nil
+	if intent.Status != core.IntentConfirmed {
+		return nil, intent, nil
+	}
+	nft, err := s.repo.GetNFTByID(*intent.NFTID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nft, intent, nil
 }
 
-func (s *MarketplaceService) RegisterNFT(tokenID, contract, chain string, collectionID, ownerID uint, metadataURL string) (*core.NFT, error) {
+func (s *MarketplaceService) RegisterNFT(tokenID, contract, chain string, collectionID, ownerID uint, name, desc, metadataURL string, attrs []core.NFTAttribute) (*core.NFT, error) {
+	contract, err := validation.NormalizeAddress(contract)
+	if err != nil {
+		return nil, err
+	}
 	nft := &core.NFT{
 		TokenID:         tokenID,
 		ContractAddress: contract,
 		Chain:           chain,
 		CollectionID:    collectionID,
 		OwnerUserID:     ownerID,
+		Name:            name,
+		Description:     desc,
 		MetadataURL:     metadataURL,
+		Attributes:      attrs,
 	}
 	if err := s.repo.CreateNFT(nft); err != nil {
 		return nil, err
@@ -136,35 +170,111 @@ func (s *MarketplaceService) RegisterNFT(tokenID, contract, chain string, collec
 	return nft, nil
 }
 
-func (s *MarketplaceService) ListNFTs(ownerID, collectionID uint, chain string) ([]core.NFT, error) {
-	return s.repo.ListNFTs(ownerID, collectionID, chain)
+// BurnNFT records the burn of a token by txHash, a transaction the owner's
+// wallet sent since the contract only lets owners burn. Like a mint it goes
+// through an intent, confirmed once the receipt shows the token burned; the
+// returned intent is confirmed unless the burn is still being mined.
+func (s *MarketplaceService) BurnNFT(nftID, userID uint, txHash string) (*core.ChainIntent, error) {
+	nft, err := s.repo.GetNFTByID(nftID)
+	if err != nil {
+		return nil, err
+	}
+	if nft.OwnerUserID != userID {
+		return nil, core.Forbidden("not_owner", "only the owner can burn this nft")
+	}
+	if nft.BurnedAt != nil {
+		return nil, core.InvalidState("nft_burned", "nft is already burned")
+	}
+
+	intent := &core.ChainIntent{Kind: core.IntentBurn, Status: core.IntentSent, UserID: userID, NFTID: &nftID, TxHash: txHash}
+	if err := s.repo.CreateIntent(intent, s.intents.Lease); err != nil {
+		return nil, err
+	}
+	if err := s.runIntent(intent, "blockchain burn failed"); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+func (s *MarketplaceService) ListNFTs(filter core.NFTFilter, page core.PageRequest) (*core.Page[core.NFT], error) {
+	return s.repo.ListNFTs(filter, page)
+}
+
+func (s *MarketplaceService) Search(q core.SearchQuery) (*core.SearchResult, error) {
+	return s.repo.Search(q)
 }
 
-func (s *MarketplaceService) CreateListing(nftID, sellerID uint, priceWei, currency string) (*core.Listing, error) {
+func (s *MarketplaceService) Suggest(text string, limit int) ([]core.Suggestion, error) {
+	return s.repo.Suggest(text, limit)
+}
+
+// CreateListing lists an NFT of the seller; a listing with expiresAt set
+// expires then.
+func (s *MarketplaceService) CreateListing(nftID, sellerID uint, priceWei core.Wei, currency string, expiresAt *time.Time) (*core.Listing, error) {
+	if priceWei.Sign() <= 0 {
+		return nil, core.Validation("invalid_price", "price must be greater than zero")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, core.Validation("invalid_expiry", "expires_at must be in the future")
+	}
+
 	// Check if seller owns NFT
 	nft, err := s.repo.GetNFTByID(nftID)
 	if err != nil {
-		return nil, fmt.Errorf("nft not found: %w", err)
+		return nil, err
 	}
 	if nft.OwnerUserID != sellerID {
-		return nil, errors.New("seller does not own this nft")
+		return nil, core.Forbidden("not_owner", "seller does not own this nft")
+	}
+	if nft.BurnedAt != nil {
+		return nil, core.InvalidState("nft_burned", "nft is burned")
+	}
+	cur, err := s.listingCurrency(nft.Chain, currency)
+	if err != nil {
+		return nil, err
 	}
 
 	listing := &core.Listing{
 		NFTID:        nftID,
 		SellerUserID: sellerID,
 		PriceWei:     priceWei,
-		Currency:     currency,
+		Currency:     cur.Symbol,
 		Status:       core.ListingActive,
+		ExpiresAt:    expiresAt,
 	}
 	if err := s.repo.CreateListing(listing); err != nil {
 		return nil, err
 	}
+	listing.Price = core.NewAmount(listing.PriceWei, cur.Decimals)
 	return listing, nil
 }
 
-func (s *MarketplaceService) ListActiveListings() ([]core.Listing, error) {
-	return s.repo.ListActiveListings()
+func (s *MarketplaceService) ListActiveListings(filter core.ListingFilter, page core.PageRequest) (*core.Page[core.Listing], error) {
+	listings, err := s.repo.ListActiveListings(filter, page)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*core.Listing, len(listings.Items))
+	for i := range listings.Items {
+		items[i] = &listings.Items[i]
+	}
+	if err := s.refreshListings(items); err != nil {
+		log.Printf("Could not check listings against the chain: %v", err)
+	}
+	if !filter.IncludeInvalid {
+		// Drop the listings the check just found invalid
+		valid := listings.Items[:0]
+		for _, listing := range listings.Items {
+			if listing.Status == core.ListingActive {
+				valid = append(valid, listing)
+			}
+		}
+		listings.Items = valid
+	}
+	if err := s.decorateListings(listings.Items); err != nil {
+		return nil, err
+	}
+	return listings, nil
 }
 
 func (s *MarketplaceService) CancelListing(listingID, userID uint) error {
@@ -173,12 +283,12 @@ func (s *MarketplaceService) CancelListing(listingID, userID uint) error {
 		return err
 	}
 	if listing.SellerUserID != userID {
-		return errors.New("only seller can cancel listing")
+		return core.Forbidden("not_seller", "only the seller can cancel this listing")
 	}
-	if listing.Status != core.ListingActive {
-		return errors.New("listing is not active")
+	if !listing.IsOpen() {
+		return core.InvalidState("listing_not_active", "listing is not active")
 	}
-	return s.repo.UpdateListingStatus(listingID, core.ListingCancelled)
+	return s.repo.UpdateListingStatus(listing, core.ListingCancelled)
 }
 
 func (s *MarketplaceService) CreateOrder(listingID, buyerID uint) (*core.Order, error) {
@@ -186,11 +296,26 @@ func (s *MarketplaceService) CreateOrder(listingID, buyerID uint) (*core.Order,
 	if err != nil {
 		return nil, err
 	}
+	if listing.Status == core.ListingExpired || listing.IsOpen() && listing.IsExpired(time.Now()) {
+		return nil, core.InvalidState("listing_expired", "listing has expired")
+	}
+	if listing.ReservedByOther(0, time.Now()) {
+		return nil, core.ListingReserved(*listing.ReservedUntil)
+	}
+	if err := s.refreshListing(listing); err != nil {
+		return nil, err
+	}
+	if listing.Status == core.ListingInvalid {
+		return nil, core.InvalidState("listing_invalid", "listing is no longer valid: "+listing.InvalidReason)
+	}
 	if listing.Status != core.ListingActive {
-		return nil, errors.New("listing is not active")
+		return nil, core.InvalidState("listing_not_active", "listing is not active")
 	}
 	if listing.SellerUserID == buyerID {
-		return nil, errors.New("seller cannot buy their own listing")
+		return nil, core.Forbidden("own_listing", "seller cannot buy their own listing")
+	}
+	if err := s.checkBuyerCanPay(listing, buyerID); err != nil {
+		return nil, err
 	}
 
 	order := &core.Order{
@@ -198,12 +323,66 @@ func (s *MarketplaceService) CreateOrder(listingID, buyerID uint) (*core.Order,
 		BuyerUserID: buyerID,
 		Status:      core.OrderPending,
 	}
-	if err := s.repo.CreateOrder(order); err != nil {
+	if s.expiry.OrderTTL > 0 {
+		expiresAt := time.Now().Add(s.expiry.OrderTTL)
+		order.ExpiresAt = &expiresAt
+	}
+	var reservedUntil *time.Time
+	if s.expiry.Reservation > 0 {
+		until := time.Now().Add(s.expiry.Reservation)
+		// The buyer may pay for as long as the order is pending, so the
+		// listing stays theirs until then
+		if order.ExpiresAt != nil && order.ExpiresAt.After(until) {
+			until = *order.ExpiresAt
+		}
+		reservedUntil = &until
+	}
+	if err := s.repo.CreateOrder(order, reservedUntil); err != nil {
 		return nil, err
 	}
 	return order, nil
 }
 
-func (s *MarketplaceService) ConfirmOrder(orderID uint, txHash string) error {
+// checkBuyerCanPay verifies, for listings priced in an ERC20 on our chain, that
+// the buyer has approved the marketplace for at least the price and holds it.
+func (s *MarketplaceService) checkBuyerCanPay(listing *core.Listing, buyerID uint) error {
+	nft, err := s.repo.GetNFTByID(listing.NFTID)
+	if err != nil {
+		return err
+	}
+	if nft.Chain != s.chain {
+		return nil
+	}
+	currency, err := s.listingCurrency(nft.Chain, listing.Currency)
+	if err != nil {
+		return err
+	}
+	if currency.IsNative() {
+		return nil
+	}
+	buyer, err := s.repo.GetUserByID(buyerID)
+	if err != nil {
+		return err
+	}
+	err = s.eth.CheckTokenPayment(currency.TokenAddress, buyer.WalletAddress, listing.PriceWei)
+	switch {
+	case errors.Is(err, core.ErrInsufficientAllowance):
+		return core.InvalidState("insufficient_allowance", err.Error())
+	case errors.Is(err, core.ErrInsufficientBalance):
+		return core.InvalidState("insufficient_balance", err.Error())
+	case err != nil:
+		return core.ChainFailure("could not check the buyer's token balance", err)
+	}
+	return nil
+}
+
+func (s *MarketplaceService) ConfirmOrder(orderID, buyerID uint, txHash string) error {
+	order, err := s.repo.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.BuyerUserID != buyerID {
+		return core.Forbidden("not_buyer", "only the buyer can confirm this order")
+	}
 	return s.repo.ConfirmOrder(orderID, txHash)
 }
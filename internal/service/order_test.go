@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/repository"
+)
+
+// newOrder mints a token to a seller, lists it and has a buyer order it.
+func newOrder(t *testing.T, svc *MarketplaceService, repo *repository.MemoryRepository) (seller, buyer *core.User, nft *core.NFT, listing *core.Listing, order *core.Order) {
+	t.Helper()
+	seller = newTestUser(t, repo, ownerWallet)
+	buyer = newTestUser(t, repo, otherWallet)
+	nft = mintTo(t, svc, seller)
+	listing, err := svc.CreateListing(nft.ID, seller.ID, core.NewWei(big.NewInt(100)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order, err = svc.CreateOrder(listing.ID, buyer.ID); err != nil {
+		t.Fatal(err)
+	}
+	return seller, buyer, nft, listing, order
+}
+
+// eventCount is how many events the store has logged.
+func eventCount(t *testing.T, repo *repository.MemoryRepository) int {
+	t.Helper()
+	events, err := repo.ListEvents(core.EventFilter{}, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(events)
+}
+
+func TestConfirmOrder(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	seller, buyer, nft, listing, order := newOrder(t, svc, repo)
+
+	if err := svc.ConfirmOrder(order.ID, seller.ID, "0x01"); !errors.Is(err, core.Forbidden("not_buyer", "")) {
+		t.Fatalf("confirmation by the seller: err = %v, want not_buyer", err)
+	}
+	if err := svc.ConfirmOrder(order.ID, buyer.ID, "0x01"); err != nil {
+		t.Fatal(err)
+	}
+
+	gotOrder, _ := repo.GetOrderByID(order.ID)
+	gotListing, _ := repo.GetListingByID(listing.ID)
+	gotNFT, _ := repo.GetNFTByID(nft.ID)
+	if gotOrder.Status != core.OrderConfirmed || gotListing.Status != core.ListingSold || gotNFT.OwnerUserID != buyer.ID {
+		t.Fatalf("order %s, listing %s, nft owned by %d after confirming; want CONFIRMED, SOLD and the buyer %d",
+			gotOrder.Status, gotListing.Status, gotNFT.OwnerUserID, buyer.ID)
+	}
+	if err := svc.ConfirmOrder(order.ID, buyer.ID, "0x01"); !errors.Is(err, core.InvalidState("order_not_pending", "")) {
+		t.Fatalf("confirming twice: err = %v, want order_not_pending", err)
+	}
+}
+
+// A confirmation that fails leaves the order, the listing and the NFT as
+// they were and records no events.
+func TestConfirmOrderIsAllOrNothing(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	seller, buyer, nft, listing, order := newOrder(t, svc, repo)
+	if err := svc.CancelListing(listing.ID, seller.ID); err != nil {
+		t.Fatal(err)
+	}
+	events := eventCount(t, repo)
+
+	if err := svc.ConfirmOrder(order.ID, buyer.ID, "0x01"); !errors.Is(err, core.InvalidState("listing_not_active", "")) {
+		t.Fatalf("confirming an order of a cancelled listing: err = %v, want listing_not_active", err)
+	}
+	gotOrder, _ := repo.GetOrderByID(order.ID)
+	gotListing, _ := repo.GetListingByID(listing.ID)
+	gotNFT, _ := repo.GetNFTByID(nft.ID)
+	if gotOrder.Status != core.OrderPending || gotOrder.TxHash != nil {
+		t.Fatalf("order %s with hash %v, want PENDING without one", gotOrder.Status, gotOrder.TxHash)
+	}
+	if gotListing.Status != core.ListingCancelled || gotNFT.OwnerUserID != seller.ID {
+		t.Fatalf("listing %s, nft owned by %d; want CANCELLED and the seller %d", gotListing.Status, gotNFT.OwnerUserID, seller.ID)
+	}
+	if n := eventCount(t, repo); n != events {
+		t.Fatalf("%d events recorded by the failed confirmation", n-events)
+	}
+}
+
+// Buyers ordering the same listing at once: exactly one gets the order and
+// the others are refused with the end of its reservation.
+func TestCreateOrderReservesListingForOneBuyer(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	seller := newTestUser(t, repo, ownerWallet)
+	nft := mintTo(t, svc, seller)
+	listing, err := svc.CreateListing(nft.ID, seller.ID, core.NewWei(big.NewInt(100)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const buyers = 8
+	ids := make([]uint, buyers)
+	for i := range ids {
+		ids[i] = newTestUser(t, repo, fmt.Sprintf("0x%040x", 0xb00+i)).ID
+	}
+
+	orders := make([]*core.Order, buyers)
+	errs := make([]error, buyers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			orders[i], errs[i] = svc.CreateOrder(listing.ID, ids[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var won *core.Order
+	for i, err := range errs {
+		if err == nil {
+			if won != nil {
+				t.Fatalf("orders %d and %d were both created", won.ID, orders[i].ID)
+			}
+			won = orders[i]
+		}
+	}
+	if won == nil {
+		t.Fatalf("no order was created: %v", errs)
+	}
+	until := *won.Listing.ReservedUntil
+	for i, err := range errs {
+		var coreErr *core.Error
+		if err != nil && (!errors.As(err, &coreErr) || coreErr.Code != "listing_reserved" || !coreErr.RetryAt.Equal(until)) {
+			t.Errorf("buyer %d: err = %v, want listing_reserved until %v", i, err, until)
+		}
+	}
+}
+
+// The listing stays reserved for as long as the buyer may pay, even when
+// reservations are shorter than orders: another buyer cannot take it over
+// and leave the first buyer's payment refused.
+func TestReservationLastsUntilOrderExpires(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	svc.expiry.OrderTTL, svc.expiry.Reservation = 30*time.Minute, 15*time.Minute
+	_, buyer, _, listing, order := newOrder(t, svc, repo)
+
+	got, _ := repo.GetListingByID(listing.ID)
+	if got.ReservedUntil == nil || !got.ReservedUntil.Equal(*order.ExpiresAt) {
+		t.Fatalf("listing reserved until %v, want until the order expires at %v", got.ReservedUntil, order.ExpiresAt)
+	}
+
+	other := newTestUser(t, repo, "0x00000000000000000000000000000000000000b3")
+	_, err := svc.CreateOrder(listing.ID, other.ID)
+	var coreErr *core.Error
+	if !errors.As(err, &coreErr) || coreErr.Code != "listing_reserved" || !coreErr.RetryAt.Equal(*order.ExpiresAt) {
+		t.Fatalf("ordering a reserved listing: err = %v, want listing_reserved until %v", err, order.ExpiresAt)
+	}
+	if err := svc.ConfirmOrder(order.ID, buyer.ID, "0x01"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/rarity"
+)
+
+// rarityBatch is how many stale collections a pass claims at a time.
+const rarityBatch = 20
+
+// RunRarity rescores the collections whose tokens were minted, registered
+// or burned every rarity interval until ctx is done, so that changes to a
+// collection cost one rescore however many there were. Replicas may all run
+// it; each collection is claimed by one of them.
+func (s *MarketplaceService) RunRarity(ctx context.Context) {
+	ticker := time.NewTicker(s.rarityInterval)
+	defer ticker.Stop()
+	for {
+		s.rescoreStale()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MarketplaceService) rescoreStale() {
+	for {
+		ids, err := s.repo.ClaimStaleRarity(rarityBatch)
+		if err != nil {
+			log.Printf("Failed to claim stale collections: %v", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+		for _, id := range ids {
+			if err := s.RecomputeRarity(id); err != nil {
+				log.Printf("Rarity refresh failed for collection %d: %v", id, err)
+				// Left for the next pass
+				if err := s.repo.MarkRarityStale(id); err != nil {
+					log.Printf("Failed to mark collection %d stale: %v", id, err)
+				}
+			}
+		}
+		if len(ids) < rarityBatch {
+			return
+		}
+	}
+}
+
+// RecomputeRarity rescores every live token of a collection from the trait
+// counts kept up to date on mint and burn.
+func (s *MarketplaceService) RecomputeRarity(collectionID uint) error {
+	counts, err := s.repo.ListTraitCounts(collectionID)
+	if err != nil {
+		return err
+	}
+	nfts, err := s.repo.ListCollectionNFTs(collectionID)
+	if err != nil {
+		return err
+	}
+
+	freq := rarity.NewFrequencies(len(nfts), counts)
+	scores := make(map[uint]float64, len(nfts))
+	for _, nft := range nfts {
+		scores[nft.ID] = freq.Score(s.rarityMethod, nft.Attributes)
+	}
+	return s.repo.UpdateNFTRarity(scores, rarity.Rank(scores))
+}
+
+func (s *MarketplaceService) ListTraits(collectionID uint) ([]core.TraitCount, error) {
+	return s.repo.ListTraitCounts(collectionID)
+}
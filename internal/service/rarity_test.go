@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+func TestRescoreStaleRanksChangedCollections(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	owner := newTestUser(t, repo, "0x00000000000000000000000000000000000000a1")
+	collection := &core.Collection{CreatorUserID: owner.ID, Name: "Test", Symbol: "TST"}
+	if err := repo.CreateCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+	var rare uint
+	for i, color := range []string{"Red", "Red", "Blue"} {
+		nft, err := svc.RegisterNFT(fmt.Sprint(i+1), chain.GetNFTAddress(), "Qubetics", collection.ID, owner.ID,
+			"Token", "", "", []core.NFTAttribute{{TraitType: "Color", Value: color}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if color == "Blue" {
+			rare = nft.ID
+		}
+	}
+
+	svc.rescoreStale()
+	nfts, err := repo.ListCollectionNFTs(collection.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nft := range nfts {
+		if nft.RarityRank == 0 {
+			t.Errorf("nft %d was not ranked", nft.ID)
+		}
+		if nft.ID == rare && nft.RarityRank != 1 {
+			t.Errorf("rarest nft ranked %d, want 1", nft.RarityRank)
+		}
+	}
+	if ids, _ := repo.ClaimStaleRarity(10); len(ids) != 0 {
+		t.Errorf("collections %v still stale after rescoring", ids)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/metrics"
+)
+
+// reconcileBatch bounds how many NFTs are read from the database and the
+// chain at a time.
+const reconcileBatch = 500
+
+func (s *MarketplaceService) ListReconciliationRuns(page core.PageRequest) (*core.Page[core.ReconciliationRun], error) {
+	return s.repo.ListReconciliationRuns(page)
+}
+
+func (s *MarketplaceService) GetReconciliationRun(id uint) (*core.ReconciliationRun, error) {
+	return s.repo.GetReconciliationRun(id)
+}
+
+// StartReconciliation starts a reconciliation for an admin in the
+// background and returns its run, which is filled in as it goes.
+func (s *MarketplaceService) StartReconciliation(actorID uint) (*core.ReconciliationRun, error) {
+	if !s.reconciling.TryLock() {
+		return nil, core.Conflict("reconciliation_running", "a reconciliation is already running")
+	}
+	run, err := s.createReconciliationRun(&actorID)
+	if err != nil {
+		s.reconciling.Unlock()
+		return nil, err
+	}
+	started := *run
+	go func() {
+		defer s.reconciling.Unlock()
+		s.reconcileRun(context.Background(), run)
+	}()
+	return &started, nil
+}
+
+// RunReconciliation compares the recorded owner of every NFT of our
+// contract with the chain and, unless auto-fix is off, corrects it.
+func (s *MarketplaceService) RunReconciliation(ctx context.Context) (*core.ReconciliationRun, error) {
+	if !s.reconciling.TryLock() {
+		return nil, core.Conflict("reconciliation_running", "a reconciliation is already running")
+	}
+	defer s.reconciling.Unlock()
+	run, err := s.createReconciliationRun(nil)
+	if err != nil {
+		return nil, err
+	}
+	s.reconcileRun(ctx, run)
+	return run, nil
+}
+
+func (s *MarketplaceService) createReconciliationRun(actorID *uint) (*core.ReconciliationRun, error) {
+	run := &core.ReconciliationRun{StartedAt: time.Now(), StartedByUserID: actorID, AutoFix: s.reconcile.AutoFix}
+	if err := s.repo.CreateReconciliationRun(run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *MarketplaceService) reconcileRun(ctx context.Context, run *core.ReconciliationRun) {
+	if err := s.reconcileOwners(ctx, run); err != nil {
+		log.Printf("Reconciliation %d failed: %v", run.ID, err)
+		run.Error = err.Error()
+		metrics.ReconciliationFailures.Inc()
+	} else {
+		metrics.OwnershipDrift.Set(float64(run.Drifted))
+		metrics.ReconciledNFTs.Set(float64(run.Checked))
+		metrics.ReconciliationLastSuccess.SetToCurrentTime()
+	}
+	if err := s.repo.FinishReconciliationRun(run); err != nil {
+		log.Printf("Failed to record reconciliation %d: %v", run.ID, err)
+		return
+	}
+	log.Printf("Reconciliation %d: checked=%d drifted=%d fixed=%d listings_cancelled=%d",
+		run.ID, run.Checked, run.Drifted, run.Fixed, run.ListingsCancelled)
+}
+
+func (s *MarketplaceService) reconcileOwners(ctx context.Context, run *core.ReconciliationRun) error {
+	contract := s.eth.GetNFTAddress()
+
+	var afterID uint
+	for {
+		nfts, err := s.repo.ListChainNFTs(s.chain, contract, afterID, reconcileBatch)
+		if err != nil || len(nfts) == 0 {
+			return err
+		}
+		afterID = nfts[len(nfts)-1].ID
+
+		tokenIds := make([]string, len(nfts))
+		for i, nft := range nfts {
+			tokenIds[i] = nft.TokenID
+		}
+		owners, err := s.eth.OwnersOf(ctx, tokenIds)
+		if err != nil {
+			return err
+		}
+		tokens := make([]core.TokenOwner, len(nfts))
+		for i, nft := range nfts {
+			tokens[i] = core.TokenOwner{TokenID: nft.TokenID, Owner: nft.Owner.WalletAddress}
+		}
+		approved, err := s.eth.MarketplaceApproved(ctx, tokens)
+		if err != nil {
+			return err
+		}
+
+		for i := range nfts {
+			nft, owner := &nfts[i], owners[i]
+			run.Checked++
+			if owner != "" && strings.EqualFold(owner, nft.Owner.WalletAddress) {
+				if approved[i] || !run.AutoFix {
+					continue
+				}
+				// The owner's listings can no longer be bought
+				cancelled, err := s.repo.CancelUnapprovedListings(nft.ID, nft.OwnerUserID)
+				if err != nil {
+					return err
+				}
+				run.ListingsCancelled += cancelled
+				metrics.ReconciliationCancelledListings.Add(float64(cancelled))
+				continue
+			}
+			run.Drifted++
+			drift := &core.OwnershipDrift{
+				RunID:           run.ID,
+				NFTID:           nft.ID,
+				TokenID:         nft.TokenID,
+				RecordedOwnerID: nft.OwnerUserID,
+				ChainOwner:      owner,
+			}
+			if owner == "" {
+				metrics.OwnershipDriftTotal.WithLabelValues("burned").Inc()
+			} else {
+				metrics.OwnershipDriftTotal.WithLabelValues("transferred").Inc()
+			}
+			if !run.AutoFix {
+				if err := s.repo.RecordDrift(drift); err != nil {
+					return err
+				}
+				continue
+			}
+
+			cancelled, err := s.fixDrift(drift)
+			if errors.Is(err, core.InvalidState("nft_changed", "")) {
+				// Changed by an order or intent since it was read; the next run checks it again
+				log.Printf("Reconciliation %d: NFT %d changed while it was fixed", run.ID, nft.ID)
+				if err := s.repo.RecordDrift(drift); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			run.Fixed++
+			run.ListingsCancelled += cancelled
+			metrics.ReconciliationCancelledListings.Add(float64(cancelled))
+		}
+	}
+}
+
+// fixDrift records the chain's view of an NFT, returning how many of its
+// listings were cancelled.
+func (s *MarketplaceService) fixDrift(drift *core.OwnershipDrift) (int, error) {
+	if drift.ChainOwner == "" {
+		return s.repo.FixBurnedNFT(drift)
+	}
+	owner, err := s.repo.FindOrCreateUserByWallet(drift.ChainOwner)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.FixTransferredNFT(drift, owner.ID)
+}
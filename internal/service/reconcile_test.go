@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/core"
+)
+
+// reconcile runs a reconciliation and returns it, failing on errors.
+func reconcile(t *testing.T, svc *MarketplaceService) *core.ReconciliationRun {
+	t.Helper()
+	run, err := svc.RunReconciliation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Error != "" {
+		t.Fatalf("reconciliation failed: %s", run.Error)
+	}
+	return run
+}
+
+func TestReconcileTransferredNFT(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	seller := newTestUser(t, repo, ownerWallet)
+	nft := mintTo(t, svc, seller)
+	listing, err := svc.CreateListing(nft.ID, seller.ID, core.NewWei(big.NewInt(100)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sold elsewhere, to a wallet without a user
+	const buyerWallet = "0x00000000000000000000000000000000000000c3"
+	if _, err := chain.Transfer(seller.WalletAddress, buyerWallet, nft.TokenID); err != nil {
+		t.Fatal(err)
+	}
+	run := reconcile(t, svc)
+	if run.Checked != 1 || run.Drifted != 1 || run.Fixed != 1 || run.ListingsCancelled != 1 {
+		t.Fatalf("run checked %d, drifted %d, fixed %d, cancelled %d; want 1 each",
+			run.Checked, run.Drifted, run.Fixed, run.ListingsCancelled)
+	}
+	buyer, err := repo.GetUserByWallet(buyerWallet)
+	if err != nil {
+		t.Fatalf("no user for the new owner: %v", err)
+	}
+	gotNFT, _ := repo.GetNFTByID(nft.ID)
+	gotListing, _ := repo.GetListingByID(listing.ID)
+	if gotNFT.OwnerUserID != buyer.ID || gotListing.Status != core.ListingCancelled {
+		t.Fatalf("nft owned by %d, listing %s; want the new owner %d and CANCELLED", gotNFT.OwnerUserID, gotListing.Status, buyer.ID)
+	}
+
+	// Nothing is left to fix
+	if run := reconcile(t, svc); run.Drifted != 0 || run.ListingsCancelled != 0 {
+		t.Fatalf("second run drifted %d, cancelled %d", run.Drifted, run.ListingsCancelled)
+	}
+}
+
+func TestReconcileBurnedNFT(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	owner := newTestUser(t, repo, ownerWallet)
+	nft := mintTo(t, svc, owner)
+	if _, err := chain.Burn(owner.WalletAddress, nft.TokenID); err != nil {
+		t.Fatal(err)
+	}
+	if run := reconcile(t, svc); run.Drifted != 1 || run.Fixed != 1 {
+		t.Fatalf("run drifted %d, fixed %d; want 1 each", run.Drifted, run.Fixed)
+	}
+	if got, _ := repo.GetNFTByID(nft.ID); got.BurnedAt == nil {
+		t.Fatal("nft was not burned")
+	}
+}
+
+// A seller who revokes the marketplace's approval keeps the token, but its
+// listings are cancelled since they can no longer be bought.
+func TestReconcileRevokedApproval(t *testing.T) {
+	svc, repo, chain := newTestService(t)
+	seller := newTestUser(t, repo, ownerWallet)
+	nft := mintTo(t, svc, seller)
+	listing, err := svc.CreateListing(nft.ID, seller.ID, core.NewWei(big.NewInt(100)), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if run := reconcile(t, svc); run.ListingsCancelled != 0 {
+		t.Fatalf("run cancelled %d listings of an approved token", run.ListingsCancelled)
+	}
+	chain.SetApprovalForAll(seller.WalletAddress, false)
+	run := reconcile(t, svc)
+	if run.Drifted != 0 || run.ListingsCancelled != 1 {
+		t.Fatalf("run drifted %d, cancelled %d; want 0 and 1", run.Drifted, run.ListingsCancelled)
+	}
+	gotNFT, _ := repo.GetNFTByID(nft.ID)
+	gotListing, _ := repo.GetListingByID(listing.ID)
+	if gotNFT.OwnerUserID != seller.ID || gotListing.Status != core.ListingCancelled {
+		t.Fatalf("nft owned by %d, listing %s; want the seller %d and CANCELLED", gotNFT.OwnerUserID, gotListing.Status, seller.ID)
+	}
+}
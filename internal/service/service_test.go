@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/db/dbtest"
+	"github.com/user/nft-marketplace/internal/platform/eth"
+	"github.com/user/nft-marketplace/internal/repository"
+)
+
+// forEachStore runs test against the in-memory store and against the SQL
+// store on a new SQLite database and, when TEST_POSTGRES_DB is set, on
+// Postgres.
+func forEachStore(t *testing.T, test func(t *testing.T, repo core.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewMemoryRepository(nil))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, repository.NewRepository(dbtest.SQLite(t), nil))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, repository.NewRepository(dbtest.Postgres(t), nil))
+	})
+}
+
+// newTestService returns a marketplace service on the in-memory store and
+// chain with the default configuration.
+func newTestService(t *testing.T) (*MarketplaceService, *repository.MemoryRepository, *eth.MemoryChain) {
+	t.Helper()
+	cfg := &config.Config{
+		Ethereum:  &config.EthConfig{ChainName: "Qubetics", NativeCurrency: "ETH"},
+		Rarity:    config.LoadRarityConfig(),
+		Intent:    config.LoadIntentConfig(),
+		Reconcile: config.LoadReconcileConfig(),
+		Listing:   config.LoadListingConfig(),
+		Expiry:    config.LoadExpiryConfig(),
+	}
+	repo := repository.NewMemoryRepository(nil)
+	chain := eth.NewMemoryChain("")
+	svc, err := NewMarketplaceService(cfg, repo, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.EnsureNativeCurrency(); err != nil {
+		t.Fatal(err)
+	}
+	return svc, repo, chain
+}
+
+// newTestUser creates a user with the given wallet.
+func newTestUser(t *testing.T, repo core.Store, wallet string) *core.User {
+	t.Helper()
+	user := &core.User{WalletAddress: wallet}
+	if err := repo.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// newTestAuthService returns an auth service on repo for chain 1 and SIWE
+// messages of example.com.
+func newTestAuthService(t *testing.T, repo core.Store) *AuthService {
+	t.Helper()
+	cfg := &config.Config{
+		Ethereum: &config.EthConfig{ChainID: 1},
+		Auth:     config.LoadAuthConfig(),
+	}
+	cfg.Auth.JWTSecret = "test"
+	cfg.Auth.SIWEDomain = "example.com"
+	svc, err := NewAuthService(cfg, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
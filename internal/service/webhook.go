@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/user/nft-marketplace/internal/config"
+	"github.com/user/nft-marketplace/internal/core"
+	"github.com/user/nft-marketplace/internal/platform/auth"
+	"github.com/user/nft-marketplace/internal/platform/webhook"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	maxWebhooksPerUser  = 20
+	// resolveTimeout bounds the lookup of a new webhook's host.
+	resolveTimeout = 5 * time.Second
+	// dispatchBatch and deliveryBatch bound how many events and deliveries
+	// a poll handles at a time.
+	dispatchBatch = 100
+	deliveryBatch = 50
+)
+
+type WebhookService struct {
+	cfg    *config.WebhookConfig
+	repo   core.WebhookStore
+	sender *webhook.Sender
+}
+
+func NewWebhookService(cfg *config.WebhookConfig, repo core.WebhookStore) *WebhookService {
+	return &WebhookService{cfg: cfg, repo: repo, sender: webhook.NewSender(cfg.Timeout, cfg.AllowPrivate)}
+}
+
+// CreateWebhook subscribes rawURL to events of the given types, all when
+// none are given. The signing secret is returned only here.
+func (s *WebhookService) CreateWebhook(owner core.WebhookOwner, rawURL string, types []core.EventType) (*core.Webhook, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || !(u.Scheme == "https" || u.Scheme == "http" && s.cfg.AllowHTTP) {
+		return nil, "", core.Validation("invalid_webhook_url", "webhook url must be an absolute https URL")
+	}
+	if !s.cfg.AllowPrivate {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		err := webhook.CheckHost(ctx, u.Hostname())
+		cancel()
+		if errors.Is(err, webhook.ErrForbiddenTarget) {
+			return nil, "", core.Validation("forbidden_webhook_url", "webhook url must not point to a loopback, private or link-local address")
+		}
+		if err != nil {
+			return nil, "", core.Validation("invalid_webhook_url", "webhook url host does not resolve")
+		}
+	}
+	n, err := s.repo.CountWebhooks(owner.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if n >= maxWebhooksPerUser {
+		return nil, "", core.Conflict("too_many_webhooks", "a user may have at most 20 webhooks")
+	}
+	secret, err := auth.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook := &core.Webhook{
+		UserID:     owner.UserID,
+		URL:        u.String(),
+		Secret:     webhookSecretPrefix + secret,
+		EventTypes: types,
+	}
+	if owner.APIKeyID != 0 {
+		hook.APIKeyID = &owner.APIKeyID
+	}
+	if err := s.repo.CreateWebhook(hook); err != nil {
+		return nil, "", err
+	}
+	return hook, hook.Secret, nil
+}
+
+func (s *WebhookService) ListWebhooks(owner core.WebhookOwner) ([]core.Webhook, error) {
+	return s.repo.ListWebhooks(owner)
+}
+
+func (s *WebhookService) DeleteWebhook(owner core.WebhookOwner, id uint) error {
+	return s.repo.DeleteWebhook(owner, id)
+}
+
+func (s *WebhookService) ListDeliveries(owner core.WebhookOwner, webhookID uint, filter core.DeliveryFilter, page core.PageRequest) (*core.Page[core.WebhookDelivery], error) {
+	if _, err := s.repo.GetWebhook(owner, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWebhookDeliveries(webhookID, filter, page)
+}
+
+// Redeliver queues the payload of a delivery again, whatever its status, as
+// a new delivery with its own attempts.
+func (s *WebhookService) Redeliver(owner core.WebhookOwner, deliveryID uint) (*core.WebhookDelivery, error) {
+	original, err := s.repo.GetWebhookDelivery(owner, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	delivery := &core.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        core.DeliveryPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &original.ID,
+	}
+	if err := s.repo.CreateWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Run queues deliveries for new events and sends those due every poll
+// interval until ctx is done. Replicas may all run it; they skip each
+// other's rows.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.dispatch()
+		s.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneDeliveries drops finished deliveries older than the retention.
+func (s *WebhookService) PruneDeliveries() error {
+	return s.repo.PruneWebhookDeliveries(time.Now().Add(-s.cfg.Retention))
+}
+
+func (s *WebhookService) dispatch() {
+	for {
+		n, err := s.repo.DispatchEvents(dispatchBatch)
+		if err != nil {
+			log.Printf("Failed to dispatch events to webhooks: %v", err)
+			return
+		}
+		if n < dispatchBatch {
+			return
+		}
+	}
+}
+
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	// The lease outlasts an attempt, so a delivery is never sent twice at once
+	deliveries, err := s.repo.ClaimWebhookDeliveries(deliveryBatch, s.cfg.Timeout+time.Minute)
+	if err != nil {
+		log.Printf("Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, s.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(d *core.WebhookDelivery) {
+			defer func() { <-sem; wg.Done() }()
+			s.deliver(ctx, d)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+}
+
+// deliver makes one attempt at d. Failures are retried with exponential
+// backoff until the attempts run out and the delivery is dead.
+func (s *WebhookService) deliver(ctx context.Context, d *core.WebhookDelivery) {
+	res := s.sender.Send(ctx, d.Webhook.URL, d.Webhook.Secret, d.ID, string(d.EventType), []byte(d.Payload))
+
+	now := time.Now()
+	attempt := &core.WebhookAttempt{
+		StatusCode:    res.StatusCode,
+		ResponseBytes: res.BodyBytes,
+		DurationMS:    res.Duration.Milliseconds(),
+	}
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.LastStatusCode = res.StatusCode
+	d.LastError = ""
+	switch {
+	case res.Err == nil:
+		d.Status = core.DeliverySucceeded
+	case d.Attempts >= s.cfg.MaxAttempts:
+		attempt.Error = res.Err.Error()
+		d.LastError = attempt.Error
+		d.Status = core.DeliveryDead
+	default:
+		attempt.Error = res.Err.Error()
+		d.LastError = attempt.Error
+		d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+	}
+	if err := s.repo.RecordWebhookAttempt(d, attempt); err != nil {
+		log.Printf("Failed to record attempt at webhook delivery %d: %v", d.ID, err)
+	}
+}
+
+// backoff is the delay after the given number of failed attempts.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.cfg.RetryBase
+	for i := 1; i < attempts && delay < s.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.RetryMax {
+		delay = s.cfg.RetryMax
+	}
+	return delay
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithBearerToken authenticates every request with a SIWE access token.
+func WithBearerToken(token string) ClientOption {
+	return WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// WithAPIKey authenticates every request with an API key.
+func WithAPIKey(key string) ClientOption {
+	return WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("X-API-Key", key)
+		return nil
+	})
+}
@@ -0,0 +1,5 @@
+package client
+
+// The client is generated from the API's OpenAPI document; regenerate it
+// with `go generate ./pkg/client` after changing the document.
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.5.1 -config oapi-codegen.yaml ../../internal/openapi/openapi.json
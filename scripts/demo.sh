@@ -1,48 +1,61 @@
 #!/bin/bash
+# Walks a mint -> list -> buy flow through the /v1 API. Assumes the API runs
+# on a local Hardhat node with ADMIN_WALLETS including the seller below.
 set -e
 
-API_URL="http://localhost:8081"
+API_URL="${API_URL:-http://localhost:8080}"
+CHAIN_ID="${CHAIN_ID:-1337}"
+SIWE_DOMAIN="${SIWE_DOMAIN:-localhost:5173}"
+
+# Hardhat development accounts #0 and #1
+SELLER_KEY="0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+BUYER_KEY="0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d"
+
+login() {
+  go run ./cmd/login -url "$API_URL" -key "$1" -domain "$SIWE_DOMAIN" -chain "$CHAIN_ID"
+}
+
+post() {
+  curl -s -X POST "$API_URL$1" \
+    -H "Authorization: Bearer $2" \
+    -H "Content-Type: application/json" \
+    -H "Idempotency-Key: $(openssl rand -hex 16)" \
+    -d "$3"
+}
 
 echo "=== NFT Marketplace Demo ==="
 
-echo "1. Getting Deployed Addresses..."
-ADDRESSES=$(curl -s $API_URL/demo/addresses)
-echo $ADDRESSES | jq .
-OWNER=$(echo $ADDRESSES | jq -r .owner)
-SELLER=$(echo $ADDRESSES | jq -r .seller)
-BUYER=$(echo $ADDRESSES | jq -r .buyer)
-
-echo -e "\n2. Minting NFT to Seller ($SELLER)..."
-MINT_RESP=$(curl -s -X POST $API_URL/mint \
-  -H "Content-Type: application/json" \
-  -d "{\"to\": \"$SELLER\", \"token_uri\": \"http://example.com/nft/1\"}")
-echo $MINT_RESP | jq .
-TOKEN_ID=$(echo $MINT_RESP | jq -r .token_id)
-echo "Minted Token ID: $TOKEN_ID"
-
-echo -e "\n3. Checking Initial Owner..."
-curl -s $API_URL/nft/$TOKEN_ID/owner | jq .
-
-echo -e "\n4. Approving Marketplace..."
-APPROVE_RESP=$(curl -s -X POST $API_URL/approve \
-  -H "Content-Type: application/json" \
-  -d "{\"token_id\": \"$TOKEN_ID\"}")
-echo $APPROVE_RESP | jq .
-
-echo -e "\n5. Listing NFT for 1 ETH..."
-PRICE_WEI="1000000000000000000"
-LIST_RESP=$(curl -s -X POST $API_URL/list \
-  -H "Content-Type: application/json" \
-  -d "{\"token_id\": \"$TOKEN_ID\", \"price_wei\": \"$PRICE_WEI\"}")
-echo $LIST_RESP | jq .
-
-echo -e "\n6. Buying NFT as Buyer ($BUYER)..."
-BUY_RESP=$(curl -s -X POST $API_URL/buy \
-  -H "Content-Type: application/json" \
-  -d "{\"token_id\": \"$TOKEN_ID\", \"price_wei\": \"$PRICE_WEI\"}")
-echo $BUY_RESP | jq .
-
-echo -e "\n7. Checking New Owner..."
-curl -s $API_URL/nft/$TOKEN_ID/owner | jq .
+echo "1. Signing in seller and buyer..."
+SELLER=$(login $SELLER_KEY)
+BUYER=$(login $BUYER_KEY)
+SELLER_TOKEN=$(echo "$SELLER" | jq -r .access_token)
+BUYER_TOKEN=$(echo "$BUYER" | jq -r .access_token)
+BUYER_ID=$(echo "$BUYER" | jq -r .user.id)
+echo "Seller: $(echo "$SELLER" | jq -r .user.wallet_address)"
+echo "Buyer:  $(echo "$BUYER" | jq -r .user.wallet_address)"
+
+echo -e "\n2. Minting NFT as seller..."
+NFT=$(post /v1/nfts/mint "$SELLER_TOKEN" \
+  '{"name": "Demo NFT", "symbol": "DEMO", "image_url": "https://example.com/nft/1.png"}')
+echo "$NFT" | jq .
+NFT_ID=$(echo "$NFT" | jq -r .id)
+
+echo -e "\n3. Listing NFT for 1 ETH..."
+LISTING=$(post /v1/listings "$SELLER_TOKEN" \
+  "{\"nft_id\": $NFT_ID, \"price_wei\": \"1000000000000000000\"}")
+echo "$LISTING" | jq .
+LISTING_ID=$(echo "$LISTING" | jq -r .id)
+
+echo -e "\n4. Placing order as buyer..."
+ORDER=$(post /v1/orders "$BUYER_TOKEN" "{\"listing_id\": $LISTING_ID}")
+echo "$ORDER" | jq .
+ORDER_ID=$(echo "$ORDER" | jq -r .id)
+
+echo -e "\n5. Confirming order..."
+post /v1/orders/$ORDER_ID/confirm "$BUYER_TOKEN" \
+  "{\"tx_hash\": \"0x$(openssl rand -hex 32)\"}" | jq .
+
+echo -e "\n6. Buyer's NFTs..."
+curl -s "$API_URL/v1/nfts?owner_id=$BUYER_ID" | jq .
 
 echo -e "\n=== Demo Complete ==="